@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envPrefix starts the environment variable that can set a flag. The rest is
+// the flag name upper-cased with dashes as underscores, e.g. --rate-limit is
+// CODE_SANDBOX_RATE_LIMIT.
+const envPrefix = "CODE_SANDBOX_"
+
+// envExcludedFlags are flags that only make sense on the command line
+var envExcludedFlags = map[string]bool{
+	"install": true,
+}
+
+// envAliases are further environment variables that can set a flag, used
+// when the flag's own variable is not set
+var envAliases = map[string][]string{
+	"artifact-ttl": {"ARTIFACT_TTL"},
+}
+
+// flagEnvName returns the environment variable for a flag
+func flagEnvName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// applyEnvConfig sets every flag of fs whose environment variable, or failing
+// that one of its aliases, is set from that variable, and names the variables
+// in the flag's usage. It must run before fs is parsed so that flags given on
+// the command line still win, giving the precedence flag > environment >
+// default.
+func applyEnvConfig(fs *flag.FlagSet) error {
+	var errs []string
+	fs.VisitAll(func(f *flag.Flag) {
+		if envExcludedFlags[f.Name] {
+			return
+		}
+		names := append([]string{flagEnvName(f.Name)}, envAliases[f.Name]...)
+		f.Usage += fmt.Sprintf(" [env %s]", strings.Join(names, ", "))
+		for _, env := range names {
+			if value, ok := os.LookupEnv(env); ok {
+				if err := f.Value.Set(value); err != nil {
+					errs = append(errs, fmt.Sprintf("invalid value %q for %s: %v", value, env, err))
+				}
+				return
+			}
+		}
+	})
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// firstNonEmpty returns the first of values that isn't empty, e.g. a flag and
+// then the standard environment variable of the tool it configures
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
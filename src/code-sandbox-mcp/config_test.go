@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestApplyEnvConfigPrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		args     []string
+		wantDir  string
+		wantRuns int
+	}{
+		{
+			name:     "default",
+			wantDir:  "/tmp/artifacts",
+			wantRuns: 0,
+		},
+		{
+			name: "environment",
+			env: map[string]string{
+				"CODE_SANDBOX_ARTIFACT_STORE_DIR":  "/srv/artifacts",
+				"CODE_SANDBOX_MAX_CONCURRENT_RUNS": "4",
+			},
+			wantDir:  "/srv/artifacts",
+			wantRuns: 4,
+		},
+		{
+			name: "flag over environment",
+			env: map[string]string{
+				"CODE_SANDBOX_ARTIFACT_STORE_DIR":  "/srv/artifacts",
+				"CODE_SANDBOX_MAX_CONCURRENT_RUNS": "4",
+			},
+			args:     []string{"--artifact-store-dir", "/data/artifacts", "--max-concurrent-runs", "2"},
+			wantDir:  "/data/artifacts",
+			wantRuns: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			dir := fs.String("artifact-store-dir", "/tmp/artifacts", "")
+			runs := fs.Int("max-concurrent-runs", 0, "")
+
+			if err := applyEnvConfig(fs); err != nil {
+				t.Fatalf("applyEnvConfig() = %v", err)
+			}
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatalf("Parse() = %v", err)
+			}
+			if *dir != tt.wantDir || *runs != tt.wantRuns {
+				t.Errorf("got dir %q, runs %d, want %q, %d", *dir, *runs, tt.wantDir, tt.wantRuns)
+			}
+		})
+	}
+}
+
+func TestApplyEnvConfigInvalidValue(t *testing.T) {
+	t.Setenv("CODE_SANDBOX_MAX_CONCURRENT_RUNS", "many")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("max-concurrent-runs", 0, "")
+
+	if err := applyEnvConfig(fs); err == nil {
+		t.Fatal("applyEnvConfig() with an invalid value succeeded")
+	}
+}
+
+func TestApplyEnvConfigAlias(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+		want time.Duration
+	}{
+		{
+			name: "default",
+			want: time.Hour,
+		},
+		{
+			name: "alias",
+			env:  map[string]string{"ARTIFACT_TTL": "30m"},
+			want: 30 * time.Minute,
+		},
+		{
+			name: "prefixed variable over alias",
+			env:  map[string]string{"ARTIFACT_TTL": "30m", "CODE_SANDBOX_ARTIFACT_TTL": "2h"},
+			want: 2 * time.Hour,
+		},
+		{
+			name: "flag over alias",
+			env:  map[string]string{"ARTIFACT_TTL": "30m"},
+			args: []string{"--artifact-ttl", "0"},
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			ttl := fs.Duration("artifact-ttl", time.Hour, "")
+
+			if err := applyEnvConfig(fs); err != nil {
+				t.Fatalf("applyEnvConfig() = %v", err)
+			}
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatalf("Parse() = %v", err)
+			}
+			if *ttl != tt.want {
+				t.Errorf("got %v, want %v", *ttl, tt.want)
+			}
+		})
+	}
+}
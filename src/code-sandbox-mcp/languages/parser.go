@@ -1,49 +1,78 @@
 package languages
 
 import (
+	"go/scanner"
+	"go/token"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
 var (
-	// Python import patterns
-	pythonImportRe  = regexp.MustCompile(`(?m)^import\s+(\w+)`)
-	pythonFromRe    = regexp.MustCompile(`(?m)^from\s+(\w+)\s+import`)
+	// Python import patterns. Imports may be indented, as in a function or a
+	// try block, and an import statement may list several modules.
+	pythonImportRe  = regexp.MustCompile(`(?m)^[ \t]*import[ \t]+([\w.]+(?:[ \t]+as[ \t]+\w+)?(?:[ \t]*,[ \t]*[\w.]+(?:[ \t]+as[ \t]+\w+)?)*)`)
+	pythonFromRe    = regexp.MustCompile(`(?m)^[ \t]*from[ \t]+(\w+)[\w.]*[ \t]+import\b`)
 	pythonDynamicRe = regexp.MustCompile(`__import__\(['"](\w+)['"]\)`)
 	// Requirements comment pattern
 	pythonRequirementsRe = regexp.MustCompile(`(?m)^#\s*requirements:\s*(.+)$`)
 
-	// Node.js import patterns
-	nodeRequireRe = regexp.MustCompile(`(?m)require\(['"]([^'"]+)['"]\)`)
-	nodeImportRe  = regexp.MustCompile(`(?m)import\s+(?:\{[^}]*\}|\*\s+as\s+\w+|\w+)\s+from\s+['"]([^'"]+)['"]`)
-	nodeDynamicRe = regexp.MustCompile(`(?m)import\(['"]([^'"]+)['"]\)`)
+	// Rust crate patterns
+	rustUseRe         = regexp.MustCompile(`(?m)^\s*(?:pub(?:\([^)]*\))?\s+)?use\s+(?:::)?\{?\s*(\w+)`)
+	rustExternCrateRe = regexp.MustCompile(`(?m)^\s*extern\s+crate\s+(\w+)`)
+	rustModRe         = regexp.MustCompile(`(?m)^\s*(?:pub(?:\([^)]*\))?\s+)?mod\s+(\w+)`)
 
-	// Go import patterns
-	goSingleImportRe = regexp.MustCompile(`(?m)^import\s+"([^"]+)"`)
-	goGroupImportRe  = regexp.MustCompile(`(?m)^[^/]*"([^"]+)"`)
-
-	// Standard library packages
-	pythonStdLib = map[string]bool{
-		"os": true, "sys": true, "datetime": true, "json": true, "math": true,
-		"random": true, "re": true, "time": true, "collections": true, "pathlib": true,
-		// Add more as needed
+	// Node.js built-in modules, which may also be imported with a "node:"
+	// prefix. Bun provides the same modules.
+	nodeStdLib = map[string]bool{
+		"assert": true, "async_hooks": true, "buffer": true, "child_process": true, "cluster": true,
+		"console": true, "constants": true, "crypto": true, "dgram": true, "diagnostics_channel": true,
+		"dns": true, "domain": true, "events": true, "fs": true, "http": true,
+		"http2": true, "https": true, "inspector": true, "module": true, "net": true,
+		"os": true, "path": true, "perf_hooks": true, "process": true, "punycode": true,
+		"querystring": true, "readline": true, "repl": true, "stream": true, "string_decoder": true,
+		"sys": true, "timers": true, "tls": true, "trace_events": true, "tty": true,
+		"url": true, "util": true, "v8": true, "vm": true, "wasi": true,
+		"worker_threads": true, "zlib": true,
 	}
 
-	nodeStdLib = map[string]bool{
-		"fs": true, "path": true, "http": true, "https": true, "crypto": true,
-		"buffer": true, "stream": true, "util": true, "events": true, "os": true,
-		// Add more as needed
+	// Crates that ship with the compiler, and path keywords that aren't crates
+	rustBuiltinCrates = map[string]bool{
+		"std": true, "core": true, "alloc": true, "proc_macro": true, "test": true,
+		"crate": true, "self": true, "super": true,
 	}
 
-	goStdLib = map[string]bool{
-		"fmt": true, "os": true, "io": true, "strings": true, "time": true,
-		"net/http": true, "encoding/json": true, "path/filepath": true,
-		// Add more as needed
+	// Hosts whose module paths have an owner and a repository, as in
+	// github.com/owner/repo, rather than a single element after the host
+	goRepoHosts = map[string]bool{
+		"github.com": true, "gitlab.com": true, "bitbucket.org": true, "golang.org": true,
 	}
 
-	// Package name mappings (for cases where import name differs from package name)
+	// goMajorVersionRe matches the major version suffix of a module path, as in example.com/mod/v2
+	goMajorVersionRe = regexp.MustCompile(`^v([2-9]|[1-9]\d+)$`)
+
+	// PyPI packages of the modules whose import name differs from the package name
 	pythonPkgMap = map[string]string{
-		"PIL": "pillow",
+		"PIL":       "Pillow",
+		"cv2":       "opencv-python",
+		"sklearn":   "scikit-learn",
+		"skimage":   "scikit-image",
+		"bs4":       "beautifulsoup4",
+		"yaml":      "PyYAML",
+		"dateutil":  "python-dateutil",
+		"dotenv":    "python-dotenv",
+		"jwt":       "PyJWT",
+		"Crypto":    "pycryptodome",
+		"serial":    "pyserial",
+		"docx":      "python-docx",
+		"pptx":      "python-pptx",
+		"fitz":      "PyMuPDF",
+		"OpenSSL":   "pyOpenSSL",
+		"MySQLdb":   "mysqlclient",
+		"zmq":       "pyzmq",
+		"git":       "GitPython",
+		"attr":      "attrs",
+		"multipart": "python-multipart",
 	}
 )
 
@@ -63,109 +92,289 @@ func parseRequirements(requirementsStr string) []string {
 	return reqs
 }
 
+// ParseRequirementsComments returns the packages listed in comments formatted
+// as "# requirements: package1, package2", in order and without duplicates
+func ParseRequirementsComments(code string) []string {
+	var reqs []string
+	seen := make(map[string]bool)
+	for _, match := range pythonRequirementsRe.FindAllStringSubmatch(code, -1) {
+		for _, req := range parseRequirements(match[1]) {
+			if !seen[req] {
+				seen[req] = true
+				reqs = append(reqs, req)
+			}
+		}
+	}
+	return reqs
+}
+
 // ParsePythonImports extracts non-standard library package imports from Python code
 func ParsePythonImports(code string) []string {
 	imports := make(map[string]bool)
+	pinned := make(map[string]bool) // Entries of imports from requirements comments
 
-	// Find standard imports
-	for _, match := range pythonImportRe.FindAllStringSubmatch(code, -1) {
-		pkg := match[1]
+	// Only the top-level package of a module path such as "matplotlib.pyplot"
+	// is installed, under its PyPI name
+	add := func(module string) {
+		pkg, _, _ := strings.Cut(module, ".")
+		if pythonStdLib[pkg] {
+			return
+		}
 		if mapped, ok := pythonPkgMap[pkg]; ok {
 			pkg = mapped
 		}
-		if !pythonStdLib[pkg] {
-			imports[pkg] = true
+		imports[pkg] = true
+	}
+
+	// Find standard imports, e.g. "import numpy as np, os.path"
+	for _, match := range pythonImportRe.FindAllStringSubmatch(code, -1) {
+		for _, module := range strings.Split(match[1], ",") {
+			add(strings.Fields(module)[0])
 		}
 	}
 
 	// Find from imports
 	for _, match := range pythonFromRe.FindAllStringSubmatch(code, -1) {
-		pkg := match[1]
-		if mapped, ok := pythonPkgMap[pkg]; ok {
-			pkg = mapped
-		}
-		if !pythonStdLib[pkg] {
-			imports[pkg] = true
-		}
+		add(match[1])
 	}
 
 	// Find dynamic imports
 	for _, match := range pythonDynamicRe.FindAllStringSubmatch(code, -1) {
-		pkg := match[1]
-		if mapped, ok := pythonPkgMap[pkg]; ok {
-			pkg = mapped
-		}
-		if !pythonStdLib[pkg] {
-			imports[pkg] = true
-		}
+		add(match[1])
 	}
 
-	// Find requirements comments
-	for _, match := range pythonRequirementsRe.FindAllStringSubmatch(code, -1) {
-		requirementsStr := match[1]
-		reqs := parseRequirements(requirementsStr)
-		for _, req := range reqs {
-			// For requirements we don't filter standard library
-			// This allows users to specify specific versions of standard lib packages
-			imports[req] = true
+	// Find requirements comments. An explicit requirement, usually pinning a
+	// version, replaces the bare package name of an import of the same package.
+	for _, req := range ParseRequirementsComments(code) {
+		name := pythonRequirementName(req)
+		for pkg := range imports {
+			if pythonRequirementName(pkg) == name && pkg != req && !pinned[pkg] {
+				delete(imports, pkg)
+			}
 		}
+		// For requirements we don't filter standard library
+		// This allows users to specify specific versions of standard lib packages
+		imports[req] = true
+		pinned[req] = true
 	}
 
 	return mapToSlice(imports)
 }
 
-// ParseNodeImports extracts non-standard library package imports from Node.js code
+// pythonRequirementName returns the normalized name of the package a
+// requirement refers to, e.g. "scikit-learn" for "Scikit_Learn>=1.4"
+func pythonRequirementName(req string) string {
+	if end := strings.IndexAny(req, "=<>!~[;@ "); end >= 0 {
+		req = req[:end]
+	}
+	return strings.ToLower(strings.NewReplacer("_", "-", ".", "-").Replace(strings.TrimSpace(req)))
+}
+
+// ParseNodeImports extracts the npm packages JavaScript or TypeScript code
+// imports, leaving out built-in modules and local files. Type-only imports
+// count too, as the package usually ships the types.
 func ParseNodeImports(code string) []string {
 	imports := make(map[string]bool)
-
-	// Find require statements
-	for _, match := range nodeRequireRe.FindAllStringSubmatch(code, -1) {
-		pkg := getBasePackage(match[1])
-		if !nodeStdLib[pkg] {
+	for _, specifier := range nodeImportSpecifiers(code) {
+		if pkg := getBasePackage(specifier); pkg != "" && !nodeStdLib[pkg] {
 			imports[pkg] = true
 		}
 	}
+	return mapToSlice(imports)
+}
+
+// nodeImportSpecifiers returns the module specifiers of the import and
+// "export ... from" declarations, require() calls and dynamic imports in
+// JavaScript or TypeScript code. The code is tokenized just enough to skip
+// comments and to only read strings where a specifier can appear, so imports
+// mentioned in either don't count.
+func nodeImportSpecifiers(code string) []string {
+	var specifiers []string
+	// The last two tokens before the current one: identifiers and single
+	// punctuation characters, or "" for anything else
+	var prev, prevPrev string
+	push := func(tok string) {
+		prev, prevPrev = tok, prev
+	}
 
-	// Find ES6 imports
-	for _, match := range nodeImportRe.FindAllStringSubmatch(code, -1) {
-		pkg := getBasePackage(match[1])
-		if !nodeStdLib[pkg] {
-			imports[pkg] = true
+	for i := 0; i < len(code); {
+		c := code[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(code[i:], "//"):
+			end := strings.IndexByte(code[i:], '\n')
+			if end < 0 {
+				return specifiers
+			}
+			i += end
+		case strings.HasPrefix(code[i:], "/*"):
+			end := strings.Index(code[i+2:], "*/")
+			if end < 0 {
+				return specifiers
+			}
+			i += end + 4
+		case c == '\'' || c == '"' || c == '`':
+			value, n := nodeStringLiteral(code[i:])
+			i += n
+			// from '...' ends import and export declarations, import '...' is
+			// a bare import, and require('...') and import('...') are calls
+			if c != '`' && (prev == "from" || prev == "import" || prev == "(" && (prevPrev == "require" || prevPrev == "import")) {
+				specifiers = append(specifiers, value)
+			}
+			push("")
+		case isNodeIdentByte(c):
+			start := i
+			for i < len(code) && isNodeIdentByte(code[i]) {
+				i++
+			}
+			// A property such as x.require isn't the keyword or function
+			if prev == "." {
+				push("")
+			} else {
+				push(code[start:i])
+			}
+		default:
+			push(string(c))
+			i++
 		}
 	}
+	return specifiers
+}
 
-	// Find dynamic imports
-	for _, match := range nodeDynamicRe.FindAllStringSubmatch(code, -1) {
-		pkg := getBasePackage(match[1])
-		if !nodeStdLib[pkg] {
-			imports[pkg] = true
+// nodeStringLiteral reads the string or template literal code starts with,
+// returning its contents and its length including the quotes
+func nodeStringLiteral(code string) (string, int) {
+	quote := code[0]
+	var b strings.Builder
+	for i := 1; i < len(code); i++ {
+		switch code[i] {
+		case '\\':
+			if i+1 < len(code) {
+				i++
+				b.WriteByte(code[i])
+			}
+		case quote:
+			return b.String(), i + 1
+		case '\n':
+			// Quoted strings end at the line, only template literals span lines
+			if quote != '`' {
+				return b.String(), i
+			}
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(code[i])
 		}
 	}
+	return b.String(), len(code)
+}
 
-	return mapToSlice(imports)
+// isNodeIdentByte tells whether c can be part of a JavaScript identifier
+func isNodeIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
 }
 
-// ParseGoImports extracts non-standard library package imports from Go code
+// ParseGoImports extracts the modules Go code imports from outside the
+// standard library. Single and grouped import declarations are both read,
+// with or without a name, dot or blank identifier, and each import path is
+// cut down to the path of the module providing it.
 func ParseGoImports(code string) []string {
-	imports := make(map[string]bool)
+	modules := make(map[string]bool)
+	for _, path := range goImportPaths(code) {
+		if module := goModulePath(path); module != "" {
+			modules[module] = true
+		}
+	}
+	return mapToSlice(modules)
+}
 
-	// Find single-line imports
-	for _, match := range goSingleImportRe.FindAllStringSubmatch(code, -1) {
-		pkg := match[1]
-		if !goStdLib[pkg] {
-			imports[pkg] = true
+// goImportPaths returns the paths of the import declarations in Go code. The
+// code is tokenized rather than parsed, so it may hold several files one
+// after another and mistakes after the imports don't matter.
+func goImportPaths(code string) []string {
+	var s scanner.Scanner
+	src := []byte(code)
+	file := token.NewFileSet().AddFile("", -1, len(src))
+	s.Init(file, src, nil, 0) // Comments are skipped
+
+	var paths []string
+	next := func() token.Token {
+		_, tok, lit := s.Scan()
+		if tok == token.STRING {
+			if path, err := strconv.Unquote(lit); err == nil {
+				paths = append(paths, path)
+			}
+		}
+		return tok
+	}
+	for {
+		_, tok, _ := s.Scan()
+		if tok == token.EOF {
+			return paths
+		}
+		if tok != token.IMPORT {
+			continue
+		}
+		if tok = next(); tok != token.LPAREN {
+			// A single import, its path read either now or after its name
+			if tok == token.IDENT || tok == token.PERIOD {
+				next()
+			}
+			continue
+		}
+		for tok != token.RPAREN && tok != token.EOF {
+			tok = next()
 		}
 	}
+}
 
-	// Find imports in import groups
-	for _, match := range goGroupImportRe.FindAllStringSubmatch(code, -1) {
-		pkg := match[1]
-		if !goStdLib[pkg] {
-			imports[pkg] = true
+// goModulePath returns the module providing the package at an import path,
+// or "" for standard library and relative paths, whose first element has no
+// dot. Modules on hosting sites such as github.com are named after their
+// repository, gopkg.in modules after their versioned package, and modules on
+// other hosts after their first path element, the usual layout of vanity
+// import paths. A major version suffix such as /v2 is kept.
+func goModulePath(path string) string {
+	elems := strings.Split(path, "/")
+	if !strings.Contains(elems[0], ".") || strings.HasPrefix(path, ".") {
+		return ""
+	}
+	n := 2
+	switch {
+	case goRepoHosts[elems[0]]:
+		n = 3
+	case elems[0] == "gopkg.in" && len(elems) > 1 && !strings.Contains(elems[1], ".v"):
+		// gopkg.in/owner/pkg.v1 rather than gopkg.in/pkg.v1
+		n = 3
+	}
+	if len(elems) > n && goMajorVersionRe.MatchString(elems[n]) {
+		n++
+	}
+	if len(elems) < n {
+		return path
+	}
+	return strings.Join(elems[:n], "/")
+}
+
+// ParseRustImports extracts the crates a Rust snippet uses from its use and
+// extern crate statements, leaving out crates that ship with the compiler and
+// modules the snippet declares itself
+func ParseRustImports(code string) []string {
+	crates := make(map[string]bool)
+	modules := make(map[string]bool)
+	for _, match := range rustModRe.FindAllStringSubmatch(code, -1) {
+		modules[match[1]] = true
+	}
+
+	for _, re := range []*regexp.Regexp{rustUseRe, rustExternCrateRe} {
+		for _, match := range re.FindAllStringSubmatch(code, -1) {
+			if crate := match[1]; !rustBuiltinCrates[crate] && !modules[crate] {
+				crates[crate] = true
+			}
 		}
 	}
 
-	return mapToSlice(imports)
+	return mapToSlice(crates)
 }
 
 // Helper function to convert a map[string]bool to []string
@@ -177,8 +386,14 @@ func mapToSlice(m map[string]bool) []string {
 	return result
 }
 
-// Helper function to get the base package name from a Node.js import path
+// Helper function to get the base package name from a Node.js import path.
+// Relative and absolute paths are local files, "#" paths are the package's own
+// subpath imports and "node:" and "bun:" paths are built in, all giving "".
 func getBasePackage(path string) string {
+	if path == "" || strings.HasPrefix(path, ".") || strings.HasPrefix(path, "/") || strings.HasPrefix(path, "#") ||
+		strings.HasPrefix(path, "node:") || strings.HasPrefix(path, "bun:") {
+		return ""
+	}
 	// Handle scoped packages (@org/pkg)
 	if strings.HasPrefix(path, "@") {
 		parts := strings.Split(path, "/")
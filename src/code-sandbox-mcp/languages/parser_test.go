@@ -1,6 +1,9 @@
 package languages
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -16,7 +19,7 @@ func TestParsePythonImports(t *testing.T) {
 import requests
 import pandas as pd
 from PIL import Image`,
-			expected: []string{"requests", "pandas", "pillow"},
+			expected: []string{"requests", "pandas", "Pillow"},
 		},
 		{
 			name: "standard library only",
@@ -69,6 +72,80 @@ np = __import__('numpy')
 requests = __import__('requests')`,
 			expected: []string{"numpy", "requests"},
 		},
+		{
+			name: "requirements comment pins an import",
+			code: `
+# requirements: numpy==1.26.0, Scikit_Learn>=1.4
+import numpy as np
+import requests
+from sklearn import svm
+import scikit_learn`,
+			expected: []string{"numpy==1.26.0", "Scikit_Learn>=1.4", "requests"},
+		},
+		{
+			name: "standard library modules are excluded",
+			code: `
+from __future__ import annotations
+import asyncio, subprocess
+import xml.etree.ElementTree as ET
+from concurrent.futures import ThreadPoolExecutor
+from urllib.parse import urlparse
+import collections.abc
+import typing, dataclasses, functools, itertools, logging, sqlite3, tomllib
+import distutils`,
+			expected: []string{},
+		},
+		{
+			name: "import names mapped to their PyPI packages",
+			code: `
+import cv2
+from PIL import Image
+from sklearn.linear_model import LinearRegression
+from bs4 import BeautifulSoup
+import yaml
+from dateutil import parser`,
+			expected: []string{"opencv-python", "Pillow", "scikit-learn", "beautifulsoup4", "PyYAML", "python-dateutil"},
+		},
+		{
+			name: "submodules install their top-level package",
+			code: `
+import matplotlib.pyplot as plt
+from requests.adapters import HTTPAdapter
+from scipy.stats import norm`,
+			expected: []string{"matplotlib", "requests", "scipy"},
+		},
+		{
+			name: "several modules in one import",
+			code: `
+import os, requests as r, numpy.linalg`,
+			expected: []string{"requests", "numpy"},
+		},
+		{
+			name: "indented imports",
+			code: `
+try:
+    import ujson as json
+except ImportError:
+    import json
+
+def load():
+    from pandas import read_csv`,
+			expected: []string{"ujson", "pandas"},
+		},
+		{
+			name: "relative imports",
+			code: `
+from . import helpers
+from .models import User`,
+			expected: []string{},
+		},
+		{
+			name: "requirements comment without an import",
+			code: `
+# requirements: pandas>=2.0
+print("hello")`,
+			expected: []string{"pandas>=2.0"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -81,6 +158,42 @@ requests = __import__('requests')`,
 	}
 }
 
+func TestParseRequirementsComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected []string
+	}{
+		{
+			name:     "no comments",
+			code:     "import requests\nprint('hello')",
+			expected: nil,
+		},
+		{
+			name: "several comments with duplicates",
+			code: `# requirements: pandas>=2.0, requests
+import pandas
+#requirements: requests,  numpy==1.26.4 ,
+print("hello")`,
+			expected: []string{"pandas>=2.0", "requests", "numpy==1.26.4"},
+		},
+		{
+			name:     "indented comment is ignored",
+			code:     "def f():\n    # requirements: pandas\n    pass",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseRequirementsComments(tt.code)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ParseRequirementsComments() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestParseNodeImports(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -102,6 +215,14 @@ import { useState } from 'react';
 import * as d3 from 'd3';`,
 			expected: []string{"axios", "react", "d3"},
 		},
+		{
+			name: "local files",
+			code: `
+import { greet } from './helpers';
+const config = require('../config.json');
+import express from 'express';`,
+			expected: []string{"express"},
+		},
 		{
 			name: "built-in modules only",
 			code: `
@@ -141,6 +262,48 @@ const mod = await import('lodash');
 import('react').then(React => {});`,
 			expected: []string{"lodash", "react"},
 		},
+		{
+			name: "typescript imports",
+			code: `
+import { z } from "zod";
+import type { Request } from "express";
+import { type Db, connect } from "mongodb";
+import express, { Router } from "express";
+import pg = require("pg");
+export { debounce } from "lodash-es";
+export * from "./local";`,
+			expected: []string{"zod", "express", "mongodb", "pg", "lodash-es"},
+		},
+		{
+			name: "multi-line and side-effect imports",
+			code: `
+import "reflect-metadata";
+import {
+	Injectable,
+	Module,
+} from "@nestjs/common";
+import { Signal } from "@preact/signals-core/dist";`,
+			expected: []string{"reflect-metadata", "@nestjs/common", "@preact/signals-core"},
+		},
+		{
+			name: "built-in prefixes",
+			code: `
+import { readFile } from "node:fs/promises";
+import { test } from "bun:test";
+import * as fs from "fs/promises";
+import { worker } from "#internal/worker";
+const { spawn } = require("child_process");`,
+			expected: []string{},
+		},
+		{
+			name: "imports in strings and comments",
+			code: `
+/* import { a } from 'old-lib'; */
+const message = ` + "`import x from 'template-lib'`" + `;
+console.log("import y from 'string-lib'", client.require('not-a-call'));
+import chalk from 'chalk'; // require('trailing')`,
+			expected: []string{"chalk"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -226,6 +389,55 @@ import (
 )`,
 			expected: []string{"github.com/gin-gonic/gin", "gorm.io/gorm"},
 		},
+		{
+			name: "blank and named single imports",
+			code: `
+package main
+
+import _ "github.com/lib/pq"
+import pgx "github.com/jackc/pgx/v5"`,
+			expected: []string{"github.com/lib/pq", "github.com/jackc/pgx/v5"},
+		},
+		{
+			name: "packages are cut to their module",
+			code: `
+package main
+
+import (
+    "github.com/go-chi/chi/v5/middleware"
+    "github.com/go-chi/chi/v5"
+    "golang.org/x/sync/errgroup"
+    "gorm.io/gorm/clause"
+    "gopkg.in/yaml.v3"
+    "gopkg.in/src-d/go-git.v4/plumbing"
+)`,
+			expected: []string{"github.com/go-chi/chi/v5", "golang.org/x/sync", "gorm.io/gorm", "gopkg.in/yaml.v3", "gopkg.in/src-d/go-git.v4"},
+		},
+		{
+			name: "several files one after another",
+			code: `
+package main
+
+import "github.com/google/uuid"
+
+func main() {}
+package main
+
+import (
+    "fmt"
+    "github.com/spf13/cobra"
+)`,
+			expected: []string{"github.com/google/uuid", "github.com/spf13/cobra"},
+		},
+		{
+			name: "relative and cgo imports",
+			code: `
+package main
+
+import "C"
+import "./helpers"`,
+			expected: []string{},
+		},
 	}
 
 	for _, tt := range tests {
@@ -238,6 +450,83 @@ import (
 	}
 }
 
+func TestParseGoImportsFixture(t *testing.T) {
+	code, err := os.ReadFile(filepath.Join("testdata", "imports.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"github.com/go-chi/chi/v5",
+		"github.com/lib/pq",
+		"github.com/onsi/gomega",
+		"go.uber.org/zap",
+		"golang.org/x/sync",
+		"google.golang.org/grpc",
+		"gopkg.in/yaml.v3",
+		"example.com/sandbox",
+		"github.com/rs/zerolog",
+	}
+	if got := ParseGoImports(string(code)); !equalStringSlices(got, want) {
+		t.Errorf("ParseGoImports() = %v, want %v", got, want)
+	}
+}
+
+func TestParseRustImports(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected []string
+	}{
+		{
+			name: "use statements",
+			code: `
+use serde::{Deserialize, Serialize};
+use std::collections::HashMap;
+use rand::Rng;
+
+fn main() {}`,
+			expected: []string{"serde", "rand"},
+		},
+		{
+			name: "extern crate and nested use",
+			code: `
+extern crate regex;
+pub use ::serde_json::Value;
+use {itertools::Itertools, std::fmt};`,
+			expected: []string{"regex", "serde_json", "itertools"},
+		},
+		{
+			name: "standard library and path keywords only",
+			code: `
+use std::io;
+use core::fmt;
+use self::inner::Thing;
+use crate::util;
+use super::parent;`,
+			expected: []string{},
+		},
+		{
+			name: "local modules",
+			code: `
+mod geometry {
+    pub struct Point;
+}
+use geometry::Point;
+use anyhow::Result;`,
+			expected: []string{"anyhow"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseRustImports(tt.code)
+			if !equalStringSlices(got, tt.expected) {
+				t.Errorf("ParseRustImports() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
 // Helper function to compare string slices regardless of order
 func equalStringSlices(a, b []string) bool {
 	if len(a) != len(b) {
@@ -0,0 +1,45 @@
+// A program mixing the import forms ParseGoImports has to read, used as a
+// fixture by parser_test.go
+package main
+
+import "C"
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	chi "github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	_ "github.com/lib/pq"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/credentials/insecure"
+	yaml "gopkg.in/yaml.v3"
+
+	// "github.com/commented/out"
+	"example.com/sandbox/internal/store"
+)
+
+import zerolog "github.com/rs/zerolog/log"
+
+/*
+import "github.com/also/commented"
+*/
+
+func main() {
+	ctx := context.Background()
+	_ = chi.NewRouter()
+	_ = middleware.Logger
+	_ = zapcore.DebugLevel
+	_, _ = errgroup.WithContext(ctx)
+	_ = insecure.NewCredentials()
+	_, _ = yaml.Marshal(nil)
+	_ = store.Open
+	zerolog.Info().Msg("started")
+	json.NewEncoder(os.Stdout).Encode(http.StatusOK)
+	fmt.Println(`import "github.com/not/an/import"`)
+}
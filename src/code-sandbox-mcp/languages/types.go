@@ -1,16 +1,53 @@
 package languages
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
 // Language represents a supported programming language
 type Language string
 type LanguageList []Language
 
 // Supported languages
 const (
-	Python Language = "python"
-	Go     Language = "go"
-	NodeJS Language = "nodejs"
+	Python     Language = "python"
+	Go         Language = "go"
+	NodeJS     Language = "nodejs"
+	TypeScript Language = "typescript"
+	Dart       Language = "dart"
+	OCaml      Language = "ocaml"
+	Fortran    Language = "fortran"
+	Rust       Language = "rust"
+	Java       Language = "java"
+	Bash       Language = "bash"
+	C          Language = "c"
+	Cpp        Language = "cpp"
 )
 
+// languageAliases maps common alternative names to supported languages
+var languageAliases = map[string]Language{
+	"py":         Python,
+	"python3":    Python,
+	"golang":     Go,
+	"node":       NodeJS,
+	"node.js":    NodeJS,
+	"js":         NodeJS,
+	"javascript": NodeJS,
+	"ts":         TypeScript,
+	"ml":         OCaml,
+	"f90":        Fortran,
+	"gfortran":   Fortran,
+	"rs":         Rust,
+	"jdk":        Java,
+	"sh":         Bash,
+	"shell":      Bash,
+	"c++":        Cpp,
+	"cxx":        Cpp,
+}
+
 // Language configurations
 type LanguageConfig struct {
 	Image string // Docker image to use
@@ -19,13 +56,26 @@ type LanguageConfig struct {
 	InstallCommand  []string // Command to install dependencies (e.g., pip install -r requirements.txt)
 	RunCommand      []string // Run command
 	FileExtension   string   // File extension for the language
+	CacheDir        string   // Package manager cache directory inside the image, empty if not cached
+	// Time a run gets, including installing dependencies, when the caller
+	// doesn't set a timeout. Compiled languages get longer for the build step.
+	DefaultTimeout time.Duration
+	// Resource limits a run gets when the caller doesn't set them. Runtimes
+	// that compile or load large libraries get more memory.
+	DefaultMemoryMB int64
+	DefaultCPU      float64
+	// User the container runs as, for images whose own user can't read the
+	// mounted code or write artifacts. Empty keeps the image's user.
+	User string
+	// Environment the language's commands need in every container
+	Env []string
 }
 
 // AllLanguages contains all supported languages in a specific order
-var AllLanguages = LanguageList{Python, Go, NodeJS}
+var AllLanguages = LanguageList{Python, Go, NodeJS, TypeScript, Dart, OCaml, Fortran, Rust, Java, Bash, C, Cpp}
 
 // SupportedLanguages maps Language to their configurations
-// IMPORTANT: We can only support Python, Go, and NodeJS projects.
+// IMPORTANT: We can only support Python, Go, NodeJS, TypeScript, Dart, OCaml, Fortran, Rust, Java, Bash, C and C++ projects.
 // The isProjectDirectory function may detect other project types, but they cannot be run.
 var SupportedLanguages = map[Language]LanguageConfig{
 	Python: {
@@ -34,20 +84,150 @@ var SupportedLanguages = map[Language]LanguageConfig{
 		InstallCommand:  []string{"uv", "pip", "install", "--system", "-r", "requirements.txt"},
 		RunCommand:      []string{"python3", "main.py"},
 		FileExtension:   "py",
+		CacheDir:        "/root/.cache/uv",
+		DefaultTimeout:  60 * time.Second,
+		DefaultMemoryMB: 1024,
+		DefaultCPU:      1.0,
 	},
 	Go: {
 		Image:           "docker.io/library/golang:1.23.6-bookworm",
 		DependencyFiles: []string{"go.mod"},
-		InstallCommand:  []string{"go", "mod", "tidy"},
+		InstallCommand:  []string{"go", "mod", "download"},
 		RunCommand:      []string{"go", "run", "main.go"},
 		FileExtension:   "go",
+		CacheDir:        "/go/pkg/mod",
+		DefaultTimeout:  120 * time.Second,
+		DefaultMemoryMB: 1024,
+		DefaultCPU:      2.0,
 	},
+	// Node.js snippets are saved as main.ts, as they were before TypeScript
+	// became a language of its own. Bun runs plain JavaScript from a .ts
+	// file too, so TypeScript sent as nodejs keeps working.
 	NodeJS: {
 		Image:           "oven/bun:debian",
 		DependencyFiles: []string{"package.json"},
 		InstallCommand:  []string{"npm", "install"},
 		RunCommand:      []string{"bun", "run", "main.ts"},
 		FileExtension:   "ts",
+		CacheDir:        "/root/.bun/install/cache",
+		DefaultTimeout:  60 * time.Second,
+		DefaultMemoryMB: 512,
+		DefaultCPU:      1.0,
+	},
+	// Bun runs TypeScript directly, stripping the types without checking
+	// them, so TypeScript shares the JavaScript image and packages.
+	TypeScript: {
+		Image:           "oven/bun:debian",
+		DependencyFiles: []string{"package.json"},
+		InstallCommand:  []string{"npm", "install"},
+		RunCommand:      []string{"bun", "run", "main.ts"},
+		FileExtension:   "ts",
+		CacheDir:        "/root/.bun/install/cache",
+		DefaultTimeout:  60 * time.Second,
+		DefaultMemoryMB: 512,
+		DefaultCPU:      1.0,
+	},
+	// Plain Dart only. Flutter needs its own SDK image and `flutter pub get`,
+	// so it would be added as a separate entry rather than a mode of this one.
+	Dart: {
+		Image:           "docker.io/library/dart:stable",
+		DependencyFiles: []string{"pubspec.yaml"},
+		InstallCommand:  []string{"dart", "pub", "get"},
+		RunCommand:      []string{"dart", "run", "/app/main.dart"},
+		FileExtension:   "dart",
+		CacheDir:        "/root/.pub-cache",
+		DefaultTimeout:  120 * time.Second,
+		DefaultMemoryMB: 1024,
+		DefaultCPU:      1.0,
+	},
+	// The opam images run as the non-root opam user, which can't read the
+	// code or write artifacts in directories mounted from the host, so runs
+	// use root with the image's opam root. Commands call `opam exec --`
+	// themselves to put the OCaml toolchain on the PATH, as pooled
+	// containers replace the image's entrypoint. Compile errors are
+	// reported on stderr.
+	OCaml: {
+		Image:           "docker.io/ocaml/opam:debian-12-ocaml-5.2",
+		DependencyFiles: []string{"dune-project"},
+		InstallCommand:  []string{"opam", "exec", "--", "dune", "build"},
+		RunCommand:      []string{"opam", "exec", "--", "ocaml", "/app/main.ml"},
+		FileExtension:   "ml",
+		DefaultTimeout:  180 * time.Second,
+		DefaultMemoryMB: 512,
+		DefaultCPU:      1.0,
+		User:            "root",
+		Env:             []string{"OPAMROOT=/home/opam/.opam"},
+	},
+	// The gcc image ships gfortran and make. Snippets are compiled outside the
+	// work directory so the binary doesn't show up next to the source.
+	Fortran: {
+		Image:           "docker.io/library/gcc:14",
+		DependencyFiles: []string{"Makefile", "CMakeLists.txt"},
+		InstallCommand:  []string{"make"},
+		RunCommand:      []string{"/bin/sh", "-c", "gfortran -o /tmp/a.out main.f90 && /tmp/a.out"},
+		FileExtension:   "f90",
+		DefaultTimeout:  120 * time.Second,
+		DefaultMemoryMB: 512,
+		DefaultCPU:      1.0,
+	},
+	// Snippets without crates are compiled with rustc outside the work
+	// directory. Snippets using crates get a generated Cargo.toml and run with cargo.
+	Rust: {
+		Image:           "docker.io/library/rust:slim",
+		DependencyFiles: []string{"Cargo.toml"},
+		InstallCommand:  []string{"cargo", "fetch"},
+		RunCommand:      []string{"/bin/sh", "-c", "rustc -o /tmp/main main.rs && /tmp/main"},
+		FileExtension:   "rs",
+		CacheDir:        "/usr/local/cargo/registry",
+		DefaultTimeout:  180 * time.Second,
+		DefaultMemoryMB: 1024,
+		DefaultCPU:      2.0,
+	},
+	// The Maven image is eclipse-temurin with mvn on top, so it builds Maven
+	// projects as well as snippets. Snippets are written to a file named after
+	// their public class and compiled outside the work directory, see javaRun.
+	Java: {
+		Image:           "docker.io/library/maven:3.9-eclipse-temurin-21",
+		DependencyFiles: []string{"pom.xml"},
+		InstallCommand:  []string{"mvn", "-q", "package"},
+		RunCommand:      []string{"/bin/sh", "-c", "javac -d /tmp/classes Main.java && java -cp /tmp/classes Main"},
+		FileExtension:   "java",
+		CacheDir:        "/root/.m2/repository",
+		DefaultTimeout:  180 * time.Second,
+		DefaultMemoryMB: 1024,
+		DefaultCPU:      2.0,
+	},
+	// C and C++ use the same gcc image as Fortran. Snippets are compiled into
+	// /tmp and run from there, projects are built with make or CMake first.
+	C: {
+		Image:           "docker.io/library/gcc:14",
+		DependencyFiles: []string{"Makefile", "CMakeLists.txt"},
+		InstallCommand:  []string{"make"},
+		RunCommand:      []string{"/bin/sh", "-c", "gcc -o /tmp/a.out main.c -lm && /tmp/a.out"},
+		FileExtension:   "c",
+		DefaultTimeout:  120 * time.Second,
+		DefaultMemoryMB: 512,
+		DefaultCPU:      1.0,
+	},
+	Cpp: {
+		Image:           "docker.io/library/gcc:14",
+		DependencyFiles: []string{"Makefile", "CMakeLists.txt"},
+		InstallCommand:  []string{"make"},
+		RunCommand:      []string{"/bin/sh", "-c", "g++ -o /tmp/a.out main.cpp && /tmp/a.out"},
+		FileExtension:   "cpp",
+		DefaultTimeout:  120 * time.Second,
+		DefaultMemoryMB: 1024,
+		DefaultCPU:      1.0,
+	},
+	// Shell scripts have no dependency files or install step. The bash image
+	// is Alpine based, so busybox provides the usual utilities.
+	Bash: {
+		Image:           "docker.io/library/bash:5",
+		RunCommand:      []string{"bash", "main.sh"},
+		FileExtension:   "sh",
+		DefaultTimeout:  60 * time.Second,
+		DefaultMemoryMB: 256,
+		DefaultCPU:      1.0,
 	},
 }
 
@@ -56,6 +236,12 @@ func (l Language) String() string {
 	return string(l)
 }
 
+// UsesNPM tells whether the language runs on a Node.js compatible runtime
+// and installs its packages from npm, as JavaScript and TypeScript do
+func (l Language) UsesNPM() bool {
+	return l == NodeJS || l == TypeScript
+}
+
 // IsValid checks if the language is supported
 func (l Language) IsValid() bool {
 	for _, valid := range AllLanguages {
@@ -66,6 +252,25 @@ func (l Language) IsValid() bool {
 	return false
 }
 
+// ParseLanguage normalizes a language name as sent by a client, accepting any
+// casing and common aliases such as "js" or "py"
+func ParseLanguage(name string) (Language, error) {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	if lang := Language(normalized); lang.IsValid() {
+		return lang, nil
+	}
+	if lang, ok := languageAliases[normalized]; ok {
+		return lang, nil
+	}
+
+	accepted := AllLanguages.ToArray()
+	for alias := range languageAliases {
+		accepted = append(accepted, alias)
+	}
+	sort.Strings(accepted[len(AllLanguages):])
+	return "", fmt.Errorf("unsupported language %q, accepted names are: %s", name, strings.Join(accepted, ", "))
+}
+
 // ToArray converts the AllLanguages slice to an array of strings
 func (l LanguageList) ToArray() []string {
 	result := make([]string, len(l))
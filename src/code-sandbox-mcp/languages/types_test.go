@@ -0,0 +1,49 @@
+package languages
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLanguage(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    Language
+		errContains string
+	}{
+		{name: "exact name", input: "python", expected: Python},
+		{name: "upper case", input: "PYTHON", expected: Python},
+		{name: "mixed case with whitespace", input: "  NodeJS ", expected: NodeJS},
+		{name: "js alias", input: "js", expected: NodeJS},
+		{name: "typescript", input: "TypeScript", expected: TypeScript},
+		{name: "ts alias", input: "ts", expected: TypeScript},
+		{name: "py alias", input: "py", expected: Python},
+		{name: "golang alias", input: "Golang", expected: Go},
+		{name: "f90 alias", input: "F90", expected: Fortran},
+		{name: "java", input: "Java", expected: Java},
+		{name: "sh alias", input: "sh", expected: Bash},
+		{name: "c", input: "C", expected: C},
+		{name: "c++ alias", input: "c++", expected: Cpp},
+		{name: "unknown language", input: "cobol", errContains: "accepted names are: python, go"},
+		{name: "empty", input: "", errContains: "unsupported language"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLanguage(tt.input)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("ParseLanguage(%q) error = %v, want error containing %q", tt.input, err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseLanguage(%q) unexpected error: %v", tt.input, err)
+			}
+			if got != tt.expected {
+				t.Errorf("ParseLanguage(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
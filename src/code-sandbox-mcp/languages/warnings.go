@@ -0,0 +1,59 @@
+package languages
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Patterns for the lines compilers and runtimes use to report non-fatal
+// warnings on stderr. Lines that don't match are treated as errors or output.
+var warningPatterns = map[Language]*regexp.Regexp{
+	// e.g. "main.py:3: DeprecationWarning: ..." from the warnings module
+	Python: regexp.MustCompile(`^\S+:\d+: \w*Warning: `),
+	// e.g. "./main.go:5:2: warning: ..." from cgo
+	Go: regexp.MustCompile(`(?i)^\S+:\d+(:\d+)?: warning: `),
+	// e.g. "warn: ..." from bun or "(node:12) Warning: ..." from node
+	NodeJS:     regexp.MustCompile(`^(warn: |\(node:\d+\) \w*Warning: )`),
+	TypeScript: regexp.MustCompile(`^(warn: |\(node:\d+\) \w*Warning: )`),
+	// e.g. "bin/main.dart:3:7: Warning: ..."
+	Dart: regexp.MustCompile(`^\S+:\d+:\d+: Warning: `),
+	// e.g. "Warning 26 [unused-var]: unused variable x."
+	OCaml: regexp.MustCompile(`^Warning \d+( \[[\w-]+\])?: `),
+	// e.g. "Warning: Unused variable 'x' declared at (1) [-Wunused-variable]"
+	Fortran: regexp.MustCompile(`^Warning: `),
+	// e.g. "warning: unused variable: `x`" from rustc or cargo
+	Rust: regexp.MustCompile(`^warning: `),
+	// e.g. "Main.java:5: warning: [removal] ..." from javac
+	Java: regexp.MustCompile(`^\S+\.java:\d+: warning: `),
+	// e.g. "main.c:3:9: warning: unused variable 'x' [-Wunused-variable]" from gcc or g++
+	C:   regexp.MustCompile(`^\S+:\d+:\d+: warning: `),
+	Cpp: regexp.MustCompile(`^\S+:\d+:\d+: warning: `),
+}
+
+// ocamlLocationRe matches the location line OCaml prints before a warning
+var ocamlLocationRe = regexp.MustCompile(`^File "[^"]+", line \d+`)
+
+// ExtractWarnings returns the warnings a language's toolchain reported in
+// stderr, one per entry. Classification is heuristic and errors are never
+// returned as warnings.
+func ExtractWarnings(language Language, stderr string) []string {
+	pattern, ok := warningPatterns[language]
+	if !ok {
+		return nil
+	}
+
+	var warnings []string
+	lines := strings.Split(stderr, "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if !pattern.MatchString(line) {
+			continue
+		}
+		// OCaml puts the location on the line before the warning
+		if language == OCaml && i > 0 && ocamlLocationRe.MatchString(lines[i-1]) {
+			line = strings.TrimRight(lines[i-1], "\r") + " " + line
+		}
+		warnings = append(warnings, line)
+	}
+	return warnings
+}
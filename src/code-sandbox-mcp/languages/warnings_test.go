@@ -0,0 +1,76 @@
+package languages
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractWarnings(t *testing.T) {
+	tests := []struct {
+		name     string
+		language Language
+		stderr   string
+		want     []string
+	}{
+		{
+			name:     "python warnings module",
+			language: Python,
+			stderr:   "main.py:3: DeprecationWarning: datetime.utcnow() is deprecated\n  now = datetime.utcnow()\n",
+			want:     []string{"main.py:3: DeprecationWarning: datetime.utcnow() is deprecated"},
+		},
+		{
+			name:     "python error is not a warning",
+			language: Python,
+			stderr:   "Traceback (most recent call last):\n  File \"main.py\", line 1, in <module>\nNameError: name 'x' is not defined\n",
+		},
+		{
+			name:     "dart warning and error",
+			language: Dart,
+			stderr:   "bin/main.dart:3:7: Warning: Operand of null-aware operation '?.' has type 'String'.\nbin/main.dart:5:1: Error: Expected ';' after this.\n",
+			want:     []string{"bin/main.dart:3:7: Warning: Operand of null-aware operation '?.' has type 'String'."},
+		},
+		{
+			name:     "ocaml warning with location",
+			language: OCaml,
+			stderr:   "File \"main.ml\", line 1, characters 4-5:\r\nWarning 26 [unused-var]: unused variable x.\r\n",
+			want:     []string{"File \"main.ml\", line 1, characters 4-5: Warning 26 [unused-var]: unused variable x."},
+		},
+		{
+			name:     "fortran compiler warning",
+			language: Fortran,
+			stderr:   "main.f90:3:12:\n\n    3 |   x = 1.5\n      |            1\nWarning: Change of value in conversion from 'REAL(4)' to 'INTEGER(4)' at (1) [-Wconversion]\n",
+			want:     []string{"Warning: Change of value in conversion from 'REAL(4)' to 'INTEGER(4)' at (1) [-Wconversion]"},
+		},
+		{
+			name:     "gcc warning and error",
+			language: C,
+			stderr:   "main.c: In function 'main':\nmain.c:3:9: warning: unused variable 'x' [-Wunused-variable]\n    3 |     int x;\n      |         ^\nmain.c:4:5: error: expected ';' before 'return'\n",
+			want:     []string{"main.c:3:9: warning: unused variable 'x' [-Wunused-variable]"},
+		},
+		{
+			name:     "javac warning and error",
+			language: Java,
+			stderr:   "Main.java:3: warning: [removal] Integer(int) in Integer has been deprecated and marked for removal\nMain.java:5: error: ';' expected\n",
+			want:     []string{"Main.java:3: warning: [removal] Integer(int) in Integer has been deprecated and marked for removal"},
+		},
+		{
+			name:     "node process warning",
+			language: NodeJS,
+			stderr:   "(node:42) ExperimentalWarning: The Fetch API is an experimental feature.\n",
+			want:     []string{"(node:42) ExperimentalWarning: The Fetch API is an experimental feature."},
+		},
+		{
+			name:     "go compile error is not a warning",
+			language: Go,
+			stderr:   "# command-line-arguments\n./main.go:5:2: declared and not used: x\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractWarnings(tt.language, tt.stderr); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractWarnings() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Package logging writes the server's diagnostics to stderr. Under the stdio
+// transport stdout carries the MCP protocol, so nothing else may write to it.
+package logging
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Level is the severity of a message. Messages below the configured level are
+// dropped.
+type Level int32
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelOff
+)
+
+// DefaultLevel keeps the server quiet unless something goes wrong
+const DefaultLevel = LevelWarn
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+	LevelOff:   "off",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// ParseLevel parses a level name such as "debug" or "warn", case-insensitively.
+// "warning" is accepted for warn.
+func ParseLevel(name string) (Level, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "warning" {
+		return LevelWarn, nil
+	}
+	for level, levelName := range levelNames {
+		if name == levelName {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q, expected debug, info, warn, error or off", name)
+}
+
+var (
+	logger = log.New(os.Stderr, "", log.LstdFlags)
+	level  atomic.Int32
+)
+
+func init() {
+	level.Store(int32(DefaultLevel))
+}
+
+// SetLevel sets the lowest level of messages that are written
+func SetLevel(l Level) {
+	level.Store(int32(l))
+}
+
+// Enabled reports whether messages at l are written
+func Enabled(l Level) bool {
+	return l >= Level(level.Load())
+}
+
+func logf(l Level, format string, args ...interface{}) {
+	if !Enabled(l) {
+		return
+	}
+	logger.Printf(strings.ToUpper(l.String())+" "+format, args...)
+}
+
+// Debugf logs details that are only useful when tracking down a problem
+func Debugf(format string, args ...interface{}) {
+	logf(LevelDebug, format, args...)
+}
+
+// Infof logs what the server is doing
+func Infof(format string, args ...interface{}) {
+	logf(LevelInfo, format, args...)
+}
+
+// Warnf logs a failure the server recovered from
+func Warnf(format string, args ...interface{}) {
+	logf(LevelWarn, format, args...)
+}
+
+// Errorf logs a failure the server could not recover from
+func Errorf(format string, args ...interface{}) {
+	logf(LevelError, format, args...)
+}
@@ -0,0 +1,43 @@
+package logging
+
+import "testing"
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Level
+		wantErr bool
+	}{
+		{name: "debug", want: LevelDebug},
+		{name: "INFO", want: LevelInfo},
+		{name: "warning", want: LevelWarn},
+		{name: " error ", want: LevelError},
+		{name: "off", want: LevelOff},
+		{name: "verbose", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLevel(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevel(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseLevel(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	defer SetLevel(DefaultLevel)
+
+	SetLevel(LevelWarn)
+	if Enabled(LevelInfo) || !Enabled(LevelWarn) || !Enabled(LevelError) {
+		t.Error("warn level should drop info and keep warn and error")
+	}
+	SetLevel(LevelOff)
+	if Enabled(LevelError) {
+		t.Error("off should drop every message")
+	}
+}
@@ -2,15 +2,22 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/Automata-Labs-team/code-sandbox-mcp/installer"
 	deps "github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+	"github.com/Automata-Labs-team/code-sandbox-mcp/logging"
 	"github.com/Automata-Labs-team/code-sandbox-mcp/resources"
+	"github.com/Automata-Labs-team/code-sandbox-mcp/streamable"
 	"github.com/Automata-Labs-team/code-sandbox-mcp/tools"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -25,10 +32,78 @@ func GenerateEnumTag() string {
 	return strings.Join(tags, ",")
 }
 
+// Command line flags are declared at package level so they are all
+// registered before parseFlags parses them
+var (
+	installFlag  = flag.Bool("install", false, "Add this binary to Claude Desktop config")
+	noUpdateFlag = flag.Bool("no-update", false, "Disable auto-update check")
+	port         = flag.String("port", "9520", "Port to listen on")
+	transport    = flag.String("transport", "stdio", "Transport to use (stdio, sse, http). http serves the Streamable HTTP transport at /mcp")
+	serverName   = flag.String("server-name", "code-sandbox-mcp", "Server name reported to MCP clients")
+	instanceFlag = flag.String("instance-id", "", "ID the server's containers are labelled with, so startup cleanup leaves those of other servers on the same Docker daemon alone (default: the server name)")
+	serverVer    = flag.String("server-version", "v1.0.0", "Server version reported to MCP clients")
+	reapFlag     = flag.String("reap", "keep-running", "Startup cleanup of containers left by a previous instance (keep-running, reap-all)")
+	lockdownFlag = flag.Bool("lockdown", false, "Refuse options that weaken the sandbox: allowDockerAccess, allowNetwork, custom seccomp profiles and readOnlyFiles")
+	previewLines = flag.Int("preview-lines", 10, "Maximum lines of a text artifact to include inline in results (0 disables previews)")
+	previewBytes = flag.Int("preview-bytes", 1024, "Maximum bytes of a text artifact to include inline in results (0 disables previews)")
+	inlineImages = flag.Int64("inline-image-kb", resources.DefaultMaxInlineImageBytes/1024, "Maximum size in KB of an image artifact returned inline as image content (0 disables inline images)")
+	ephemeralTTL = flag.Duration("ephemeral-artifact-ttl", resources.DefaultEphemeralArtifactTTL, "How long artifacts of runs with retainArtifacts=false stay available")
+	artifactTTL  = flag.Duration("artifact-ttl", resources.DefaultArtifactTTL, "How long a finished run's artifacts and logs are kept before they are deleted, 0 to keep them")
+	maxArtifacts = flag.Int64("max-artifacts-total-mb", resources.DefaultMaxTotalArtifactBytes/(1024*1024), "Maximum combined size in MB of the artifacts collected for a run")
+	maxArtifact  = flag.Int64("max-artifact-file-mb", resources.DefaultMaxArtifactFileBytes/(1024*1024), "Maximum size in MB of a single artifact collected for a run")
+	maxPulls     = flag.Int("max-pulls", tools.DefaultMaxConcurrentPulls, "Maximum number of concurrent image pulls")
+	poolSize     = flag.Int("container-pool-size", 0, "Maximum number of idle containers kept warm for run_code snippets that install nothing, 0 to start a new container for every run")
+	poolIdle     = flag.Duration("container-pool-idle", tools.DefaultContainerPoolIdle, "How long an idle pooled container is kept before it is removed")
+	imageRefresh = flag.Duration("image-refresh-interval", tools.DefaultImageRefreshInterval, "How long a local image is used before it is pulled again, 0 to never pull images that are already local")
+	depCache     = flag.Bool("dependency-cache", false, "Share package manager caches between runs using Docker volumes")
+	recordRuns   = flag.Bool("record-runs", false, "Write a JSON record of every finished run next to its artifacts")
+	artifactDir  = flag.String("artifact-store-dir", resources.DefaultArtifactStoreDir, "Directory runs' artifacts, saved output and run records are kept in")
+	maxRuns      = flag.Int("max-concurrent-runs", 0, "Maximum number of runs executing at the same time, further runs waiting for a slot, 0 for no limit")
+	safeModeFlag = flag.Bool("safe-mode", false, "Refuse to mount or write to sensitive host paths such as /etc and ~/.ssh")
+	denyPaths    = flag.String("deny-paths", "", "Comma-separated host paths to refuse in safe mode, in addition to the built-in list")
+	allowPaths   = flag.String("allow-paths", "", "Comma-separated host directories projectDir and outputPath must be inside (default: no restriction)")
+	rateLimit    = flag.Float64("rate-limit", 0, "Maximum tool calls per second across all clients, 0 for no limit")
+	rateBurst    = flag.Int("rate-burst", 10, "Tool calls allowed in a burst above --rate-limit")
+	pipIndexURL  = flag.String("pip-index-url", "", "Python package index for dependency installs, e.g. an internal mirror (default: $PIP_INDEX_URL, else PyPI)")
+	npmRegistry  = flag.String("npm-registry", "", "npm registry for Node.js dependency installs (default: $npm_config_registry, else the public registry)")
+	regToken     = flag.String("registry-token", "", "Registry credentials passed to installs as REGISTRY_TOKEN and masked in run output")
+	allowImages  = flag.String("allowed-images", "", "Comma-separated images the image parameter may name, an entry ending in * allowing every image with that prefix (default: $ALLOWED_IMAGES, else any image)")
+	logLevel     = flag.String("log-level", "", "Lowest level of diagnostics written to stderr (debug, info, warn, error, off) (default: $LOG_LEVEL, else warn)")
+	remoteDocker = flag.String("remote-docker", tools.RemoteDockerAuto, "Copy code, projects and artifacts over the Docker API instead of bind mounts (auto, always, never). auto does so unless DOCKER_HOST is a local socket")
+)
+
+// templateFlags collects repeated --command-template language=template flags
+type templateFlags map[string]string
+
+func (t templateFlags) String() string {
+	return fmt.Sprint(map[string]string(t))
+}
+
+func (t templateFlags) Set(value string) error {
+	language, template, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("expected language=template, got %q", value)
+	}
+	t[language] = template
+	return nil
+}
+
+// commandTemplates holds the --command-template flags
+var commandTemplates = templateFlags{}
+
 func init() {
-	// Check for --install flag
-	installFlag := flag.Bool("install", false, "Add this binary to Claude Desktop config")
-	noUpdateFlag := flag.Bool("no-update", false, "Disable auto-update check")
+	flag.Var(commandTemplates, "command-template", "Shell template chaining a language's install step and run command, as language={install} && {run}. May be repeated")
+}
+
+// parseFlags reads the configuration from the environment and the command
+// line, then handles --install and the update check
+func parseFlags() {
+	// Every flag can also be set from the environment, for deployments where
+	// that is the natural place for configuration
+	if err := applyEnvConfig(flag.CommandLine); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 	flag.Parse()
 
 	if *installFlag {
@@ -45,20 +120,84 @@ func init() {
 			fmt.Fprintf(os.Stderr, "Warning: Failed to check for updates: %v\n", err)
 			os.Exit(1)
 		} else if hasUpdate {
-			fmt.Println("Updating to new version...")
+			fmt.Fprintln(os.Stderr, "Updating to new version...")
 			if err := installer.PerformUpdate(downloadURL); err != nil {
 				fmt.Fprintf(os.Stderr, "Warning: Failed to update: %v\n", err)
 			}
-			fmt.Println("Update complete. Restarting...")
+			fmt.Fprintln(os.Stderr, "Update complete. Restarting...")
 		}
 	}
 }
 
 func main() {
-	port := flag.String("port", "9520", "Port to listen on")
-	transport := flag.String("transport", "stdio", "Transport to use (stdio, sse)")
-	flag.Parse()
-	s := server.NewMCPServer("code-sandbox-mcp", "v1.0.0", server.WithLogging(), server.WithResourceCapabilities(true, true), server.WithPromptCapabilities(false))
+	parseFlags()
+	if level := firstNonEmpty(*logLevel, os.Getenv("LOG_LEVEL")); level != "" {
+		parsed, err := logging.ParseLevel(level)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --log-level: %v\n", err)
+			os.Exit(1)
+		}
+		logging.SetLevel(parsed)
+	}
+	tools.SetMaxConcurrentPulls(*maxPulls)
+	tools.SetMaxConcurrentRuns(*maxRuns)
+	tools.SetImageRefreshInterval(*imageRefresh)
+	tools.SetContainerPool(*poolSize, *poolIdle)
+	tools.SetLockdown(*lockdownFlag)
+	tools.SetDependencyCache(*depCache)
+	tools.SetSafeMode(*safeModeFlag, strings.Split(*denyPaths, ","))
+	tools.SetAllowedPaths(strings.Split(*allowPaths, ","))
+	tools.SetAllowedImages(strings.Split(firstNonEmpty(*allowImages, os.Getenv("ALLOWED_IMAGES")), ","))
+	tools.SetRateLimit(*rateLimit, *rateBurst)
+	tools.SetPackageRegistries(tools.PackageRegistries{
+		PipIndexURL: firstNonEmpty(*pipIndexURL, os.Getenv("PIP_INDEX_URL")),
+		NPMRegistry: firstNonEmpty(*npmRegistry, os.Getenv("npm_config_registry"), os.Getenv("NPM_CONFIG_REGISTRY")),
+		Token:       *regToken,
+	})
+	for language, template := range commandTemplates {
+		parsed, err := deps.ParseLanguage(language)
+		if err == nil {
+			err = tools.SetCommandTemplate(parsed, template)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --command-template: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := tools.SetRemoteDocker(*remoteDocker); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := resources.SetArtifactStoreDir(*artifactDir); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	resources.SetRunRecording(*recordRuns)
+	resources.SetPreviewLimits(*previewLines, *previewBytes)
+	resources.SetMaxInlineImageBytes(*inlineImages * 1024)
+	resources.SetMaxTotalArtifactBytes(*maxArtifacts * 1024 * 1024)
+	resources.SetMaxArtifactFileBytes(*maxArtifact * 1024 * 1024)
+	resources.SetEphemeralArtifactTTL(*ephemeralTTL)
+	resources.SetArtifactTTL(*artifactTTL)
+	tools.SetLogStreaming(tools.TransportStreamsLogs(*transport))
+
+	// Clean up containers orphaned by a crashed prior instance
+	if *instanceFlag != "" {
+		tools.SetInstanceID(*instanceFlag)
+	} else {
+		tools.SetInstanceID(*serverName)
+	}
+	switch *reapFlag {
+	case "keep-running", "reap-all":
+		if err := tools.ReconcileContainers(context.Background(), *reapFlag == "reap-all"); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to reconcile containers: %v\n", err)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid value for --reap: %s\n", *reapFlag)
+		os.Exit(1)
+	}
+
+	s := server.NewMCPServer(*serverName, *serverVer, server.WithLogging(), server.WithResourceCapabilities(true, true), server.WithPromptCapabilities(false))
 	s.AddNotificationHandler("notifications/error", handleNotification)
 
 	// Register a tool to run code in a docker container
@@ -72,11 +211,22 @@ func main() {
 				"Returns the execution logs of the container and any generated artifacts.\n\n"+
 				"To save output files, write them to the /artifacts directory:\n"+
 				"Example: `plt.savefig('/artifacts/plot.png')`\n\n"+
-				"You can specify an outputPath parameter to save artifacts to a specific directory.",
+				"You can specify an outputPath parameter to save artifacts to a specific directory.\n\n"+
+				"The code has no network access unless allowNetwork is set.",
 		),
 		mcp.WithString("code",
-			mcp.Required(),
-			mcp.Description("The code to run"),
+			mcp.Description("The code to run. Either code, filePath or files is required"),
+		),
+		mcp.WithString("filePath",
+			mcp.Description("Full path of a host file to run instead of passing code inline. Its extension is kept when the language accepts it, e.g. .tsx or .f95"),
+		),
+		mcp.WithString("files",
+			mcp.Description("Optional JSON object of relative file names to contents, written to the work directory next to the code, "+
+				"e.g. `{\"helpers.py\": \"def greet(): ...\", \"data/input.csv\": \"a,b\\n1,2\"}`. "+
+				"Names may only contain letters, digits, '.', '_', '-' and '/', and must stay inside the work directory"),
+		),
+		mcp.WithString("entrypoint",
+			mcp.Description("Optional name of the file among files to run instead of the code. Defaults to main.<ext> when only files are given"),
 		),
 		mcp.WithString("language",
 			mcp.Required(),
@@ -86,6 +236,85 @@ func main() {
 		mcp.WithString("outputPath",
 			mcp.Description("Optional full path to a directory where artifacts will be saved"),
 		),
+		mcp.WithString("artifactPaths",
+			mcp.Description("Comma-separated absolute in-container directories to collect artifacts from in addition to /artifacts, e.g. `/output,/app/dist`. "+
+				"Files are collected by name; a file named like one already in /artifacts is skipped"),
+		),
+		mcp.WithString("stdin",
+			mcp.Description("Optional input written to the program's standard input, e.g. for `input()` in Python or `scanf` in C. Standard input is closed after it, so the program reads end of file"),
+		),
+		mcp.WithBoolean("retainArtifacts",
+			mcp.Description("Keep the run's artifacts in the persistent store (default true). "+
+				"When false, artifacts are only available for a short time (see --ephemeral-artifact-ttl) and are then deleted"),
+		),
+		mcp.WithString("outputGlobs",
+			mcp.Description("Optional comma-separated glob patterns, e.g. `*.png,report.csv`, selecting which artifacts are copied to outputPath. All artifacts are still available as resources. Copies everything when omitted"),
+		),
+		mcp.WithString("overwrite",
+			mcp.Description("What to do when a file with the same name already exists in outputPath: `overwrite` (default), `skip` to keep the existing file, or `rename` to save the new artifact with a numeric suffix"),
+			mcp.Enum("overwrite", "skip", "rename"),
+		),
+		mcp.WithString("sessionId",
+			mcp.Description("Optional session identifier used to group runs so they can be cancelled together"),
+		),
+		mcp.WithNumber("stopTimeout",
+			mcp.Description("Seconds the program gets to shut down gracefully when the container is stopped before it is killed (default 2)"),
+		),
+		mcp.WithString("workDir",
+			mcp.Description("Absolute in-container path the code or project is mounted at and run from (default /app)"),
+		),
+		mcp.WithString("seccompProfile",
+			mcp.Description("Optional seccomp profile: `default` for Docker's default profile, `restrictive` for a built-in profile that blocks dangerous syscalls, or a full path to a JSON profile file"),
+		),
+		mcp.WithNumber("timeout",
+			mcp.Description("Optional number of seconds the run may take, including installing dependencies, before it is stopped. Defaults to a per-language value that gives compiled languages longer"),
+		),
+		mcp.WithString("installCommand",
+			mcp.Description("Optional command that replaces the automatically generated dependency install step, e.g. `uv pip install --system --no-deps -r requirements.txt`. It runs before the code and must be a single command without shell operators"),
+		),
+		mcp.WithString("image",
+			mcp.Description("Optional Docker image to run in instead of the language's default image, e.g. a pinned digest or an image with heavy dependencies preinstalled. The server may restrict which images are allowed"),
+		),
+		mcp.WithBoolean("skipDependencyInstall",
+			mcp.Description("Set with image when the image already has the dependencies, so none are detected or installed (default false)"),
+		),
+		mcp.WithBoolean("continueOnDepError",
+			mcp.Description("Run the code even when installing its dependencies fails. The result still reports the failed install (default false)"),
+		),
+		mcp.WithBoolean("forcePull",
+			mcp.Description("Pull the image even if a local copy was pulled recently (default false)"),
+		),
+		mcp.WithString("env",
+			mcp.Description("Optional JSON object of environment variables set in the container, e.g. `{\"API_URL\": \"https://example.com\", \"DEBUG\": \"1\"}`. "+
+				"Names must be valid variable names; ARTIFACTS_DIR and USER_ARTIFACTS_DIR are set by the server"),
+		),
+		mcp.WithString("secrets",
+			mcp.Description("Optional JSON object of secret names to values, e.g. `{\"api_key\": \"...\"}`. Each secret is written to /run/secrets/<name> on an in-memory filesystem before the program starts. "+
+				"Secrets are not passed as environment variables or command arguments, and their values are masked in the returned logs"),
+		),
+		mcp.WithBoolean("includeWarnings",
+			mcp.Description("Report compiler and runtime warnings found in stderr in a separate section of the result (default true)"),
+		),
+		mcp.WithBoolean("returnCommand",
+			mcp.Description("Include the exact command the container ran in the result, e.g. to see whether it was shell-wrapped or dependencies were installed first"),
+		),
+		mcp.WithNumber("memoryLimitMB",
+			mcp.Description("Memory limit for the container in MB. Defaults to a per-language limit, e.g. 1024 for Python and Go, 512 for Node.js"),
+		),
+		mcp.WithNumber("cpuLimit",
+			mcp.Description("CPU limit for the container in CPUs, fractions allowed (e.g. 0.5). Defaults to a per-language limit, e.g. 2 for Go and 1 for the others"),
+		),
+		mcp.WithString("readOnlyFiles",
+			mcp.Description("Comma-separated hostPath:containerPath pairs of single host files to mount read-only, e.g. a config file or certificate. Host files must exist and be inside the server's allowed paths"),
+		),
+		mcp.WithBoolean("allowDockerAccess",
+			mcp.Description("DANGEROUS: mount the host Docker socket into the container so the code can build and run containers. "+
+				"This gives the code full control of the host, equivalent to root access. Only enable for trusted code. Off by default."),
+		),
+		mcp.WithBoolean("allowNetwork",
+			mcp.Description("Give the code network access. Off by default: dependencies are still installed with network access, "+
+				"but the container is disconnected before the code runs, so requests from the code fail"),
+		),
 	)
 
 	runProjectTool := mcp.NewTool("run_project",
@@ -97,11 +326,18 @@ func main() {
 				"The supported languages are: "+GenerateEnumTag()+". \n"+
 				"Returns the resource URI of the container logs.\n\n"+
 				"Wait until container completes running before accessing. Check container logs to monitor status.\n\n"+
+				"The project has no network access once its dependencies are installed, unless allowNetwork is set.\n\n"+
 				"Example: `plt.savefig('plot.png')`",
 		),
 		mcp.WithString("projectDir",
-			mcp.Required(),
-			mcp.Description("Location of the project to run. Provide full path to project."),
+			mcp.Description("Location of the project to run. Provide full path to project. Either projectDir or projectArchive is required."),
+		),
+		mcp.WithBoolean("cleanProjectArtifacts",
+			mcp.Description("Empty the project's artifacts/ subdirectory before the run so it starts without outputs from earlier runs. Other project files are never touched. Off by default"),
+		),
+		mcp.WithString("projectArchive",
+			mcp.Description("Base64-encoded tar archive (optionally gzip-compressed) of the project, for clients that don't share a filesystem with the server. "+
+				"It is extracted to a temporary directory that is removed after the run. Up to 50MB, files and directories only."),
 		),
 		mcp.WithString("language",
 			mcp.Required(),
@@ -109,49 +345,327 @@ func main() {
 			mcp.Enum(deps.AllLanguages.ToArray()...),
 		),
 		mcp.WithString("entrypointCmd",
+			mcp.Description("Entrypoint command to run at the root of the project directory. "+
+				"When omitted it is inferred from the project: package.json scripts.start or scripts.dev for Node.js and TypeScript, __main__.py or main.py for Python, main.go for Go, bin/main.dart for Dart. "+
+				"Examples: `npm run dev`, `python main.py`, `go run main.go`"),
+		),
+		mcp.WithString("entrypointSteps",
+			mcp.Description("Commands to run one after the other in place of entrypointCmd, one per line, e.g. a build, then the tests, then the program. "+
+				"Runs stop at the first failing step unless continueOnError is set. get_run_results shows the exit code, duration and output of every step"),
+		),
+		mcp.WithBoolean("continueOnError",
+			mcp.Description("Keep running entrypointSteps after one fails. The run still exits with the first failing step's exit code"),
+		),
+		mcp.WithString("dependencyFile",
+			mcp.Description("Optional path to the dependency file to use, relative to the project directory (e.g. `backend/requirements.txt`). Overrides auto-detection."),
+		),
+		mcp.WithString("sessionId",
+			mcp.Description("Optional session identifier used to group runs so they can be cancelled together"),
+		),
+		mcp.WithNumber("stopTimeout",
+			mcp.Description("Seconds the program gets to shut down gracefully when the container is stopped before it is killed (default 2)"),
+		),
+		mcp.WithString("workDir",
+			mcp.Description("Absolute in-container path the code or project is mounted at and run from (default /app)"),
+		),
+		mcp.WithString("seccompProfile",
+			mcp.Description("Optional seccomp profile: `default` for Docker's default profile, `restrictive` for a built-in profile that blocks dangerous syscalls, or a full path to a JSON profile file"),
+		),
+		mcp.WithNumber("timeout",
+			mcp.Description("Optional number of seconds the run may take, including installing dependencies, before it is stopped. Defaults to a per-language value that gives compiled languages longer"),
+		),
+		mcp.WithString("installCommand",
+			mcp.Description("Optional command that replaces the automatically generated dependency install step, e.g. `uv pip install --system --no-deps -r requirements.txt`. It runs before the code and must be a single command without shell operators"),
+		),
+		mcp.WithString("image",
+			mcp.Description("Optional Docker image to run in instead of the language's default image, e.g. a pinned digest or an image with heavy dependencies preinstalled. The server may restrict which images are allowed"),
+		),
+		mcp.WithBoolean("skipDependencyInstall",
+			mcp.Description("Set with image when the image already has the dependencies, so none are detected or installed (default false)"),
+		),
+		mcp.WithBoolean("continueOnDepError",
+			mcp.Description("Run the code even when installing its dependencies fails. The result still reports the failed install (default false)"),
+		),
+		mcp.WithBoolean("forcePull",
+			mcp.Description("Pull the image even if a local copy was pulled recently (default false)"),
+		),
+		mcp.WithString("env",
+			mcp.Description("Optional JSON object of environment variables set in the container, e.g. `{\"API_URL\": \"https://example.com\", \"DEBUG\": \"1\"}`. "+
+				"Names must be valid variable names; ARTIFACTS_DIR and USER_ARTIFACTS_DIR are set by the server"),
+		),
+		mcp.WithString("secrets",
+			mcp.Description("Optional JSON object of secret names to values, e.g. `{\"api_key\": \"...\"}`. Each secret is written to /run/secrets/<name> on an in-memory filesystem before the program starts. "+
+				"Secrets are not passed as environment variables or command arguments, and their values are masked in the returned logs"),
+		),
+		mcp.WithBoolean("returnCommand",
+			mcp.Description("Include the exact command the container ran in the result, e.g. to see whether it was shell-wrapped or dependencies were installed first"),
+		),
+		mcp.WithNumber("memoryLimitMB",
+			mcp.Description("Memory limit for the container in MB. Defaults to a per-language limit, e.g. 1024 for Python and Go, 512 for Node.js"),
+		),
+		mcp.WithNumber("cpuLimit",
+			mcp.Description("CPU limit for the container in CPUs, fractions allowed (e.g. 0.5). Defaults to a per-language limit, e.g. 2 for Go and 1 for the others"),
+		),
+		mcp.WithString("readOnlyFiles",
+			mcp.Description("Comma-separated hostPath:containerPath pairs of single host files to mount read-only, e.g. a config file or certificate. Host files must exist and be inside the server's allowed paths"),
+		),
+		mcp.WithBoolean("allowDockerAccess",
+			mcp.Description("DANGEROUS: mount the host Docker socket into the container so the code can build and run containers. "+
+				"This gives the code full control of the host, equivalent to root access. Only enable for trusted code. Off by default."),
+		),
+		mcp.WithBoolean("allowNetwork",
+			mcp.Description("Give the code network access. Off by default: dependencies are still installed with network access, "+
+				"but the container is disconnected before the code runs, so requests from the code fail"),
+		),
+	)
+
+	cancelSessionTool := mcp.NewTool("cancel_session",
+		mcp.WithDescription(
+			"Stop and remove every container started for a session. \n"+
+				"Use this to abort all in-flight runs that were started with the given sessionId.\n"+
+				"Returns a summary of the cancelled runs.",
+		),
+		mcp.WithString("sessionId",
+			mcp.Required(),
+			mcp.Description("The session identifier passed to run_code or run_project"),
+		),
+	)
+
+	cleanupContainerTool := mcp.NewTool("cleanup_container",
+		mcp.WithDescription(
+			"Release everything kept for a run once you are done with its logs and artifacts. \n"+
+				"Deletes its artifacts and saved logs and removes its container if it still exists, stopping it if it is running.\n"+
+				"Returns the number of artifacts removed and the bytes freed.",
+		),
+		mcp.WithString("containerId",
+			mcp.Required(),
+			mcp.Description("The container ID of the run, as returned by run_code or run_project"),
+		),
+	)
+
+	checkDependenciesTool := mcp.NewTool("check_dependencies",
+		mcp.WithDescription(
+			"Check whether a Python dependency set resolves without running any code. \n"+
+				"Performs a dry-run install with uv in a sandboxed docker container. \n"+
+				"Provide either the contents of a requirements.txt file or a project directory.\n"+
+				"Returns the resolver output and whether resolution succeeded.",
+		),
+		mcp.WithString("requirements",
+			mcp.Description("Contents of a requirements.txt file to check"),
+		),
+		mcp.WithString("projectDir",
+			mcp.Description("Full path to a Python project containing requirements.txt, pyproject.toml or setup.py"),
+		),
+	)
+
+	analyzeProjectTool := mcp.NewTool("analyze_project",
+		mcp.WithDescription(
+			"Preview how run_project would interpret a project directory without running anything. \n"+
+				"Reports the detected languages, dependency files, `# requirements:` comments, "+
+				"the entrypoint that would be chosen and the resulting container command.",
+		),
+		mcp.WithString("projectDir",
 			mcp.Required(),
-			mcp.Description("Entrypoint command to run at the root of the project directory."),
-			mcp.Description("Examples: `npm run dev`, `python main.py`, `go run main.go`"),
+			mcp.Description("Full path to the project directory"),
+		),
+		mcp.WithString("language",
+			mcp.Description("Only analyze the project as this language, even if nothing for it is detected"),
+			mcp.Enum(deps.AllLanguages.ToArray()...),
+		),
+	)
+
+	analyzeCodeTool := mcp.NewTool("analyze_code",
+		mcp.WithDescription(
+			"Preview how run_code would run a snippet without creating a container. \n"+
+				"Returns JSON with the packages detected from the imports and `# requirements:` comments, "+
+				"the image, the file the code is saved as, the network access and the final container command. "+
+				"Takes the same code and dependency arguments as run_code.",
+		),
+		mcp.WithString("code",
+			mcp.Description("The code to analyze. Either code, filePath or files is required"),
+		),
+		mcp.WithString("filePath",
+			mcp.Description("Full path of a host file to analyze instead of passing code inline"),
+		),
+		mcp.WithString("files",
+			mcp.Description("Optional JSON object of relative file names to contents that would be written next to the code. Their imports count too"),
+		),
+		mcp.WithString("entrypoint",
+			mcp.Description("Optional name of the file among files that would run instead of the code"),
+		),
+		mcp.WithString("language",
+			mcp.Required(),
+			mcp.Description("The programming language of the code"),
+			mcp.Enum(deps.AllLanguages.ToArray()...),
+		),
+		mcp.WithString("workDir",
+			mcp.Description("Absolute in-container path the code would be mounted at and run from (default /app)"),
+		),
+		mcp.WithString("installCommand",
+			mcp.Description("Optional command that would replace the automatically generated dependency install step"),
+		),
+		mcp.WithString("image",
+			mcp.Description("Optional Docker image that would be used instead of the language's default image"),
+		),
+		mcp.WithBoolean("skipDependencyInstall",
+			mcp.Description("Set with image when the image already has the dependencies, so none are detected or installed (default false)"),
+		),
+		mcp.WithBoolean("continueOnDepError",
+			mcp.Description("Whether the code would run even when installing its dependencies fails (default false)"),
+		),
+		mcp.WithBoolean("allowNetwork",
+			mcp.Description("Whether the code would get network access (default false)"),
+		),
+	)
+
+	inspectImageTool := mcp.NewTool("inspect_image",
+		mcp.WithDescription(
+			"List the packages already installed in a language's image, or in a custom image, "+
+				"to decide whether dependencies need installing or which image to use. \n"+
+				"Runs the ecosystem's package manager (pip, npm or bun, dart pub, opam) in a throwaway container "+
+				"and returns the image, the language and the installed packages as JSON.",
+		),
+		mcp.WithString("language",
+			mcp.Required(),
+			mcp.Description("The ecosystem whose packages to list"),
+			mcp.Enum(deps.AllLanguages.ToArray()...),
+		),
+		mcp.WithString("image",
+			mcp.Description("Image to inspect instead of the language's default image"),
+		),
+	)
+
+	listSupportedLanguagesTool := mcp.NewTool("list_supported_languages",
+		mcp.WithDescription(
+			"List the languages run_code and run_project support. \n"+
+				"Returns JSON with the name, default Docker image, file extension and recognized dependency files "+
+				"of every language, sorted by name.",
 		),
 	)
 
+	getRunResultsTool := mcp.NewTool("get_run_results",
+		mcp.WithDescription(
+			"Get everything a run produced in a single call. \n"+
+				"Returns the run's metadata, its logs (truncated to the most recent output if very large) "+
+				"and the URIs and types of all its artifacts.",
+		),
+		mcp.WithString("containerId",
+			mcp.Required(),
+			mcp.Description("The container ID of the run, as returned by run_code or run_project"),
+		),
+	)
+
+	streamStatsTool := mcp.NewTool("stream_stats",
+		mcp.WithDescription(
+			"Watch the resource usage of a running container. \n"+
+				"Sends CPU, memory and network usage as notifications/message events about once a second "+
+				"until the container exits or the duration runs out, then returns the peak usage.",
+		),
+		mcp.WithString("containerId",
+			mcp.Required(),
+			mcp.Description("The container ID of the run, as returned by run_code or run_project"),
+		),
+		mcp.WithNumber("durationSeconds",
+			mcp.Description("How long to stream stats for, in seconds (default 60, max 600)"),
+		),
+	)
+
+	tailLogsTool := mcp.NewTool("tail_logs",
+		mcp.WithDescription(
+			"Get the log output a run produced since the last call. \n"+
+				"Returns the new output, the offset to pass next time and whether the container has exited. "+
+				"Use waitSeconds to wait for new output instead of polling rapidly.",
+		),
+		mcp.WithString("containerId",
+			mcp.Required(),
+			mcp.Description("The container ID of the run, as returned by run_code or run_project"),
+		),
+		mcp.WithNumber("offset",
+			mcp.Description("Byte offset to return output from. Defaults to where the previous tail_logs call for this container stopped"),
+		),
+		mcp.WithNumber("waitSeconds",
+			mcp.Description("Wait up to this many seconds (max 30) for new output or for the container to exit before returning (default 0)"),
+		),
+	)
+
+	// Register a resource listing the containers the server is running
+	activeContainersResource := mcp.NewResource(
+		resources.ActiveContainersURI,
+		"Active Containers",
+		mcp.WithResourceDescription("Lists the containers the server started and hasn't removed yet, with their language, image, start time and Docker status. Use it to see the current load and find container IDs for the logs and artifacts resources."),
+		mcp.WithMIMEType("application/json"),
+		mcp.WithAnnotations([]mcp.Role{mcp.RoleAssistant, mcp.RoleUser}, 0.5),
+	)
+	s.AddResource(activeContainersResource, resources.ListActiveContainers)
+
 	// Register dynamic resource for container logs
 	// Dynamic resource example - Container Logs by ID
 	containerLogsTemplate := mcp.NewResourceTemplate(
 		"containers://{id}/logs",
 		"Container Logs",
-		mcp.WithTemplateDescription("Returns all container logs from the specified container. Logs are returned as a single text resource."),
+		mcp.WithTemplateDescription("Returns all container logs from the specified container. Logs are returned as a single text resource, keeping the last 1MB of larger output."),
 		mcp.WithTemplateMIMEType("text/plain"),
 		mcp.WithTemplateAnnotations([]mcp.Role{mcp.RoleAssistant, mcp.RoleUser}, 0.5),
 	)
 
-	// Register dynamic resource for container artifacts
-	containerArtifactsTemplate := mcp.NewResourceTemplate(
-		"artifacts://{containerid}/{filename}",
-		"Container Artifacts",
-		mcp.WithTemplateDescription("Returns file artifacts generated during code execution. Supports images, PDFs, and other file types."),
+	s.AddResourceTemplate(containerLogsTemplate, resources.GetContainerLogs)
+	containerLogsQueryTemplate := mcp.NewResourceTemplate(
+		"containers://{id}/logs{?tail,since}",
+		"Container Logs (partial)",
+		mcp.WithTemplateDescription("Returns part of the logs of the specified container: the last N lines with ?tail=N, or the output from a byte offset on with ?since=<offset>. Each read returns at most 1MB and says how to read the rest."),
+		mcp.WithTemplateMIMEType("text/plain"),
 		mcp.WithTemplateAnnotations([]mcp.Role{mcp.RoleAssistant, mcp.RoleUser}, 0.5),
 	)
+	s.AddResourceTemplate(containerLogsQueryTemplate, resources.GetContainerLogs)
 
-	s.AddResourceTemplate(containerLogsTemplate, resources.GetContainerLogs)
-	s.AddResourceTemplate(containerArtifactsTemplate, resources.GetContainerArtifact)
-	s.AddTool(runCodeTool, tools.RunCodeSandbox)
-	s.AddTool(runProjectTool, tools.RunProjectSandbox)
+	// Register dynamic resources for container artifacts. Template variables
+	// can't span a slash, so artifacts in subdirectories such as
+	// artifacts://{containerid}/plots/a.png need a template for each depth.
+	for _, uriTemplate := range resources.ArtifactURITemplates() {
+		containerArtifactsTemplate := mcp.NewResourceTemplate(
+			uriTemplate,
+			"Container Artifacts",
+			mcp.WithTemplateDescription("Returns file artifacts generated during code execution, including those written to subdirectories of /artifacts. Supports images, PDFs, and other file types."),
+			mcp.WithTemplateAnnotations([]mcp.Role{mcp.RoleAssistant, mcp.RoleUser}, 0.5),
+		)
+		s.AddResourceTemplate(containerArtifactsTemplate, resources.GetContainerArtifact)
+	}
+	// Tool calls are rate limited, and cancelled when the server shuts down
+	handler := func(h server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return tools.WithShutdown(tools.RateLimited(h))
+	}
+	s.AddTool(runCodeTool, handler(tools.RunCodeSandbox))
+	s.AddTool(runProjectTool, handler(tools.RunProjectSandbox))
+	s.AddTool(cancelSessionTool, handler(tools.CancelSession))
+	s.AddTool(cleanupContainerTool, handler(tools.CleanupContainer))
+	s.AddTool(checkDependenciesTool, handler(tools.CheckDependencies))
+	s.AddTool(analyzeProjectTool, handler(tools.AnalyzeProject))
+	s.AddTool(analyzeCodeTool, handler(tools.AnalyzeCode))
+	s.AddTool(inspectImageTool, handler(tools.InspectImage))
+	s.AddTool(listSupportedLanguagesTool, handler(tools.ListSupportedLanguages))
+	s.AddTool(getRunResultsTool, handler(tools.GetRunResults))
+	s.AddTool(streamStatsTool, handler(tools.StreamStats))
+	s.AddTool(tailLogsTool, handler(tools.TailLogs))
+
+	// Serve until the process is interrupted or terminated, or a stdio client
+	// goes away, and then shut down
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	switch *transport {
 	case "stdio":
-		if err := server.ServeStdio(s); err != nil {
+		stdioServer := server.NewStdioServer(s)
+		stdioServer.SetErrorLogger(log.New(os.Stderr, "", log.LstdFlags))
+		if err := stdioServer.Listen(ctx, os.Stdin, os.Stdout); err != nil && !errors.Is(err, context.Canceled) {
 			s.SendNotificationToClient("notifications/error", map[string]interface{}{
 				"message": fmt.Sprintf("Failed to start stdio server: %v", err),
 			})
 		}
+		shutdown(nil)
 	case "sse":
-		sseServer := server.NewSSEServer(s, fmt.Sprintf("http://localhost:%s", *port))
-		if err := sseServer.Start(fmt.Sprintf(":%s", *port)); err != nil {
-			s.SendNotificationToClient("notifications/error", map[string]interface{}{
-				"message": fmt.Sprintf("Failed to start SSE server: %v", err),
-			})
-		}
+		serveHTTP(ctx, "SSE", server.NewSSEServer(s, fmt.Sprintf("http://localhost:%s", *port)))
+	case "http":
+		serveHTTP(ctx, "Streamable HTTP", streamable.NewServer(s, streamable.DefaultEndpoint))
 	default:
 		s.SendNotificationToClient("notifications/error", map[string]interface{}{
 			"message": fmt.Sprintf("Invalid transport: %s", *transport),
@@ -159,9 +673,55 @@ func main() {
 	}
 }
 
+// httpTransport is a transport served over HTTP on --port
+type httpTransport interface {
+	Start(addr string) error
+	Shutdown(ctx context.Context) error
+}
+
+// serveHTTP serves an HTTP transport on --port until ctx is done, then shuts down
+func serveHTTP(ctx context.Context, name string, transport httpTransport) {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- transport.Start(fmt.Sprintf(":%s", *port))
+	}()
+	logging.Infof("Serving %s on port %s", name, *port)
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "Error: failed to start %s server: %v\n", name, err)
+			os.Exit(1)
+		}
+	case <-ctx.Done():
+		shutdown(transport)
+	}
+}
+
+// shutdownTimeout bounds how long shutting down waits for containers to be
+// removed and for requests in progress to finish
+const shutdownTimeout = 30 * time.Second
+
+// shutdown cancels the tool calls in progress, removes the containers the
+// server started, stops transport if there is one, and flushes the artifact
+// registry
+func shutdown(transport httpTransport) {
+	logging.Infof("Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	tools.Shutdown(ctx)
+	if transport != nil {
+		if err := transport.Shutdown(ctx); err != nil {
+			logging.Warnf("Server did not shut down cleanly: %v", err)
+		}
+	}
+	resources.FlushArtifacts()
+}
+
 func handleNotification(
 	ctx context.Context,
 	notification mcp.JSONRPCNotification,
 ) {
-	log.Printf("Received notification from client: %s", notification.Method)
+	logging.Debugf("Received notification from client: %s", notification.Method)
 }
@@ -0,0 +1,111 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/moby/moby/client"
+)
+
+// ActiveContainersURI is the resource listing the containers the server is running
+const ActiveContainersURI = "containers://"
+
+// ActiveContainer is a container the server created and hasn't removed yet
+type ActiveContainer struct {
+	ContainerID string    `json:"containerId"`
+	Language    string    `json:"language"`
+	Image       string    `json:"image"`
+	StartedAt   time.Time `json:"startedAt"`
+	Pooled      bool      `json:"pooled,omitempty"` // Belongs to the container pool
+}
+
+// The containers registry holds every container the server created and hasn't
+// removed, keyed by container ID. Tool calls, the pool and shutdown use it
+// concurrently, so it is only accessed through the functions below.
+var (
+	containersMu       sync.RWMutex
+	containersRegistry = make(map[string]ActiveContainer)
+)
+
+// RegisterContainer records a container the server created
+func RegisterContainer(c ActiveContainer) {
+	if c.StartedAt.IsZero() {
+		c.StartedAt = time.Now()
+	}
+	containersMu.Lock()
+	defer containersMu.Unlock()
+	containersRegistry[c.ContainerID] = c
+}
+
+// UnregisterContainer forgets a container that was removed
+func UnregisterContainer(containerID string) {
+	containersMu.Lock()
+	defer containersMu.Unlock()
+	delete(containersRegistry, containerID)
+}
+
+// ActiveContainers returns the containers the server created and hasn't
+// removed, oldest first
+func ActiveContainers() []ActiveContainer {
+	containersMu.RLock()
+	defer containersMu.RUnlock()
+	active := make([]ActiveContainer, 0, len(containersRegistry))
+	for _, c := range containersRegistry {
+		active = append(active, c)
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].StartedAt.Before(active[j].StartedAt)
+	})
+	return active
+}
+
+// activeContainerStatus is an active container together with its Docker state
+type activeContainerStatus struct {
+	ActiveContainer
+	Status string `json:"status"`
+}
+
+// ListActiveContainers returns the containers the server is running as JSON,
+// each with its status as reported by Docker
+func ListActiveContainers(ctx context.Context, request mcp.ReadResourceRequest) ([]interface{}, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Docker client: %w", err)
+	}
+	defer cli.Close()
+
+	containers := []activeContainerStatus{}
+	for _, c := range ActiveContainers() {
+		status := "unknown"
+		inspect, err := cli.ContainerInspect(ctx, c.ContainerID)
+		switch {
+		case client.IsErrNotFound(err):
+			// Removed since the registry was read
+			continue
+		case err == nil && inspect.State != nil:
+			status = inspect.State.Status
+		}
+		containers = append(containers, activeContainerStatus{ActiveContainer: c, Status: status})
+	}
+
+	data, err := json.MarshalIndent(struct {
+		Containers []activeContainerStatus `json:"containers"`
+	}{containers}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode container list: %w", err)
+	}
+	return []interface{}{
+		mcp.TextResourceContents{
+			ResourceContents: mcp.ResourceContents{
+				URI:      ActiveContainersURI,
+				MIMEType: "application/json",
+			},
+			Text: string(data),
+		},
+	}, nil
+}
@@ -0,0 +1,63 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestActiveContainers(t *testing.T) {
+	now := time.Now()
+	RegisterContainer(ActiveContainer{ContainerID: "test-active-b", Language: "go", Image: "golang", StartedAt: now})
+	RegisterContainer(ActiveContainer{ContainerID: "test-active-a", Language: "python", Image: "python", StartedAt: now.Add(-time.Minute)})
+	RegisterContainer(ActiveContainer{ContainerID: "test-active-c", Language: "python", Image: "python"})
+	UnregisterContainer("test-active-c")
+	defer UnregisterContainer("test-active-a")
+	defer UnregisterContainer("test-active-b")
+
+	var ids []string
+	for _, c := range ActiveContainers() {
+		ids = append(ids, c.ContainerID)
+	}
+	if len(ids) != 2 || ids[0] != "test-active-a" || ids[1] != "test-active-b" {
+		t.Errorf("ActiveContainers() = %v, want [test-active-a test-active-b]", ids)
+	}
+}
+
+func TestListActiveContainers(t *testing.T) {
+	// Docker can't be reached, so the status of each container is unknown
+	t.Setenv("DOCKER_HOST", "unix://"+t.TempDir()+"/docker.sock")
+	RegisterContainer(ActiveContainer{ContainerID: "test-list-active", Language: "python", Image: "python:3.12-slim-bookworm"})
+	defer UnregisterContainer("test-list-active")
+
+	contents, err := ListActiveContainers(context.Background(), mcp.ReadResourceRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, ok := contents[0].(mcp.TextResourceContents)
+	if !ok || text.URI != ActiveContainersURI || text.MIMEType != "application/json" {
+		t.Fatalf("contents = %+v, want the JSON container list", contents[0])
+	}
+	var list struct {
+		Containers []struct {
+			ContainerID string    `json:"containerId"`
+			Language    string    `json:"language"`
+			Image       string    `json:"image"`
+			StartedAt   time.Time `json:"startedAt"`
+			Status      string    `json:"status"`
+		} `json:"containers"`
+	}
+	if err := json.Unmarshal([]byte(text.Text), &list); err != nil {
+		t.Fatalf("container list isn't JSON: %v\n%s", err, text.Text)
+	}
+	if len(list.Containers) != 1 {
+		t.Fatalf("containers = %+v, want test-list-active only", list.Containers)
+	}
+	c := list.Containers[0]
+	if c.ContainerID != "test-list-active" || c.Language != "python" || c.Image != "python:3.12-slim-bookworm" || c.StartedAt.IsZero() || c.Status != "unknown" {
+		t.Errorf("container = %+v", c)
+	}
+}
@@ -0,0 +1,71 @@
+package resources
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultArtifactTTL is the default time a run's artifacts, saved output and
+// run record are kept before the artifact GC deletes them
+const DefaultArtifactTTL = time.Hour
+
+// artifactGCInterval is how often the artifact GC looks for expired runs
+const artifactGCInterval = time.Minute
+
+// Time a run's files are kept, zero to keep them forever
+var (
+	artifactTTLMu sync.RWMutex
+	artifactTTL   = DefaultArtifactTTL
+)
+
+// SetArtifactTTL configures how long a run's files are kept after they were
+// last written. Zero keeps them until they are cleaned up explicitly.
+func SetArtifactTTL(d time.Duration) {
+	artifactTTLMu.Lock()
+	defer artifactTTLMu.Unlock()
+	artifactTTL = d
+}
+
+// runArtifactGC deletes expired runs from the artifact stores every interval.
+// It runs for the lifetime of the server.
+func runArtifactGC(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		pruneEphemeralArtifacts(now)
+		artifactTTLMu.RLock()
+		ttl := artifactTTL
+		artifactTTLMu.RUnlock()
+		if ttl > 0 {
+			collectExpiredArtifacts(now.Add(-ttl))
+		}
+	}
+}
+
+// collectExpiredArtifacts deletes the directories of runs in the persistent
+// store that were last modified before cutoff, and unregisters their
+// artifacts. Runs still going are left alone.
+func collectExpiredArtifacts(cutoff time.Time) {
+	entries, err := os.ReadDir(persistentArtifactsDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		containerID := entry.Name()
+		if run, ok := GetRun(containerID); ok && run.Status == RunStatusRunning {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+
+		unregisterContainerArtifacts(containerID)
+		os.RemoveAll(filepath.Join(persistentArtifactsDir, containerID))
+	}
+}
@@ -0,0 +1,46 @@
+package resources
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCollectExpiredArtifacts(t *testing.T) {
+	old := time.Now().Add(-2 * time.Hour)
+	newRunDir := func(containerID string, modTime time.Time) {
+		t.Helper()
+		dir := filepath.Join(persistentArtifactsDir, containerID)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.RemoveAll(dir) })
+		path := filepath.Join(dir, "out.txt")
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		RegisterArtifact(containerID, "out.txt", path)
+		if err := os.Chtimes(dir, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newRunDir("gc-expired", old)
+	newRunDir("gc-fresh", time.Now())
+	newRunDir("gc-running", old)
+	RegisterRun(Run{ContainerID: "gc-running"})
+
+	collectExpiredArtifacts(time.Now().Add(-time.Hour))
+
+	if _, err := os.Stat(filepath.Join(persistentArtifactsDir, "gc-expired")); !os.IsNotExist(err) {
+		t.Errorf("expired run directory still exists: %v", err)
+	}
+	if _, ok := lookupArtifact("gc-expired/out.txt"); ok {
+		t.Error("expired artifact still registered")
+	}
+	for _, containerID := range []string{"gc-fresh", "gc-running"} {
+		if _, ok := lookupArtifact(containerID + "/out.txt"); !ok {
+			t.Errorf("artifact of %s was collected", containerID)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package resources
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The artifact registry maps artifact keys, "<containerID>/<name>", to the
+// path each artifact is stored at, and records when the ephemeral artifacts of
+// each container expire. Request handlers and the artifact GC use it
+// concurrently, so it is only accessed through the functions below.
+var (
+	artifactsMu       sync.RWMutex
+	artifactsRegistry = make(map[string]string)
+	ephemeralExpiry   = make(map[string]time.Time) // By container ID
+)
+
+// RegisterArtifact adds an artifact to the registry
+func RegisterArtifact(containerID, name, path string) {
+	key := fmt.Sprintf("%s/%s", containerID, name)
+	artifactsMu.Lock()
+	defer artifactsMu.Unlock()
+	artifactsRegistry[key] = path
+}
+
+// lookupArtifact returns the path of the artifact registered under key
+func lookupArtifact(key string) (string, bool) {
+	artifactsMu.RLock()
+	defer artifactsMu.RUnlock()
+	path, ok := artifactsRegistry[key]
+	return path, ok
+}
+
+// artifactKeys returns the sorted keys of the registered artifacts starting with prefix
+func artifactKeys(prefix string) []string {
+	artifactsMu.RLock()
+	defer artifactsMu.RUnlock()
+	var keys []string
+	for key := range artifactsRegistry {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// unregisterArtifactPath removes every registry entry pointing at path
+func unregisterArtifactPath(path string) {
+	artifactsMu.Lock()
+	defer artifactsMu.Unlock()
+	for key, registered := range artifactsRegistry {
+		if registered == path {
+			delete(artifactsRegistry, key)
+		}
+	}
+}
+
+// unregisterContainerArtifacts removes the artifacts of a container from the
+// registry, along with their expiry, and returns their paths
+func unregisterContainerArtifacts(containerID string) []string {
+	artifactsMu.Lock()
+	defer artifactsMu.Unlock()
+	var paths []string
+	for key, path := range artifactsRegistry {
+		if strings.HasPrefix(key, containerID+"/") {
+			paths = append(paths, path)
+			delete(artifactsRegistry, key)
+		}
+	}
+	delete(ephemeralExpiry, containerID)
+	return paths
+}
+
+// clearArtifactRegistry unregisters every artifact and returns the containers
+// that had ephemeral artifacts
+func clearArtifactRegistry() []string {
+	artifactsMu.Lock()
+	defer artifactsMu.Unlock()
+	containerIDs := make([]string, 0, len(ephemeralExpiry))
+	for containerID := range ephemeralExpiry {
+		containerIDs = append(containerIDs, containerID)
+	}
+	artifactsRegistry = make(map[string]string)
+	ephemeralExpiry = make(map[string]time.Time)
+	return containerIDs
+}
+
+// setEphemeralExpiry records when the ephemeral artifacts of a container expire
+func setEphemeralExpiry(containerID string, expiry time.Time) {
+	artifactsMu.Lock()
+	defer artifactsMu.Unlock()
+	ephemeralExpiry[containerID] = expiry
+}
+
+// expiredEphemeralContainers returns the containers whose ephemeral artifacts expired by now
+func expiredEphemeralContainers(now time.Time) []string {
+	artifactsMu.RLock()
+	defer artifactsMu.RUnlock()
+	var containerIDs []string
+	for containerID, expiry := range ephemeralExpiry {
+		if !now.Before(expiry) {
+			containerIDs = append(containerIDs, containerID)
+		}
+	}
+	return containerIDs
+}
@@ -0,0 +1,47 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Run with -race: the registry is shared by concurrent request handlers and the artifact GC
+func TestArtifactRegistryConcurrentAccess(t *testing.T) {
+	const workers, artifacts = 16, 50
+	dir := t.TempDir()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		containerID := fmt.Sprintf("test-concurrent-%d", w)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < artifacts; i++ {
+				name := fmt.Sprintf("file-%d.txt", i)
+				path := filepath.Join(dir, containerID+"-"+name)
+				RegisterArtifact(containerID, name, path)
+				if got, ok := lookupArtifact(containerID + "/" + name); !ok || got != path {
+					t.Errorf("lookupArtifact(%s/%s) = %q, %v, want %q", containerID, name, got, ok, path)
+				}
+				if _, err := ListContainerArtifacts(context.Background(), "artifacts://test-concurrent-"); err != nil {
+					t.Errorf("ListContainerArtifacts() unexpected error: %v", err)
+				}
+				InlineImage("artifacts://" + containerID + "/" + name)
+				if i%10 == 0 {
+					CleanupArtifact(path)
+				}
+			}
+			setEphemeralExpiry(containerID, time.Now())
+			pruneEphemeralArtifacts(time.Now())
+		}()
+	}
+	wg.Wait()
+
+	if keys := artifactKeys("test-concurrent-"); len(keys) != 0 {
+		t.Errorf("%d artifacts still registered after every container expired", len(keys))
+	}
+}
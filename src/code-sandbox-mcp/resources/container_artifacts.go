@@ -1,69 +1,147 @@
 package resources
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/Automata-Labs-team/code-sandbox-mcp/logging"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
-// Map to store artifact locations
-var artifactsRegistry = make(map[string]string)
+// Limits for inline previews of text artifacts. A limit of 0 disables previews.
+var (
+	previewMaxLines = 10
+	previewMaxBytes = 1024
+)
+
+// DefaultMaxInlineImageBytes is the default size limit for images returned inline in results
+const DefaultMaxInlineImageBytes = 256 * 1024
+
+// Largest image artifact returned inline in results. A limit of 0 disables inline images.
+var maxInlineImageBytes int64 = DefaultMaxInlineImageBytes
+
+// DefaultMaxTotalArtifactBytes is the default cap on the combined size of a run's artifacts
+const DefaultMaxTotalArtifactBytes = 100 * 1024 * 1024
+
+// Cap on the combined size of the artifacts collected for a single run
+var maxTotalArtifactBytes int64 = DefaultMaxTotalArtifactBytes
+
+// DefaultMaxArtifactFileBytes is the default size limit of a single artifact
+const DefaultMaxArtifactFileBytes = 50 * 1024 * 1024
+
+// Largest single artifact collected for a run
+var maxArtifactFileBytes int64 = DefaultMaxArtifactFileBytes
+
+// DefaultArtifactStoreDir is the default directory runs' artifacts, saved
+// output and run records are kept in
+var DefaultArtifactStoreDir = filepath.Join(os.TempDir(), "persistent-code-sandbox-artifacts")
 
 // Persistent directory for artifacts
-var persistentArtifactsDir = filepath.Join(os.TempDir(), "persistent-code-sandbox-artifacts")
+var persistentArtifactsDir = DefaultArtifactStoreDir
+
+// Directory for artifacts of runs that don't retain them, removed after the ephemeral TTL
+var ephemeralArtifactsDir = filepath.Join(os.TempDir(), "ephemeral-code-sandbox-artifacts")
+
+// DefaultEphemeralArtifactTTL is the default time artifacts of runs that don't retain them stay available
+const DefaultEphemeralArtifactTTL = 10 * time.Minute
+
+// Time artifacts of runs that don't retain them stay available
+var ephemeralArtifactTTL = DefaultEphemeralArtifactTTL
 
 func init() {
 	// Create the persistent artifacts directory if it doesn't exist
 	if _, err := os.Stat(persistentArtifactsDir); os.IsNotExist(err) {
 		err := os.MkdirAll(persistentArtifactsDir, 0755)
 		if err != nil {
-			fmt.Printf("Failed to create persistent artifacts directory: %v\n", err)
+			logging.Errorf("Failed to create persistent artifacts directory: %v", err)
 		} else {
-			fmt.Printf("Created persistent artifacts directory: %s\n", persistentArtifactsDir)
+			logging.Debugf("Created persistent artifacts directory: %s", persistentArtifactsDir)
 		}
 	}
+
+	go runArtifactGC(artifactGCInterval)
+}
+
+// SetEphemeralArtifactTTL configures how long artifacts of runs that don't retain them stay available
+func SetEphemeralArtifactTTL(d time.Duration) {
+	ephemeralArtifactTTL = d
 }
 
-// RegisterArtifact adds an artifact to the registry
-func RegisterArtifact(containerID, name, path string) {
-	key := fmt.Sprintf("%s/%s", containerID, name)
-	artifactsRegistry[key] = path
+// pruneEphemeralArtifacts unregisters and deletes the ephemeral artifacts that expired by now
+func pruneEphemeralArtifacts(now time.Time) {
+	for _, containerID := range expiredEphemeralContainers(now) {
+		unregisterContainerArtifacts(containerID)
+		os.RemoveAll(filepath.Join(ephemeralArtifactsDir, containerID))
+	}
+}
+
+// FlushArtifacts empties the artifact registry when the server shuts down.
+// Ephemeral artifacts are deleted, as only this process knows when they
+// expire; persistent ones stay on disk until the artifact TTL has passed.
+func FlushArtifacts() {
+	for _, containerID := range clearArtifactRegistry() {
+		os.RemoveAll(filepath.Join(ephemeralArtifactsDir, containerID))
+	}
 }
 
 // ListContainerArtifacts returns a list of artifacts for a container
 func ListContainerArtifacts(ctx context.Context, prefix string) ([]mcp.Resource, error) {
+	pruneEphemeralArtifacts(time.Now())
 	prefix = strings.TrimPrefix(prefix, "artifacts://")
 	var resources []mcp.Resource
 
-	for key, _ := range artifactsRegistry {
-		if strings.HasPrefix(key, prefix) {
-			parts := strings.Split(key, "/")
-			if len(parts) >= 2 {
-				fileName := parts[len(parts)-1]
-				resources = append(resources, mcp.Resource{
-					URI:         fmt.Sprintf("artifacts://%s", key),
-					Name:        fileName,
-					MIMEType:    guessMimeType(fileName),
-					Description: fmt.Sprintf("Artifact %s from container %s", fileName, parts[0]),
-				})
-			}
+	for _, key := range artifactKeys(prefix) {
+		// Artifacts in subdirectories are named by their path, e.g. plots/a.png
+		containerID, name, ok := strings.Cut(key, "/")
+		if ok {
+			resources = append(resources, mcp.Resource{
+				URI:         fmt.Sprintf("artifacts://%s", key),
+				Name:        name,
+				MIMEType:    guessMimeType(name),
+				Description: fmt.Sprintf("Artifact %s from container %s", name, containerID),
+			})
 		}
 	}
 
 	return resources, nil
 }
 
+// ParseArtifactURI splits an artifact URI into the container ID and the
+// artifact's name, which is everything after the container ID, e.g.
+// plots/loss.png for artifacts://<id>/plots/loss.png
+func ParseArtifactURI(uri string) (containerID string, name string, err error) {
+	rest, ok := strings.CutPrefix(uri, "artifacts://")
+	if ok {
+		containerID, name, ok = strings.Cut(rest, "/")
+	}
+	if !ok || !validContainerID(containerID) || name == "" {
+		return "", "", fmt.Errorf("invalid artifact URI: %s", uri)
+	}
+	return containerID, name, nil
+}
+
 // GetContainerArtifact retrieves an artifact by URI
 func GetContainerArtifact(ctx context.Context, request mcp.ReadResourceRequest) ([]interface{}, error) {
-	uriPath := strings.TrimPrefix(request.Params.URI, "artifacts://")
+	containerID, name, err := ParseArtifactURI(request.Params.URI)
+	if err != nil {
+		return nil, err
+	}
 
-	path, ok := artifactsRegistry[uriPath]
+	pruneEphemeralArtifacts(time.Now())
+	path, ok := lookupArtifact(containerID + "/" + name)
 	if !ok {
-		return nil, fmt.Errorf("artifact not found: %s", uriPath)
+		return nil, fmt.Errorf("artifact not found: %s/%s", containerID, name)
 	}
 
 	data, err := os.ReadFile(path)
@@ -72,19 +150,169 @@ func GetContainerArtifact(ctx context.Context, request mcp.ReadResourceRequest)
 	}
 
 	fileName := filepath.Base(path)
-	mimeType := guessMimeType(fileName)
+	contents := mcp.ResourceContents{URI: request.Params.URI}
+
+	// Binary artifacts don't survive being sent as text, so they are base64-encoded
+	if !isTextArtifact(fileName, data) {
+		contents.MIMEType = artifactMIMEType(fileName, "application/octet-stream")
+		return []interface{}{
+			mcp.BlobResourceContents{
+				ResourceContents: contents,
+				Blob:             base64.StdEncoding.EncodeToString(data),
+			},
+		}, nil
+	}
 
+	contents.MIMEType = artifactMIMEType(fileName, "text/plain")
 	return []interface{}{
 		mcp.TextResourceContents{
-			ResourceContents: mcp.ResourceContents{
-				URI:      request.Params.URI,
-				MIMEType: mimeType,
-			},
-			Text: string(data),
+			ResourceContents: contents,
+			Text:             string(data),
 		},
 	}, nil
 }
 
+// isTextArtifact reports whether an artifact can be returned as text. Files
+// without a known extension count as text when their content is UTF-8.
+func isTextArtifact(fileName string, data []byte) bool {
+	switch guessMimeType(fileName) {
+	case "text":
+		return true
+	case "binary":
+		return utf8.Valid(data) && bytes.IndexByte(data, 0) < 0
+	default:
+		return false
+	}
+}
+
+// artifactMIMETypes maps artifact extensions to their MIME types
+var artifactMIMETypes = map[string]string{
+	".pdf":  "application/pdf",
+	".txt":  "text/plain",
+	".md":   "text/markdown",
+	".json": "application/json",
+	".yaml": "application/yaml",
+	".yml":  "application/yaml",
+	".csv":  "text/csv",
+	".tsv":  "text/tab-separated-values",
+	".mp3":  "audio/mpeg",
+	".wav":  "audio/wav",
+	".ogg":  "audio/ogg",
+	".flac": "audio/flac",
+	".mp4":  "video/mp4",
+	".webm": "video/webm",
+	".avi":  "video/x-msvideo",
+	".mov":  "video/quicktime",
+}
+
+// artifactMIMEType returns the MIME type of an artifact, or fallback for
+// extensions without a known type
+func artifactMIMEType(fileName string, fallback string) string {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if mimeType, ok := imageMIMETypes[ext]; ok {
+		return mimeType
+	}
+	if mimeType, ok := artifactMIMETypes[ext]; ok {
+		return mimeType
+	}
+	return fallback
+}
+
+// SetPreviewLimits configures how much of a text artifact is included inline
+func SetPreviewLimits(maxLines, maxBytes int) {
+	previewMaxLines = maxLines
+	previewMaxBytes = maxBytes
+}
+
+// SetMaxInlineImageBytes sets the size limit for inline images. 0 disables them.
+func SetMaxInlineImageBytes(n int64) {
+	maxInlineImageBytes = n
+}
+
+// imageMIMETypes maps the image extensions clients can render to their MIME types
+var imageMIMETypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".svg":  "image/svg+xml",
+}
+
+// InlineImage returns the base64-encoded content and MIME type of an image
+// artifact small enough to include in a result. It returns false for other
+// artifacts and for images over the inline size limit, which are only
+// available through their URI.
+func InlineImage(uri string) (data string, mimeType string, ok bool) {
+	if maxInlineImageBytes <= 0 {
+		return "", "", false
+	}
+
+	path, ok := lookupArtifact(strings.TrimPrefix(uri, "artifacts://"))
+	if !ok {
+		return "", "", false
+	}
+	mimeType, ok = imageMIMETypes[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return "", "", false
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > maxInlineImageBytes {
+		return "", "", false
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", false
+	}
+	return base64.StdEncoding.EncodeToString(content), mimeType, true
+}
+
+// ArtifactPreview returns the head of a text artifact for inline display,
+// truncated to the preview limits with a marker pointing at the full URI.
+// It returns false for non-text artifacts or when previews are disabled.
+func ArtifactPreview(uri string) (string, bool) {
+	if previewMaxLines <= 0 || previewMaxBytes <= 0 {
+		return "", false
+	}
+
+	path, ok := lookupArtifact(strings.TrimPrefix(uri, "artifacts://"))
+	if !ok || guessMimeType(path) != "text" {
+		return "", false
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	// Read one byte past the limit to know whether the file was cut off
+	data, err := io.ReadAll(io.LimitReader(f, int64(previewMaxBytes)+1))
+	if err != nil {
+		return "", false
+	}
+	truncated := len(data) > previewMaxBytes
+	if truncated {
+		data = data[:previewMaxBytes]
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > previewMaxLines {
+		lines = lines[:previewMaxLines]
+		truncated = true
+	}
+
+	preview := strings.TrimRight(strings.Join(lines, ""), "\n")
+	if truncated {
+		preview += fmt.Sprintf("\n... [truncated, read %s for the full content]", uri)
+	}
+	return preview, true
+}
+
 // guessMimeType returns a simple MIME type based on file extension
 func guessMimeType(filename string) string {
 	// Very basic type detection based only on common extensions
@@ -106,103 +334,400 @@ func guessMimeType(filename string) string {
 
 // CleanupArtifact removes an artifact from the registry and deletes the file
 func CleanupArtifact(artifactPath string) {
-	// Find and remove from registry
-	var keysToRemove []string
-	for key, path := range artifactsRegistry {
-		if path == artifactPath {
-			keysToRemove = append(keysToRemove, key)
+	unregisterArtifactPath(artifactPath)
+
+	// Remove the file
+	os.Remove(artifactPath)
+}
+
+// CleanupContainerArtifacts removes every artifact of a container along with
+// its directories in the artifact stores, which also hold its saved output and
+// run record. It returns the number of artifacts removed and the bytes freed.
+func CleanupContainerArtifacts(containerID string) (artifacts int, bytesFreed int64, err error) {
+	if !validContainerID(containerID) {
+		return 0, 0, fmt.Errorf("invalid container ID: %s", containerID)
+	}
+
+	// Count the whole store directories, so files that were never registered,
+	// such as the saved output, are included
+	dirs := []string{filepath.Join(persistentArtifactsDir, containerID), filepath.Join(ephemeralArtifactsDir, containerID)}
+	for _, dir := range dirs {
+		bytesFreed += dirSize(dir)
+	}
+
+	paths := unregisterContainerArtifacts(containerID)
+	for _, path := range paths {
+		os.Remove(path)
+	}
+
+	for _, dir := range dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			return len(paths), bytesFreed, fmt.Errorf("failed to remove artifacts directory: %w", err)
+		}
+	}
+	return len(paths), bytesFreed, nil
+}
+
+// dirSize returns the combined size of the files under dir, or 0 if it doesn't exist
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil && !entry.IsDir() {
+			size += info.Size()
 		}
+		return nil
+	})
+	return size
+}
+
+// SetMaxTotalArtifactBytes configures the cap on the combined size of a run's artifacts
+func SetMaxTotalArtifactBytes(n int64) {
+	maxTotalArtifactBytes = n
+}
+
+// MaxTotalArtifactBytes returns the cap on the combined size of a run's artifacts
+func MaxTotalArtifactBytes() int64 {
+	return maxTotalArtifactBytes
+}
+
+// SetArtifactStoreDir keeps runs' artifacts, saved output and run records in
+// dir, creating it if needed. It must be called before any run starts.
+func SetArtifactStoreDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create artifact store directory: %w", err)
 	}
+	persistentArtifactsDir = dir
+	return nil
+}
+
+// SetMaxArtifactFileBytes configures the size limit of a single artifact
+func SetMaxArtifactFileBytes(n int64) {
+	maxArtifactFileBytes = n
+}
 
-	for _, key := range keysToRemove {
-		delete(artifactsRegistry, key)
+// MaxArtifactFileBytes returns the size limit of a single artifact
+func MaxArtifactFileBytes() int64 {
+	return maxArtifactFileBytes
+}
+
+// ArtifactCollection describes the outcome of collecting a run's artifacts
+type ArtifactCollection struct {
+	URIs    []string // URIs of the artifacts that were collected
+	Skipped []string // Artifacts that were deliberately not collected, with the reason
+	// Names of the skipped artifacts that were over the size limit of a
+	// single artifact or didn't fit in the total cap
+	Oversized []string
+	// Host paths artifacts were copied to in the output directory, by artifact
+	// name. An empty path means the overwrite policy kept an existing file.
+	OutputFiles map[string]string
+}
+
+// OverwritePolicy decides what happens when an artifact is copied to an output
+// directory that already has a file with the same name
+type OverwritePolicy string
+
+const (
+	OverwriteReplace OverwritePolicy = "overwrite" // Replace the existing file
+	OverwriteSkip    OverwritePolicy = "skip"      // Keep the existing file and don't copy
+	OverwriteRename  OverwritePolicy = "rename"    // Copy under a new name with a numeric suffix
+)
+
+// ParseOverwritePolicy validates an overwrite policy name. An empty name
+// selects OverwriteReplace.
+func ParseOverwritePolicy(name string) (OverwritePolicy, error) {
+	switch policy := OverwritePolicy(name); policy {
+	case "":
+		return OverwriteReplace, nil
+	case OverwriteReplace, OverwriteSkip, OverwriteRename:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("overwrite must be one of: %s, %s, %s", OverwriteReplace, OverwriteSkip, OverwriteRename)
 	}
+}
 
-	// Remove the file
-	os.Remove(artifactPath)
+// ArtifactOutput describes where collected artifacts are copied on the host
+type ArtifactOutput struct {
+	Dir       string // Output directory, empty to only register artifacts
+	Overwrite OverwritePolicy
+	// Glob patterns selecting which artifacts are copied to Dir, matched
+	// against the artifact's path and its file name, so *.png also selects
+	// plots/a.png. All artifacts are copied when empty.
+	Globs []string
+	// Keep the artifacts out of the persistent store. They stay registered
+	// for the ephemeral TTL and are then deleted.
+	Ephemeral bool
 }
 
-// CollectArtifactsFromDir scans a directory for artifacts, copies them to destinations and registers them
-// If targetPath is provided, artifacts will be copied there in addition to being registered in the MCP system
-func CollectArtifactsFromDir(containerID, artifactsDir string, targetPath string) ([]string, error) {
-	// Enhanced debugging with more visibility
-	fmt.Printf("======= ARTIFACT COLLECTION DIAGNOSTICS =======\n")
-	fmt.Printf("CollectArtifactsFromDir called with:\n")
-	fmt.Printf("  containerID: %s\n", containerID)
-	fmt.Printf("  artifactsDir: %s\n", artifactsDir)
-	fmt.Printf("  targetPath: %s\n", targetPath)
-
-	// Print current directory for debugging
-	curDir, _ := os.Getwd()
-	fmt.Printf("  Current working directory: %s\n", curDir)
-
-	// Phase 1: Collect artifacts from container
-	files, err := os.ReadDir(artifactsDir)
+// Includes reports whether the artifact named name should be copied to the
+// output directory
+func (o ArtifactOutput) Includes(name string) bool {
+	if len(o.Globs) == 0 {
+		return true
+	}
+	for _, glob := range o.Globs {
+		if matched, _ := path.Match(glob, name); matched {
+			return true
+		}
+		if matched, _ := path.Match(glob, path.Base(name)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Errors copyFileLimited returns for files that aren't collected
+var (
+	errArtifactTooLarge   = errors.New("artifact is larger than the size limit")
+	errArtifactNotRegular = errors.New("artifact is not a regular file")
+)
+
+// copyFileLimited streams the file at src to a new file at dst, so artifacts
+// are never held in memory. A file that turns out to be larger than limit
+// bytes, e.g. because it grew since its size was checked, isn't copied.
+func copyFileLimited(src, dst string, limit int64) error {
+	// The file could have been swapped for a symlink since it was listed,
+	// so neither the path nor the opened file is trusted to be the same one
+	in, err := os.OpenFile(src, os.O_RDONLY|openNoFollow, 0)
+	if err != nil {
+		if info, lerr := os.Lstat(src); lerr == nil && info.Mode()&fs.ModeSymlink != 0 {
+			return errArtifactNotRegular
+		}
+		return err
+	}
+	defer in.Close()
+	if info, err := in.Stat(); err != nil {
+		return err
+	} else if !info.Mode().IsRegular() {
+		return errArtifactNotRegular
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read artifacts directory: %w", err)
+		return err
 	}
+	n, err := io.Copy(out, io.LimitReader(in, limit+1))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && n > limit {
+		err = errArtifactTooLarge
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return err
+}
 
-	if len(files) == 0 {
-		fmt.Println("No artifacts found in container")
-		return []string{}, nil
+// WriteOutputFile writes an artifact named name to the output directory,
+// applying the overwrite policy. Artifacts in subdirectories keep them. It
+// returns the path written, or an empty path when an existing file was kept.
+func WriteOutputFile(output ArtifactOutput, name string, data io.Reader) (string, error) {
+	name = filepath.FromSlash(name)
+	destPath := filepath.Join(output.Dir, name)
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(destPath); err == nil {
+		switch output.Overwrite {
+		case OverwriteSkip:
+			return "", nil
+		case OverwriteRename:
+			ext := filepath.Ext(name)
+			base := strings.TrimSuffix(name, ext)
+			for i := 1; ; i++ {
+				destPath = filepath.Join(output.Dir, fmt.Sprintf("%s-%d%s", base, i, ext))
+				if _, err := os.Stat(destPath); os.IsNotExist(err) {
+					break
+				}
+			}
+		}
+	}
+
+	f, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(f, data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	return destPath, nil
+}
+
+// copyOutputFile copies the stored artifact at src to the output directory,
+// see WriteOutputFile
+func copyOutputFile(output ArtifactOutput, name string, src string) (string, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return WriteOutputFile(output, name, f)
+}
+
+// MaxArtifactDepth is how many directories deep below the artifacts directory
+// artifacts are collected from. Resource reads are routed by URI templates
+// whose variables can't contain a slash, so each depth has a template of its
+// own, see ArtifactURITemplates.
+const MaxArtifactDepth = 8
+
+// ArtifactURITemplates returns the URI templates of artifacts, from those at
+// the top of the artifacts directory to those MaxArtifactDepth deep
+func ArtifactURITemplates() []string {
+	templates := []string{"artifacts://{containerid}/{filename}"}
+	dirs := ""
+	for depth := 1; depth <= MaxArtifactDepth; depth++ {
+		dirs += fmt.Sprintf("{dir%d}/", depth)
+		templates = append(templates, "artifacts://{containerid}/"+dirs+"{filename}")
+	}
+	return templates
+}
+
+// CollectArtifactsFromDir scans a directory for artifacts, copies them to destinations and registers them
+// Files in subdirectories are collected too, named by their slash-separated path
+// relative to the directory, e.g. plots/a.png with the URI artifacts://<id>/plots/a.png
+// If an output directory is provided, artifacts will be copied there in addition to being registered in the MCP system
+// Files that fail to collect are reported in the returned error, but the URIs of the
+// artifacts that were collected successfully are always returned alongside it
+// Files larger than the size limit of a single artifact are skipped, and collection
+// stops once the combined size of the artifacts would exceed the total cap, with the
+// remaining files reported as skipped. Files are streamed, never read into memory whole.
+// Ephemeral artifacts are stored outside the persistent store and expire after the ephemeral TTL
+func CollectArtifactsFromDir(containerID, artifactsDir string, output ArtifactOutput) (ArtifactCollection, error) {
+	targetPath := output.Dir
+	logging.Debugf("Collecting artifacts of container %s from %s (output directory %q)", containerID, artifactsDir, targetPath)
+
+	pruneEphemeralArtifacts(time.Now())
+
+	// Phase 1: Find the artifacts in the directory and its subdirectories
+	// The walk keeps going past directories it can't read, so only give up
+	// when nothing could be read at all
+	var collectErrs []error
+	var names []string
+	collection := ArtifactCollection{OutputFiles: make(map[string]string)}
+	filepath.WalkDir(artifactsDir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			collectErrs = append(collectErrs, fmt.Errorf("failed to read artifacts directory: %w", err))
+			return nil
+		}
+		rel, err := filepath.Rel(artifactsDir, p)
+		if err != nil || rel == "." {
+			return nil
+		}
+		name := filepath.ToSlash(rel)
+
+		switch {
+		case entry.IsDir() && name == runMetadataDir:
+			collection.Skipped = append(collection.Skipped, fmt.Sprintf("%s/ (reserved for the run's metadata)", name))
+			return fs.SkipDir
+		case entry.IsDir() && strings.Count(name, "/") >= MaxArtifactDepth:
+			collection.Skipped = append(collection.Skipped, fmt.Sprintf("%s/ (more than %d directories deep)", name, MaxArtifactDepth))
+			return fs.SkipDir
+		case entry.IsDir():
+			return nil
+		case !entry.Type().IsRegular():
+			// Symlinks are not followed, they could point at files on the host
+			collection.Skipped = append(collection.Skipped, fmt.Sprintf("%s (not a regular file)", name))
+			return nil
+		}
+		names = append(names, name)
+		return nil
+	})
+
+	if len(names) == 0 {
+		if len(collectErrs) > 0 {
+			return ArtifactCollection{}, errors.Join(collectErrs...)
+		}
+		logging.Debugf("No artifacts found in container %s", containerID)
+		return ArtifactCollection{URIs: []string{}, Skipped: collection.Skipped}, nil
 	}
 
 	// Create container-specific directory in persistent storage
-	containerDir := filepath.Join(persistentArtifactsDir, containerID)
+	storeDir := persistentArtifactsDir
+	if output.Ephemeral {
+		storeDir = ephemeralArtifactsDir
+		setEphemeralExpiry(containerID, time.Now().Add(ephemeralArtifactTTL))
+	}
+	containerDir := filepath.Join(storeDir, containerID)
 	if err := os.MkdirAll(containerDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create container directory: %w", err)
+		return ArtifactCollection{}, fmt.Errorf("failed to create container directory: %w", err)
 	}
 
 	// Phase 2: Process and copy each artifact
-	var artifactURIs []string
-	for _, file := range files {
-		if file.IsDir() {
-			continue // Skip directories
-		}
-
-		fileName := file.Name()
-		srcPath := filepath.Join(artifactsDir, fileName)
+	var totalBytes int64
+	tooLarge := func(name string) string {
+		collection.Oversized = append(collection.Oversized, name)
+		return fmt.Sprintf("%s (larger than the artifact size limit of %d bytes)", name, maxArtifactFileBytes)
+	}
+	for i, fileName := range names {
+		srcPath := filepath.Join(artifactsDir, filepath.FromSlash(fileName))
 
-		// Read the file once
-		srcData, err := os.ReadFile(srcPath)
+		info, err := os.Lstat(srcPath)
 		if err != nil {
-			fmt.Printf("Warning: failed to read artifact %s: %v\n", fileName, err)
+			logging.Warnf("Failed to read artifact %s: %v", fileName, err)
+			collectErrs = append(collectErrs, fmt.Errorf("failed to read artifact %s: %w", fileName, err))
 			continue
 		}
-
-		// Always copy to persistent storage (for registry)
-		persistentPath := filepath.Join(containerDir, fileName)
-		if err := os.WriteFile(persistentPath, srcData, 0644); err != nil {
-			fmt.Printf("Warning: failed to write artifact to persistent storage: %v\n", err)
+		if !info.Mode().IsRegular() {
+			collection.Skipped = append(collection.Skipped, fmt.Sprintf("%s (not a regular file)", fileName))
 			continue
 		}
+		if info.Size() > maxArtifactFileBytes {
+			collection.Skipped = append(collection.Skipped, tooLarge(fileName))
+			continue
+		}
+		// Stop collecting once the total size cap would be exceeded
+		if totalBytes+info.Size() > maxTotalArtifactBytes {
+			reason := fmt.Sprintf("total artifact size limit of %d bytes exceeded", maxTotalArtifactBytes)
+			for _, remaining := range names[i:] {
+				collection.Skipped = append(collection.Skipped, fmt.Sprintf("%s (%s)", remaining, reason))
+				collection.Oversized = append(collection.Oversized, remaining)
+			}
+			break
+		}
 
-		// Copy to target location if specified
-		if targetPath != "" {
-			// Print target path for debugging
-			fmt.Printf("Target directory for artifacts: %s\n", targetPath)
+		// Always copy to storage (for registry)
+		persistentPath := filepath.Join(containerDir, filepath.FromSlash(fileName))
+		if err := os.MkdirAll(filepath.Dir(persistentPath), 0755); err != nil {
+			logging.Warnf("Failed to create directory in persistent storage: %v", err)
+			collectErrs = append(collectErrs, fmt.Errorf("failed to store artifact %s: %w", fileName, err))
+			continue
+		}
+		if err := copyFileLimited(srcPath, persistentPath, maxArtifactFileBytes); errors.Is(err, errArtifactTooLarge) {
+			collection.Skipped = append(collection.Skipped, tooLarge(fileName))
+			continue
+		} else if errors.Is(err, errArtifactNotRegular) {
+			collection.Skipped = append(collection.Skipped, fmt.Sprintf("%s (not a regular file)", fileName))
+			continue
+		} else if err != nil {
+			logging.Warnf("Failed to write artifact to persistent storage: %v", err)
+			collectErrs = append(collectErrs, fmt.Errorf("failed to store artifact %s: %w", fileName, err))
+			continue
+		}
+		totalBytes += info.Size()
 
+		// Copy to target location if specified. Artifacts not selected by the
+		// output globs are still registered below.
+		if targetPath != "" && output.Includes(fileName) {
 			// Create the target directory if it doesn't exist
 			if err := os.MkdirAll(targetPath, 0755); err != nil {
-				fmt.Printf("Warning: Failed to create target directory %s: %v\n", targetPath, err)
+				logging.Warnf("Failed to create target directory %s: %v", targetPath, err)
 			} else {
-				// Copy the file to the target directory
-				destPath := filepath.Join(targetPath, fileName)
-				fmt.Printf("Writing artifact to: %s\n", destPath)
-				if err := os.WriteFile(destPath, srcData, 0644); err != nil {
-					fmt.Printf("Warning: Failed to write artifact to target directory: %v\n", err)
+				// Copy the stored file to the target directory
+				destPath, err := copyOutputFile(output, fileName, persistentPath)
+				if err != nil {
+					logging.Warnf("Failed to write artifact to target directory: %v", err)
+				} else if destPath == "" {
+					logging.Debugf("Kept existing file for artifact %s in %s", fileName, targetPath)
+					collection.OutputFiles[fileName] = ""
 				} else {
-					fmt.Printf("Artifact copied to directory: %s\n", destPath)
-
-					// Verify the file was actually written
-					if _, err := os.Stat(destPath); err != nil {
-						fmt.Printf("ERROR: After writing, file still not found at %s: %v\n", destPath, err)
-					} else {
-						// Get file info to verify permissions and size
-						fileInfo, _ := os.Stat(destPath)
-						fmt.Printf("File successfully verified at %s (size: %d bytes, mode: %s)\n",
-							destPath, fileInfo.Size(), fileInfo.Mode())
-					}
+					collection.OutputFiles[fileName] = destPath
+					logging.Debugf("Artifact copied to %s", destPath)
 				}
 			}
 		}
@@ -210,8 +735,8 @@ func CollectArtifactsFromDir(containerID, artifactsDir string, targetPath string
 		// Register the artifact with the persistent path
 		RegisterArtifact(containerID, fileName, persistentPath)
 		artifactURI := fmt.Sprintf("artifacts://%s/%s", containerID, fileName)
-		artifactURIs = append(artifactURIs, artifactURI)
+		collection.URIs = append(collection.URIs, artifactURI)
 	}
 
-	return artifactURIs, nil
+	return collection, errors.Join(collectErrs...)
 }
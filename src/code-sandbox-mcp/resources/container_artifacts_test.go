@@ -0,0 +1,557 @@
+package resources
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/logging"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestCollectArtifactsFromDirTotalCap(t *testing.T) {
+	artifactsDir := t.TempDir()
+	for name, size := range map[string]int{"a.txt": 40, "b.txt": 40, "c.txt": 40} {
+		if err := os.WriteFile(filepath.Join(artifactsDir, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer SetMaxTotalArtifactBytes(maxTotalArtifactBytes)
+	SetMaxTotalArtifactBytes(100)
+
+	containerID := "test-total-cap"
+	defer os.RemoveAll(filepath.Join(persistentArtifactsDir, containerID))
+
+	collection, err := CollectArtifactsFromDir(containerID, artifactsDir, ArtifactOutput{})
+	if err != nil {
+		t.Fatalf("CollectArtifactsFromDir() unexpected error: %v", err)
+	}
+
+	// os.ReadDir returns entries sorted by name, so the last file is the one skipped
+	if len(collection.URIs) != 2 {
+		t.Errorf("CollectArtifactsFromDir() collected %v, want 2 artifacts", collection.URIs)
+	}
+	if len(collection.Skipped) != 1 || !strings.HasPrefix(collection.Skipped[0], "c.txt") {
+		t.Errorf("CollectArtifactsFromDir() skipped %v, want c.txt", collection.Skipped)
+	}
+}
+
+func TestCollectArtifactsFromDirFileLimit(t *testing.T) {
+	artifactsDir := t.TempDir()
+	for name, size := range map[string]int{"big.bin": 200, "small.txt": 10, "zz.txt": 10} {
+		if err := os.WriteFile(filepath.Join(artifactsDir, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer SetMaxArtifactFileBytes(maxArtifactFileBytes)
+	SetMaxArtifactFileBytes(100)
+
+	containerID := "test-file-limit"
+	defer os.RemoveAll(filepath.Join(persistentArtifactsDir, containerID))
+	outputDir := t.TempDir()
+
+	collection, err := CollectArtifactsFromDir(containerID, artifactsDir, ArtifactOutput{Dir: outputDir})
+	if err != nil {
+		t.Fatalf("CollectArtifactsFromDir() unexpected error: %v", err)
+	}
+
+	// Files after the oversized one are still collected
+	want := []string{"artifacts://" + containerID + "/small.txt", "artifacts://" + containerID + "/zz.txt"}
+	if strings.Join(collection.URIs, ",") != strings.Join(want, ",") {
+		t.Errorf("CollectArtifactsFromDir() collected %v, want %v", collection.URIs, want)
+	}
+	if len(collection.Skipped) != 1 || !strings.Contains(collection.Skipped[0], "big.bin (larger than the artifact size limit of 100 bytes)") {
+		t.Errorf("CollectArtifactsFromDir() skipped %v, want big.bin", collection.Skipped)
+	}
+	if len(collection.Oversized) != 1 || collection.Oversized[0] != "big.bin" {
+		t.Errorf("CollectArtifactsFromDir() oversized = %v, want big.bin", collection.Oversized)
+	}
+	for _, dir := range []string{outputDir, filepath.Join(persistentArtifactsDir, containerID)} {
+		if _, err := os.Stat(filepath.Join(dir, "big.bin")); !os.IsNotExist(err) {
+			t.Errorf("big.bin was copied to %s", dir)
+		}
+		if data, err := os.ReadFile(filepath.Join(dir, "small.txt")); err != nil || len(data) != 10 {
+			t.Errorf("small.txt in %s = %d bytes, %v, want 10 bytes", dir, len(data), err)
+		}
+	}
+}
+
+func TestSetArtifactStoreDir(t *testing.T) {
+	defer func(dir string) { persistentArtifactsDir = dir }(persistentArtifactsDir)
+
+	storeDir := filepath.Join(t.TempDir(), "store")
+	if err := SetArtifactStoreDir(storeDir); err != nil {
+		t.Fatalf("SetArtifactStoreDir() unexpected error: %v", err)
+	}
+	if info, err := os.Stat(storeDir); err != nil || !info.IsDir() {
+		t.Fatalf("SetArtifactStoreDir() did not create %s: %v", storeDir, err)
+	}
+
+	artifactsDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(artifactsDir, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CollectArtifactsFromDir("test-store-dir", artifactsDir, ArtifactOutput{}); err != nil {
+		t.Fatalf("CollectArtifactsFromDir() unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(storeDir, "test-store-dir", "a.txt")); err != nil {
+		t.Errorf("artifact not kept in the configured store: %v", err)
+	}
+
+	// A path below a regular file can't be created
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetArtifactStoreDir(filepath.Join(file, "store")); err == nil {
+		t.Error("SetArtifactStoreDir() below a file succeeded")
+	}
+	if persistentArtifactsDir != storeDir {
+		t.Errorf("failed SetArtifactStoreDir() changed the store to %s", persistentArtifactsDir)
+	}
+}
+
+func TestCopyFileLimited(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.WriteFile(src, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "fits")
+	if err := copyFileLimited(src, dst, 10); err != nil {
+		t.Fatalf("copyFileLimited() unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "0123456789" {
+		t.Errorf("copyFileLimited() copied %q", data)
+	}
+
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(src, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := copyFileLimited(link, filepath.Join(dir, "linked"), 10); err != errArtifactNotRegular {
+		t.Errorf("copyFileLimited(symlink) error = %v, want errArtifactNotRegular", err)
+	}
+
+	dst = filepath.Join(dir, "too-large")
+	if err := copyFileLimited(src, dst, 9); err != errArtifactTooLarge {
+		t.Errorf("copyFileLimited() error = %v, want errArtifactTooLarge", err)
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("copyFileLimited() left a partial copy behind")
+	}
+}
+
+func TestWriteOutputFile(t *testing.T) {
+	tests := []struct {
+		policy   OverwritePolicy
+		wantPath string
+		wantData map[string]string
+	}{
+		{policy: OverwriteReplace, wantPath: "plot.png", wantData: map[string]string{"plot.png": "new", "plot-1.png": "older"}},
+		{policy: OverwriteSkip, wantPath: "", wantData: map[string]string{"plot.png": "old", "plot-1.png": "older"}},
+		{policy: OverwriteRename, wantPath: "plot-2.png", wantData: map[string]string{"plot.png": "old", "plot-1.png": "older", "plot-2.png": "new"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			dir := t.TempDir()
+			for name, data := range map[string]string{"plot.png": "old", "plot-1.png": "older"} {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := WriteOutputFile(ArtifactOutput{Dir: dir, Overwrite: tt.policy}, "plot.png", strings.NewReader("new"))
+			if err != nil {
+				t.Fatalf("WriteOutputFile() unexpected error: %v", err)
+			}
+			want := ""
+			if tt.wantPath != "" {
+				want = filepath.Join(dir, tt.wantPath)
+			}
+			if got != want {
+				t.Errorf("WriteOutputFile() = %q, want %q", got, want)
+			}
+			for name, data := range tt.wantData {
+				content, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil || string(content) != data {
+					t.Errorf("%s = %q (%v), want %q", name, content, err, data)
+				}
+			}
+		})
+	}
+}
+
+func TestCollectArtifactsFromDirOutputGlobs(t *testing.T) {
+	artifactsDir := t.TempDir()
+	outputDir := t.TempDir()
+	for _, name := range []string{"plot.png", "data.csv", "debug.log"} {
+		if err := os.WriteFile(filepath.Join(artifactsDir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	containerID := "test-output-globs"
+	defer os.RemoveAll(filepath.Join(persistentArtifactsDir, containerID))
+
+	collection, err := CollectArtifactsFromDir(containerID, artifactsDir, ArtifactOutput{Dir: outputDir, Globs: []string{"*.png", "data.csv"}})
+	if err != nil {
+		t.Fatalf("CollectArtifactsFromDir() unexpected error: %v", err)
+	}
+
+	if len(collection.URIs) != 3 {
+		t.Errorf("CollectArtifactsFromDir() registered %v, want all 3 artifacts", collection.URIs)
+	}
+	for name, wantCopied := range map[string]bool{"plot.png": true, "data.csv": true, "debug.log": false} {
+		_, err := os.Stat(filepath.Join(outputDir, name))
+		if copied := err == nil; copied != wantCopied {
+			t.Errorf("%s copied to output = %v, want %v", name, copied, wantCopied)
+		}
+	}
+}
+
+func TestCollectArtifactsFromDirSubdirectories(t *testing.T) {
+	artifactsDir := t.TempDir()
+	outputDir := t.TempDir()
+	deep := strings.Repeat("d/", MaxArtifactDepth+1)
+	files := map[string]string{
+		"summary.txt":         "top",
+		"plots/loss.png":      "png",
+		"plots/2024/acc.csv":  "a,b",
+		".meta/run.json":      "{}",
+		deep + "too-deep.txt": "x",
+	}
+	for name, content := range files {
+		path := filepath.Join(artifactsDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("/etc/passwd", filepath.Join(artifactsDir, "plots", "passwd")); err != nil {
+		t.Fatal(err)
+	}
+
+	containerID := "test-subdirectories"
+	defer CleanupContainerArtifacts(containerID)
+
+	collection, err := CollectArtifactsFromDir(containerID, artifactsDir, ArtifactOutput{Dir: outputDir, Globs: []string{"*.png"}})
+	if err != nil {
+		t.Fatalf("CollectArtifactsFromDir() unexpected error: %v", err)
+	}
+	want := []string{
+		"artifacts://test-subdirectories/plots/2024/acc.csv",
+		"artifacts://test-subdirectories/plots/loss.png",
+		"artifacts://test-subdirectories/summary.txt",
+	}
+	if strings.Join(collection.URIs, ",") != strings.Join(want, ",") {
+		t.Errorf("CollectArtifactsFromDir() = %v, want %v", collection.URIs, want)
+	}
+	skipped := strings.Join(collection.Skipped, "\n")
+	for _, name := range []string{".meta/", "plots/passwd", strings.TrimSuffix(deep, "/") + "/"} {
+		if !strings.Contains(skipped, name+" (") {
+			t.Errorf("CollectArtifactsFromDir() skipped %v, want %s among them", collection.Skipped, name)
+		}
+	}
+	if content, err := os.ReadFile(filepath.Join(outputDir, "plots", "loss.png")); err != nil || string(content) != "png" {
+		t.Errorf("plots/loss.png in output = %q, %v, want it copied with its directory", content, err)
+	}
+
+	// Reads are routed by the artifact URI templates as the server does
+	s := server.NewMCPServer("test", "v0.0.0", server.WithResourceCapabilities(true, true))
+	for _, uriTemplate := range ArtifactURITemplates() {
+		s.AddResourceTemplate(mcp.NewResourceTemplate(uriTemplate, "Container Artifacts"), GetContainerArtifact)
+	}
+	for _, uri := range want {
+		message := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":%q}}`, uri)
+		response, err := json.Marshal(s.HandleMessage(context.Background(), []byte(message)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(response), `"contents"`) {
+			t.Errorf("reading %s = %s, want its contents", uri, response)
+		}
+	}
+}
+
+func TestInlineImage(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]int{"small.png": 10, "large.png": 100, "notes.txt": 10}
+	for name, size := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		RegisterArtifact("test-inline", name, path)
+		defer unregisterArtifactPath(path)
+	}
+
+	defer SetMaxInlineImageBytes(maxInlineImageBytes)
+	SetMaxInlineImageBytes(50)
+
+	data, mimeType, ok := InlineImage("artifacts://test-inline/small.png")
+	if !ok || mimeType != "image/png" || data != "AAAAAAAAAAAAAA==" {
+		t.Errorf("InlineImage(small.png) = %q, %q, %v, want base64 data, image/png, true", data, mimeType, ok)
+	}
+	for _, name := range []string{"large.png", "notes.txt", "missing.png"} {
+		if _, _, ok := InlineImage("artifacts://test-inline/" + name); ok {
+			t.Errorf("InlineImage(%s) returned an inline image, want URI only", name)
+		}
+	}
+}
+
+// Under the stdio transport stdout carries the MCP protocol, so collecting
+// artifacts must not write to it even with debug logging on
+func TestCollectArtifactsFromDirQuietOnStdout(t *testing.T) {
+	defer logging.SetLevel(logging.DefaultLevel)
+	logging.SetLevel(logging.LevelDebug)
+
+	artifactsDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(artifactsDir, "out.txt"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	containerID := "test-quiet-stdout"
+	defer os.RemoveAll(filepath.Join(persistentArtifactsDir, containerID))
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := os.Stdout
+	os.Stdout = w
+	_, collectErr := CollectArtifactsFromDir(containerID, artifactsDir, ArtifactOutput{Dir: t.TempDir()})
+	os.Stdout = saved
+	w.Close()
+
+	written, _ := io.ReadAll(r)
+	if collectErr != nil {
+		t.Fatalf("CollectArtifactsFromDir() unexpected error: %v", collectErr)
+	}
+	if len(written) > 0 {
+		t.Errorf("CollectArtifactsFromDir() wrote to stdout: %q", written)
+	}
+}
+
+func TestCollectArtifactsFromDirEphemeral(t *testing.T) {
+	artifactsDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(artifactsDir, "scratch.txt"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	containerID := "test-ephemeral"
+	defer os.RemoveAll(filepath.Join(ephemeralArtifactsDir, containerID))
+
+	collection, err := CollectArtifactsFromDir(containerID, artifactsDir, ArtifactOutput{Ephemeral: true})
+	if err != nil || len(collection.URIs) != 1 {
+		t.Fatalf("CollectArtifactsFromDir() = %v, %v, want one artifact", collection.URIs, err)
+	}
+	if _, err := os.Stat(filepath.Join(persistentArtifactsDir, containerID)); !os.IsNotExist(err) {
+		t.Errorf("ephemeral artifact was written to the persistent store")
+	}
+	stored, _ := lookupArtifact(containerID + "/scratch.txt")
+	if _, err := os.Stat(stored); err != nil {
+		t.Fatalf("ephemeral artifact not stored: %v", err)
+	}
+
+	pruneEphemeralArtifacts(time.Now())
+	if _, ok := lookupArtifact(containerID + "/scratch.txt"); !ok {
+		t.Errorf("ephemeral artifact pruned before its TTL")
+	}
+
+	pruneEphemeralArtifacts(time.Now().Add(ephemeralArtifactTTL + time.Second))
+	if _, ok := lookupArtifact(containerID + "/scratch.txt"); ok {
+		t.Errorf("expired ephemeral artifact still registered")
+	}
+	if _, err := os.Stat(stored); !os.IsNotExist(err) {
+		t.Errorf("expired ephemeral artifact still on disk")
+	}
+}
+
+func TestCleanupContainerArtifacts(t *testing.T) {
+	containerID := "test-cleanup"
+	artifactsDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(artifactsDir, "out.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CollectArtifactsFromDir(containerID, artifactsDir, ArtifactOutput{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveContainerOutput(containerID, 0, "ab", "a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	exit, err := os.Stat(filepath.Join(persistentArtifactsDir, containerID, runMetadataDir, exitFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	artifacts, bytesFreed, err := CleanupContainerArtifacts(containerID)
+	if err != nil {
+		t.Fatalf("CleanupContainerArtifacts() unexpected error: %v", err)
+	}
+	// The artifact and the saved output and exit code
+	if want := 9 + exit.Size(); artifacts != 1 || bytesFreed != want {
+		t.Errorf("CleanupContainerArtifacts() = %d artifacts, %d bytes, want 1 artifact, %d bytes", artifacts, bytesFreed, want)
+	}
+	if _, ok := lookupArtifact(containerID + "/out.txt"); ok {
+		t.Error("artifact still registered after cleanup")
+	}
+	if _, err := os.Stat(filepath.Join(persistentArtifactsDir, containerID)); !os.IsNotExist(err) {
+		t.Errorf("container directory still exists after cleanup: %v", err)
+	}
+
+	if _, _, err := CleanupContainerArtifacts("../" + containerID); err == nil {
+		t.Error("CleanupContainerArtifacts() accepted a path as container ID")
+	}
+}
+
+func TestGetContainerArtifactBinary(t *testing.T) {
+	dir := t.TempDir()
+	// PNG signature followed by bytes that aren't valid UTF-8
+	png := []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0x00, 0xff, 0xfe}
+	files := map[string][]byte{"plot.png": png, "notes.txt": []byte("hello"), "run.log": []byte("done\n"), "data.bin": {0x00, 0x01}}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		RegisterArtifact("test-blob", name, path)
+		defer CleanupArtifact(path)
+	}
+
+	read := func(name string) interface{} {
+		t.Helper()
+		var request mcp.ReadResourceRequest
+		request.Params.URI = "artifacts://test-blob/" + name
+		contents, err := GetContainerArtifact(context.Background(), request)
+		if err != nil || len(contents) != 1 {
+			t.Fatalf("GetContainerArtifact(%s) = %v, %v, want one content", name, contents, err)
+		}
+		return contents[0]
+	}
+
+	blob, ok := read("plot.png").(mcp.BlobResourceContents)
+	if !ok {
+		t.Fatalf("plot.png returned as %T, want BlobResourceContents", read("plot.png"))
+	}
+	decoded, err := base64.StdEncoding.DecodeString(blob.Blob)
+	if err != nil || !bytes.Equal(decoded, png) || blob.MIMEType != "image/png" {
+		t.Errorf("plot.png = %v (%s), want the original bytes as image/png", decoded, blob.MIMEType)
+	}
+	if blob, ok := read("data.bin").(mcp.BlobResourceContents); !ok || blob.MIMEType != "application/octet-stream" {
+		t.Errorf("data.bin = %+v, want an application/octet-stream blob", read("data.bin"))
+	}
+
+	for name, mimeType := range map[string]string{"notes.txt": "text/plain", "run.log": "text/plain"} {
+		text, ok := read(name).(mcp.TextResourceContents)
+		if !ok || text.Text != string(files[name]) || text.MIMEType != mimeType {
+			t.Errorf("%s = %+v, want its content as %s text", name, read(name), mimeType)
+		}
+	}
+}
+
+func TestParseArtifactURI(t *testing.T) {
+	tests := []struct {
+		uri             string
+		wantContainerID string
+		wantName        string
+		wantErr         bool
+	}{
+		{uri: "artifacts://abc123/plot.png", wantContainerID: "abc123", wantName: "plot.png"},
+		{uri: "artifacts://abc123/plots/2024/loss.png", wantContainerID: "abc123", wantName: "plots/2024/loss.png"},
+		{uri: "artifacts://abc123", wantErr: true},
+		{uri: "artifacts://abc123/", wantErr: true},
+		{uri: "artifacts://../plot.png", wantErr: true},
+		{uri: "containers://abc123/logs", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			containerID, name, err := ParseArtifactURI(tt.uri)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseArtifactURI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if containerID != tt.wantContainerID || name != tt.wantName {
+				t.Errorf("ParseArtifactURI() = %q, %q, want %q, %q", containerID, name, tt.wantContainerID, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestGetContainerArtifactFlatAndNested(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"report.txt": "flat", "plots/2024/notes.txt": "nested"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		RegisterArtifact("test-nested-uri", name, path)
+		defer CleanupArtifact(path)
+	}
+
+	for uri, want := range map[string]string{
+		"artifacts://test-nested-uri/report.txt":           "flat",
+		"artifacts://test-nested-uri/plots/2024/notes.txt": "nested",
+	} {
+		var request mcp.ReadResourceRequest
+		request.Params.URI = uri
+		contents, err := GetContainerArtifact(context.Background(), request)
+		if err != nil || len(contents) != 1 {
+			t.Fatalf("GetContainerArtifact(%s) = %v, %v, want one content", uri, contents, err)
+		}
+		if text, ok := contents[0].(mcp.TextResourceContents); !ok || text.Text != want || text.URI != uri {
+			t.Errorf("GetContainerArtifact(%s) = %+v, want %q", uri, contents[0], want)
+		}
+	}
+
+	var request mcp.ReadResourceRequest
+	request.Params.URI = "artifacts://test-nested-uri/plots/missing.txt"
+	if _, err := GetContainerArtifact(context.Background(), request); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("GetContainerArtifact(missing) error = %v, want not found", err)
+	}
+}
+
+func TestFlushArtifacts(t *testing.T) {
+	artifactsDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(artifactsDir, "out.txt"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(filepath.Join(persistentArtifactsDir, "test-flush-persistent"))
+	if _, err := CollectArtifactsFromDir("test-flush-persistent", artifactsDir, ArtifactOutput{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CollectArtifactsFromDir("test-flush-ephemeral", artifactsDir, ArtifactOutput{Ephemeral: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	FlushArtifacts()
+
+	for _, key := range []string{"test-flush-persistent/out.txt", "test-flush-ephemeral/out.txt"} {
+		if _, ok := lookupArtifact(key); ok {
+			t.Errorf("%s still registered after flush", key)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(ephemeralArtifactsDir, "test-flush-ephemeral")); !os.IsNotExist(err) {
+		t.Errorf("ephemeral artifacts still on disk after flush")
+	}
+	if _, err := os.Stat(filepath.Join(persistentArtifactsDir, "test-flush-persistent", "out.txt")); err != nil {
+		t.Errorf("persistent artifact deleted by flush: %v", err)
+	}
+}
@@ -2,7 +2,13 @@ package resources
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/docker/docker/api/types/container"
@@ -12,19 +18,218 @@ import (
 	"github.com/moby/moby/client"
 )
 
+// maxLogResourceBytes bounds the output returned by one read of the logs resource
+const maxLogResourceBytes = 1024 * 1024
+
+// logsQuery selects part of a container's logs: the last tail lines, or the
+// output from byte offset since on
+type logsQuery struct {
+	tail     int
+	since    int64
+	hasSince bool
+}
+
+// GetContainerLogs returns the output of a container. The URI can select
+// part of it with containers://{id}/logs?tail=N for the last N lines or
+// ?since=<offset> for the output from a byte offset on. Reads return at most
+// maxLogResourceBytes, with a notice saying how to get the rest.
 func GetContainerLogs(ctx context.Context, request mcp.ReadResourceRequest) ([]interface{}, error) {
+	containerID, query, err := parseLogsURI(request.Params.URI)
+	if err != nil {
+		return nil, err
+	}
 
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	combined, err := ReadContainerLogs(ctx, containerID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Docker client: %w", err)
+		return nil, err
 	}
-	defer cli.Close()
 
-	containerIDPath, found := strings.CutPrefix(request.Params.URI, "containers://") // Extract ID from the full URI
+	return []interface{}{
+		mcp.TextResourceContents{
+			ResourceContents: mcp.ResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: "text/plain",
+			},
+			Text: selectLogs(containerID, combined, query),
+		},
+	}, nil
+}
+
+// parseLogsURI returns the container ID and the query of a logs resource URI
+func parseLogsURI(uri string) (string, logsQuery, error) {
+	var query logsQuery
+	rest, found := strings.CutPrefix(uri, "containers://")
 	if !found {
-		return nil, fmt.Errorf("invalid URI: %s", request.Params.URI)
+		return "", query, fmt.Errorf("invalid URI: %s", uri)
+	}
+	rest, rawQuery, _ := strings.Cut(rest, "?")
+	containerID, found := strings.CutSuffix(rest, "/logs")
+	if !found || containerID == "" {
+		return "", query, fmt.Errorf("invalid URI: %s", uri)
 	}
-	containerID := strings.TrimSuffix(containerIDPath, "/logs")
+
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", query, fmt.Errorf("invalid query in URI %s: %w", uri, err)
+	}
+	for name := range values {
+		value := values.Get(name)
+		switch name {
+		case "tail":
+			query.tail, err = strconv.Atoi(value)
+			if err != nil || query.tail <= 0 {
+				return "", query, fmt.Errorf("tail must be a positive number of lines, got %q", value)
+			}
+		case "since":
+			query.since, err = strconv.ParseInt(value, 10, 64)
+			if err != nil || query.since < 0 {
+				return "", query, fmt.Errorf("since must be a non-negative byte offset, got %q", value)
+			}
+			query.hasSince = true
+		default:
+			return "", query, fmt.Errorf("unsupported logs parameter %q, use tail or since", name)
+		}
+	}
+	if query.tail > 0 && query.hasSince {
+		return "", query, fmt.Errorf("tail and since can't be combined")
+	}
+	return containerID, query, nil
+}
+
+// selectLogs returns the part of a container's logs a query selects, capped
+// at maxLogResourceBytes. A page read with since that stops short says where
+// the next one starts; otherwise the end of the output is kept, which is where
+// errors usually are.
+func selectLogs(containerID string, logs string, query logsQuery) string {
+	if query.hasSince {
+		since := min(query.since, int64(len(logs)))
+		page := logs[since:]
+		if len(page) <= maxLogResourceBytes {
+			return page
+		}
+		return fmt.Sprintf("%s\n... [more output, read containers://%s/logs?since=%d for the next part]",
+			page[:maxLogResourceBytes], containerID, since+maxLogResourceBytes)
+	}
+
+	if query.tail > 0 {
+		logs = lastLines(logs, query.tail)
+	}
+	if len(logs) <= maxLogResourceBytes {
+		return logs
+	}
+	return fmt.Sprintf("... [truncated %d bytes, read containers://%s/logs?since=0 for the output from the start]\n%s",
+		len(logs)-maxLogResourceBytes, containerID, logs[len(logs)-maxLogResourceBytes:])
+}
+
+// lastLines returns the last n lines of s. A trailing newline doesn't start a line.
+func lastLines(s string, n int) string {
+	end := len(strings.TrimSuffix(s, "\n"))
+	for ; n > 0; n-- {
+		end = strings.LastIndexByte(s[:end], '\n')
+		if end < 0 {
+			return s
+		}
+	}
+	return s[end+1:]
+}
+
+// ReadContainerLogs returns the combined stdout and stderr of a container
+func ReadContainerLogs(ctx context.Context, containerID string) (string, error) {
+	combined, _, _, err := ReadContainerOutput(ctx, containerID)
+	return combined, err
+}
+
+// ReadContainerStreams returns the stdout and stderr of a container separately
+func ReadContainerStreams(ctx context.Context, containerID string) (stdout string, stderr string, err error) {
+	_, stdout, stderr, err = ReadContainerOutput(ctx, containerID)
+	return stdout, stderr, err
+}
+
+// Files in a run's metadata directory holding the output of its removed container
+const (
+	combinedOutputFile = "output.log"
+	stdoutFile         = "stdout.log"
+	stderrFile         = "stderr.log"
+	exitFile           = "exit.json"
+)
+
+// savedExit is how a finished container exited, stored alongside its output
+type savedExit struct {
+	ExitCode int64 `json:"exitCode"`
+}
+
+// SaveContainerOutput stores the output and exit code of a finished container
+// in the run's metadata directory, so they can still be read once the
+// container is removed
+func SaveContainerOutput(containerID string, exitCode int64, combined string, stdout string, stderr string) error {
+	dir := filepath.Join(persistentArtifactsDir, containerID, runMetadataDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create run metadata directory: %w", err)
+	}
+	exit, err := json.Marshal(savedExit{ExitCode: exitCode})
+	if err != nil {
+		return fmt.Errorf("failed to encode exit code: %w", err)
+	}
+	for name, output := range map[string]string{combinedOutputFile: combined, stdoutFile: stdout, stderrFile: stderr, exitFile: string(exit)} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(output), 0644); err != nil {
+			return fmt.Errorf("failed to save container output: %w", err)
+		}
+	}
+	return nil
+}
+
+// SavedExitCode returns the exit code stored by SaveContainerOutput
+func SavedExitCode(containerID string) (int64, bool) {
+	if !validContainerID(containerID) {
+		return 0, false
+	}
+	data, err := os.ReadFile(filepath.Join(persistentArtifactsDir, containerID, runMetadataDir, exitFile))
+	if err != nil {
+		return 0, false
+	}
+	var exit savedExit
+	if err := json.Unmarshal(data, &exit); err != nil {
+		return 0, false
+	}
+	return exit.ExitCode, true
+}
+
+// validContainerID reports whether a container ID can name a directory in the
+// artifact stores. IDs come from clients, so they must not name any other directory.
+func validContainerID(containerID string) bool {
+	return containerID != "" && containerID != "." && containerID != ".." && filepath.Base(containerID) == containerID
+}
+
+// savedContainerOutput returns the output stored by SaveContainerOutput
+func savedContainerOutput(containerID string) (combined string, stdout string, stderr string, ok bool) {
+	if !validContainerID(containerID) {
+		return "", "", "", false
+	}
+	dir := filepath.Join(persistentArtifactsDir, containerID, runMetadataDir)
+	var outputs [3]string
+	for i, name := range []string{combinedOutputFile, stdoutFile, stderrFile} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return "", "", "", false
+		}
+		outputs[i] = string(data)
+	}
+	return outputs[0], outputs[1], outputs[2], true
+}
+
+// ReadContainerOutput returns the output of a container, both combined and by
+// stream. The output of a finished run is read from where it was saved, as
+// its container may already have been removed.
+func ReadContainerOutput(ctx context.Context, containerID string) (combined string, stdout string, stderr string, err error) {
+	if combined, stdout, stderr, ok := savedContainerOutput(containerID); ok {
+		return combined, stdout, stderr, nil
+	}
+
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to create Docker client: %w", err)
+	}
+	defer cli.Close()
 
 	// Set default ContainerLogsOptions
 	logOpts := container.LogsOptions{
@@ -35,25 +240,14 @@ func GetContainerLogs(ctx context.Context, request mcp.ReadResourceRequest) ([]i
 	// Actually fetch the logs
 	reader, err := cli.ContainerLogs(ctx, containerID, logOpts)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching container logs: %w", err)
+		return "", "", "", fmt.Errorf("error fetching container logs: %w", err)
 	}
 	defer reader.Close()
 
-	var b strings.Builder
-	if _, err := stdcopy.StdCopy(&b, &b, reader); err != nil {
-		return nil, fmt.Errorf("error copying container logs: %w", err)
+	var b, outB, errB strings.Builder
+	if _, err := stdcopy.StdCopy(io.MultiWriter(&b, &outB), io.MultiWriter(&b, &errB), reader); err != nil {
+		return "", "", "", fmt.Errorf("error copying container logs: %w", err)
 	}
 
-	// Combine them. You could also return them separately if you prefer.
-	combined := b.String()
-
-	return []interface{}{
-		mcp.TextResourceContents{
-			ResourceContents: mcp.ResourceContents{
-				URI:      fmt.Sprintf("containers://%s/logs", containerID),
-				MIMEType: "text/plain",
-			},
-			Text: combined,
-		},
-	}, nil
+	return MaskRunSecrets(containerID, b.String()), MaskRunSecrets(containerID, outB.String()), MaskRunSecrets(containerID, errB.String()), nil
 }
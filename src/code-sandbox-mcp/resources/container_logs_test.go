@@ -0,0 +1,133 @@
+package resources
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestSaveContainerOutput(t *testing.T) {
+	containerID := "test-saved-output"
+	defer os.RemoveAll(filepath.Join(persistentArtifactsDir, containerID))
+
+	if err := SaveContainerOutput(containerID, 3, "out\nerr\n", "out\n", "err\n"); err != nil {
+		t.Fatalf("SaveContainerOutput() unexpected error: %v", err)
+	}
+
+	// The saved output is read without asking Docker for the container
+	combined, stdout, stderr, err := ReadContainerOutput(context.Background(), containerID)
+	if err != nil {
+		t.Fatalf("ReadContainerOutput() unexpected error: %v", err)
+	}
+	if combined != "out\nerr\n" || stdout != "out\n" || stderr != "err\n" {
+		t.Errorf("ReadContainerOutput() = %q, %q, %q, want the saved output", combined, stdout, stderr)
+	}
+	if exitCode, ok := SavedExitCode(containerID); !ok || exitCode != 3 {
+		t.Errorf("SavedExitCode() = %d, %v, want 3", exitCode, ok)
+	}
+}
+
+func TestSavedContainerOutputRejectsPaths(t *testing.T) {
+	for _, containerID := range []string{"", "..", "../other", "a/b"} {
+		if _, _, _, ok := savedContainerOutput(containerID); ok {
+			t.Errorf("savedContainerOutput(%q) found output, want none", containerID)
+		}
+		if _, ok := SavedExitCode(containerID); ok {
+			t.Errorf("SavedExitCode(%q) found an exit code, want none", containerID)
+		}
+	}
+}
+
+func TestParseLogsURI(t *testing.T) {
+	tests := []struct {
+		uri     string
+		want    logsQuery
+		wantErr bool
+	}{
+		{uri: "containers://abc/logs", want: logsQuery{}},
+		{uri: "containers://abc/logs?tail=200", want: logsQuery{tail: 200}},
+		{uri: "containers://abc/logs?since=0", want: logsQuery{hasSince: true}},
+		{uri: "containers://abc/logs?since=4096", want: logsQuery{since: 4096, hasSince: true}},
+		{uri: "containers://abc/logs?tail=0", wantErr: true},
+		{uri: "containers://abc/logs?tail=x", wantErr: true},
+		{uri: "containers://abc/logs?since=-1", wantErr: true},
+		{uri: "containers://abc/logs?tail=5&since=10", wantErr: true},
+		{uri: "containers://abc/logs?lines=5", wantErr: true},
+		{uri: "containers:///logs", wantErr: true},
+		{uri: "artifacts://abc/logs", wantErr: true},
+	}
+	for _, tt := range tests {
+		containerID, query, err := parseLogsURI(tt.uri)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseLogsURI(%q) = %+v, want an error", tt.uri, query)
+			}
+			continue
+		}
+		if err != nil || containerID != "abc" || query != tt.want {
+			t.Errorf("parseLogsURI(%q) = %q, %+v, %v, want abc, %+v", tt.uri, containerID, query, err, tt.want)
+		}
+	}
+}
+
+func TestLastLines(t *testing.T) {
+	tests := []struct {
+		logs string
+		n    int
+		want string
+	}{
+		{"a\nb\nc\n", 2, "b\nc\n"},
+		{"a\nb\nc", 2, "b\nc"},
+		{"a\nb\nc\n", 3, "a\nb\nc\n"},
+		{"a\nb\nc\n", 10, "a\nb\nc\n"},
+		{"", 1, ""},
+	}
+	for _, tt := range tests {
+		if got := lastLines(tt.logs, tt.n); got != tt.want {
+			t.Errorf("lastLines(%q, %d) = %q, want %q", tt.logs, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestSelectLogsCapsOutput(t *testing.T) {
+	logs := strings.Repeat("x", maxLogResourceBytes) + "end"
+
+	full := selectLogs("abc", logs, logsQuery{})
+	if !strings.HasPrefix(full, "... [truncated 3 bytes, read containers://abc/logs?since=0") || !strings.HasSuffix(full, "end") {
+		t.Errorf("full logs not truncated to their end: %.100q", full)
+	}
+
+	page := selectLogs("abc", logs, logsQuery{hasSince: true})
+	if !strings.HasSuffix(page, "[more output, read containers://abc/logs?since=1048576 for the next part]") {
+		t.Errorf("first page doesn't point at the next one: ...%q", page[len(page)-100:])
+	}
+	if next := selectLogs("abc", logs, logsQuery{since: maxLogResourceBytes, hasSince: true}); next != "end" {
+		t.Errorf("next page = %q, want end", next)
+	}
+	if past := selectLogs("abc", logs, logsQuery{since: 1 << 30, hasSince: true}); past != "" {
+		t.Errorf("page past the end = %.100q, want nothing", past)
+	}
+}
+
+func TestGetContainerLogsTail(t *testing.T) {
+	containerID := "test-logs-tail"
+	defer os.RemoveAll(filepath.Join(persistentArtifactsDir, containerID))
+	if err := SaveContainerOutput(containerID, 0, "one\ntwo\nthree\n", "one\ntwo\nthree\n", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	request := mcp.ReadResourceRequest{}
+	request.Params.URI = "containers://" + containerID + "/logs?tail=2"
+	contents, err := GetContainerLogs(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := contents[0].(mcp.TextResourceContents)
+	if text.Text != "two\nthree\n" || text.URI != request.Params.URI {
+		t.Errorf("GetContainerLogs() = %q at %s, want the last two lines", text.Text, text.URI)
+	}
+}
@@ -0,0 +1,156 @@
+package resources
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// RunStatus describes the lifecycle state of a sandbox run
+type RunStatus string
+
+const (
+	RunStatusRunning   RunStatus = "running"
+	RunStatusExited    RunStatus = "exited"
+	RunStatusCancelled RunStatus = "cancelled"
+)
+
+// Run holds the metadata the server keeps about a container it started
+type Run struct {
+	ContainerID string
+	SessionID   string
+	Language    string
+	Image       string
+	StartedAt   time.Time
+	Status      RunStatus
+	LogOffset   int64        // Log bytes already returned by tail_logs
+	Steps       []StepResult // Outcome of each entrypoint step, for runs made of steps
+	OOMKilled   bool         // The container was killed for exceeding its memory limit
+	ExitCode    int64        // Exit code of the container, set once Status is exited
+	FinishedAt  time.Time    // When the run exited, set together with ExitCode
+	// PullDuration is how long pulling the run's image took, InstalledAt when
+	// an isolated run reported the end of its install step
+	PullDuration time.Duration
+	InstalledAt  time.Time
+	// PooledContainerID is the pooled container a run executed in, shared
+	// with the runs before and after it. ContainerID then holds the ID of the
+	// Docker exec that ran the command, which identifies the run.
+	PooledContainerID string
+}
+
+// StepResult is the outcome of one entrypoint step of a run
+type StepResult struct {
+	Command  string
+	Started  bool // The step began running
+	Finished bool // The step ran to completion, so ExitCode and Duration are set
+	ExitCode int
+	Duration time.Duration
+	Logs     string // Output the step produced
+}
+
+// Registry of runs keyed by container ID
+var (
+	runsMu       sync.RWMutex
+	runsRegistry = make(map[string]*Run)
+)
+
+// RegisterRun adds a run to the registry
+func RegisterRun(run Run) {
+	runsMu.Lock()
+	defer runsMu.Unlock()
+	if run.Status == "" {
+		run.Status = RunStatusRunning
+	}
+	runsRegistry[run.ContainerID] = &run
+}
+
+// GetRun returns a copy of the run registered for a container
+func GetRun(containerID string) (Run, bool) {
+	runsMu.RLock()
+	defer runsMu.RUnlock()
+	run, ok := runsRegistry[containerID]
+	if !ok {
+		return Run{}, false
+	}
+	return *run, true
+}
+
+// SetRunStatus updates the status of a registered run.
+// A cancelled run keeps its status so that late completions don't overwrite it.
+func SetRunStatus(containerID string, status RunStatus) {
+	runsMu.Lock()
+	defer runsMu.Unlock()
+	run, ok := runsRegistry[containerID]
+	if !ok {
+		return
+	}
+	if run.Status == RunStatusCancelled {
+		return
+	}
+	run.Status = status
+}
+
+// SetRunLogOffset records how much of a run's log output has been returned
+func SetRunLogOffset(containerID string, offset int64) {
+	runsMu.Lock()
+	defer runsMu.Unlock()
+	if run, ok := runsRegistry[containerID]; ok {
+		run.LogOffset = offset
+	}
+}
+
+// SetRunSteps records the step results of a finished run
+func SetRunSteps(containerID string, steps []StepResult) {
+	runsMu.Lock()
+	defer runsMu.Unlock()
+	if run, ok := runsRegistry[containerID]; ok {
+		run.Steps = steps
+	}
+}
+
+// SetRunExited marks a run as exited with the container's exit code
+func SetRunExited(containerID string, exitCode int64) {
+	runsMu.Lock()
+	defer runsMu.Unlock()
+	run, ok := runsRegistry[containerID]
+	if !ok || run.Status == RunStatusCancelled {
+		return
+	}
+	run.Status = RunStatusExited
+	run.ExitCode = exitCode
+	run.FinishedAt = time.Now()
+}
+
+// SetRunInstalled records when a run finished its dependency install step
+func SetRunInstalled(containerID string, at time.Time) {
+	runsMu.Lock()
+	defer runsMu.Unlock()
+	if run, ok := runsRegistry[containerID]; ok {
+		run.InstalledAt = at
+	}
+}
+
+// SetRunOOMKilled records that a run was killed for exceeding its memory limit
+func SetRunOOMKilled(containerID string) {
+	runsMu.Lock()
+	defer runsMu.Unlock()
+	if run, ok := runsRegistry[containerID]; ok {
+		run.OOMKilled = true
+	}
+}
+
+// ListSessionRuns returns all runs registered for a session, oldest first
+func ListSessionRuns(sessionID string) []Run {
+	runsMu.RLock()
+	defer runsMu.RUnlock()
+	var runs []Run
+	for _, run := range runsRegistry {
+		if run.SessionID == sessionID {
+			runs = append(runs, *run)
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].StartedAt.Before(runs[j].StartedAt)
+	})
+	return runs
+}
@@ -0,0 +1,21 @@
+package resources
+
+import "testing"
+
+func TestSetRunExited(t *testing.T) {
+	RegisterRun(Run{ContainerID: "exited-run"})
+	SetRunExited("exited-run", 2)
+	if run, _ := GetRun("exited-run"); run.Status != RunStatusExited || run.ExitCode != 2 {
+		t.Errorf("got status %s and exit code %d, want exited with exit code 2", run.Status, run.ExitCode)
+	} else if run.FinishedAt.IsZero() {
+		t.Error("FinishedAt was not set")
+	}
+
+	// A cancelled run keeps its status when the container exits afterwards
+	RegisterRun(Run{ContainerID: "cancelled-run"})
+	SetRunStatus("cancelled-run", RunStatusCancelled)
+	SetRunExited("cancelled-run", 137)
+	if run, _ := GetRun("cancelled-run"); run.Status != RunStatusCancelled {
+		t.Errorf("got status %s, want cancelled", run.Status)
+	}
+}
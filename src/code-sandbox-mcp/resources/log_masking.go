@@ -0,0 +1,77 @@
+package resources
+
+import (
+	"strings"
+	"sync"
+)
+
+// secretMask replaces secrets in container output
+const secretMask = "****"
+
+// Values masked in container output before it is returned or stored, such as
+// package registry credentials an install step may print
+var (
+	logSecretsMu sync.RWMutex
+	logSecrets   []string
+)
+
+// SetLogSecrets sets the values masked in container output. Empty values are ignored.
+func SetLogSecrets(secrets []string) {
+	logSecretsMu.Lock()
+	defer logSecretsMu.Unlock()
+	logSecrets = nil
+	for _, secret := range secrets {
+		if secret != "" {
+			logSecrets = append(logSecrets, secret)
+		}
+	}
+}
+
+// MaskSecrets replaces every configured secret in s with a mask
+func MaskSecrets(s string) string {
+	logSecretsMu.RLock()
+	defer logSecretsMu.RUnlock()
+	for _, secret := range logSecrets {
+		s = strings.ReplaceAll(s, secret, secretMask)
+	}
+	return s
+}
+
+// Values masked in the output of a single run only, such as the secrets the
+// run was given, by container ID
+var runSecrets = make(map[string][]string)
+
+// SetRunSecrets sets the values masked in the output of a run, on top of the
+// configured ones. Empty values are ignored.
+func SetRunSecrets(containerID string, secrets []string) {
+	logSecretsMu.Lock()
+	defer logSecretsMu.Unlock()
+	var kept []string
+	for _, secret := range secrets {
+		if secret != "" {
+			kept = append(kept, secret)
+		}
+	}
+	if len(kept) > 0 {
+		runSecrets[containerID] = kept
+	}
+}
+
+// ForgetRunSecrets drops the secrets of a run once its output has been saved masked
+func ForgetRunSecrets(containerID string) {
+	logSecretsMu.Lock()
+	defer logSecretsMu.Unlock()
+	delete(runSecrets, containerID)
+}
+
+// MaskRunSecrets replaces every configured secret and every secret of the
+// run in s with a mask
+func MaskRunSecrets(containerID string, s string) string {
+	s = MaskSecrets(s)
+	logSecretsMu.RLock()
+	defer logSecretsMu.RUnlock()
+	for _, secret := range runSecrets[containerID] {
+		s = strings.ReplaceAll(s, secret, secretMask)
+	}
+	return s
+}
@@ -0,0 +1,23 @@
+package resources
+
+import "testing"
+
+func TestMaskRunSecrets(t *testing.T) {
+	SetLogSecrets([]string{"registry-token"})
+	defer SetLogSecrets(nil)
+	SetRunSecrets("run-with-secrets", []string{"s3cr3t-value", ""})
+
+	logs := "token registry-token, password s3cr3t-value\n"
+	if got, want := MaskRunSecrets("run-with-secrets", logs), "token ****, password ****\n"; got != want {
+		t.Errorf("MaskRunSecrets() = %q, want %q", got, want)
+	}
+	// Another run's secrets are not its own
+	if got, want := MaskRunSecrets("other-run", logs), "token ****, password s3cr3t-value\n"; got != want {
+		t.Errorf("MaskRunSecrets() for another run = %q, want %q", got, want)
+	}
+
+	ForgetRunSecrets("run-with-secrets")
+	if got, want := MaskRunSecrets("run-with-secrets", logs), "token ****, password s3cr3t-value\n"; got != want {
+		t.Errorf("MaskRunSecrets() after ForgetRunSecrets = %q, want %q", got, want)
+	}
+}
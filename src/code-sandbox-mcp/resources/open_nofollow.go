@@ -0,0 +1,8 @@
+//go:build !windows
+
+package resources
+
+import "syscall"
+
+// openNoFollow makes opening a symlink fail instead of opening its target
+const openNoFollow = syscall.O_NOFOLLOW
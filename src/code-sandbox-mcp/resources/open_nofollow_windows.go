@@ -0,0 +1,5 @@
+package resources
+
+// openNoFollow is not available on Windows, where copyFileLimited relies on
+// checking the opened file instead
+const openNoFollow = 0
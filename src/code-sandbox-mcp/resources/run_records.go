@@ -0,0 +1,77 @@
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// maxRecordLogBytes bounds how much log output is kept in a run record
+const maxRecordLogBytes = 64 * 1024
+
+// recordRuns controls whether a JSON record of every finished run is written
+var recordRuns bool
+
+// SetRunRecording enables or disables writing run records
+func SetRunRecording(enabled bool) {
+	recordRuns = enabled
+}
+
+// RunRecordingEnabled reports whether run records are written
+func RunRecordingEnabled() bool {
+	return recordRuns
+}
+
+// RunRecord is the durable, machine-readable account of a finished run
+type RunRecord struct {
+	ContainerID string    `json:"containerId"`
+	SessionID   string    `json:"sessionId,omitempty"`
+	Language    string    `json:"language"`
+	Image       string    `json:"image"`
+	ImageDigest string    `json:"imageDigest,omitempty"`
+	Command     []string  `json:"command"`
+	StartedAt   time.Time `json:"startedAt"`
+	FinishedAt  time.Time `json:"finishedAt"`
+	DurationMs  int64     `json:"durationMs"`
+	Status      RunStatus `json:"status"`
+	ExitCode    int64     `json:"exitCode"`
+	TimedOut    bool      `json:"timedOut,omitempty"`
+	OOMKilled   bool      `json:"oomKilled,omitempty"`
+	Artifacts   []string  `json:"artifacts,omitempty"`
+	Logs        string    `json:"logs"`
+	// LogsTruncated is set when only the end of the logs was kept
+	LogsTruncated bool `json:"logsTruncated,omitempty"`
+}
+
+// runMetadataDir is the subdirectory of a run's artifact directory that holds
+// server-written files. Artifacts are always plain files, so it can't clash with one.
+const runMetadataDir = ".meta"
+
+// WriteRunRecord stores a run record as run.json in the run's artifact
+// directory, so it is removed together with the artifacts
+func WriteRunRecord(record RunRecord) error {
+	if record.DurationMs == 0 && !record.FinishedAt.IsZero() {
+		record.DurationMs = record.FinishedAt.Sub(record.StartedAt).Milliseconds()
+	}
+	if len(record.Logs) > maxRecordLogBytes {
+		// Keep the end of the output, which is where errors usually are
+		record.Logs = record.Logs[len(record.Logs)-maxRecordLogBytes:]
+		record.LogsTruncated = true
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode run record: %w", err)
+	}
+
+	dir := filepath.Join(persistentArtifactsDir, record.ContainerID, runMetadataDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create run record directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "run.json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write run record: %w", err)
+	}
+	return nil
+}
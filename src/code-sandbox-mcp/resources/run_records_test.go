@@ -0,0 +1,49 @@
+package resources
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteRunRecord(t *testing.T) {
+	containerID := "test-run-record"
+	defer os.RemoveAll(filepath.Join(persistentArtifactsDir, containerID))
+
+	started := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	logs := strings.Repeat("x", maxRecordLogBytes) + "tail"
+	if err := WriteRunRecord(RunRecord{
+		ContainerID: containerID,
+		Language:    "python",
+		Command:     []string{"/bin/sh", "-c", "python3 main.py"},
+		StartedAt:   started,
+		FinishedAt:  started.Add(1500 * time.Millisecond),
+		Status:      RunStatusExited,
+		ExitCode:    1,
+		Logs:        logs,
+	}); err != nil {
+		t.Fatalf("WriteRunRecord() unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(persistentArtifactsDir, containerID, runMetadataDir, "run.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got RunRecord
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.DurationMs != 1500 {
+		t.Errorf("DurationMs = %d, want 1500", got.DurationMs)
+	}
+	if got.ExitCode != 1 {
+		t.Errorf("ExitCode = %d, want 1", got.ExitCode)
+	}
+	if !got.LogsTruncated || len(got.Logs) != maxRecordLogBytes || !strings.HasSuffix(got.Logs, "tail") {
+		t.Errorf("logs were not truncated to the last %d bytes", maxRecordLogBytes)
+	}
+}
@@ -0,0 +1,341 @@
+// Package streamable serves an MCP server over the Streamable HTTP transport
+// of the MCP specification. Clients POST JSON-RPC messages to a single
+// endpoint and get the responses back in the HTTP response, with a session ID
+// handed out on initialization tying their requests together.
+//
+// Responses are sent as a single JSON body, unless a tool sends notifications
+// while the request is handled and the client accepts an event stream. The
+// response then becomes a stream of Server-Sent Events carrying the
+// notifications and finally the response. The server doesn't offer a stream
+// for notifications outside of a request, which the specification allows.
+package streamable
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// DefaultEndpoint is the path the MCP endpoint is served at
+const DefaultEndpoint = "/mcp"
+
+// SessionHeader carries the session ID of a client's requests
+const SessionHeader = "Mcp-Session-Id"
+
+// maxRequestBytes bounds the size of a POST body. Requests carry code and
+// files inline, so this is well above their own limits.
+const maxRequestBytes = 32 * 1024 * 1024
+
+// Server is a Streamable HTTP transport for an MCP server
+type Server struct {
+	mcpServer *server.MCPServer
+	endpoint  string
+	srv       *http.Server
+	sessions  sync.Map // Session IDs of initialized clients
+}
+
+// NewServer returns a transport serving mcpServer at endpoint
+func NewServer(mcpServer *server.MCPServer, endpoint string) *Server {
+	return &Server{mcpServer: mcpServer, endpoint: endpoint}
+}
+
+// Start listens on addr and serves the endpoint until Shutdown is called,
+// after which it returns http.ErrServerClosed
+func (s *Server) Start(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle(s.endpoint, s)
+	s.srv = &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s.srv.ListenAndServe()
+}
+
+// Shutdown stops accepting requests and waits for the ones in progress,
+// such as running tool calls, to finish or for ctx to be done
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.srv == nil {
+		return nil
+	}
+	return s.srv.Shutdown(ctx)
+}
+
+// ServeHTTP handles a request to the endpoint
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Browsers can be made to call local servers, so requests from other
+	// web origins are refused
+	if !allowedOrigin(r) {
+		http.Error(w, "Forbidden: origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		s.handlePost(w, r)
+	case http.MethodDelete:
+		s.handleDelete(w, r)
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Notifier sends a notification to the client of the request being handled
+type Notifier func(method string, params map[string]interface{}) error
+
+// notifierKey is the context key of a request's Notifier
+type notifierKey struct{}
+
+// NotifierFromContext returns the Notifier of a request served by this
+// transport. mcp-go delivers the notifications of an MCPServer to its own
+// SSE transport only, so tools send theirs through the Notifier instead.
+func NotifierFromContext(ctx context.Context) (Notifier, bool) {
+	notify, ok := ctx.Value(notifierKey{}).(Notifier)
+	return notify, ok
+}
+
+// errStreamClosed is returned for notifications sent after the response
+var errStreamClosed = errors.New("the request's response has already been sent")
+
+// eventStream turns a response into a stream of Server-Sent Events with the
+// first notification, so requests that send none still get a JSON body
+type eventStream struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	started bool
+	closed  bool
+}
+
+// notify writes a notification as an event, starting the stream if needed
+func (e *eventStream) notify(method string, params map[string]interface{}) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return errStreamClosed
+	}
+	if !e.started {
+		e.w.Header().Set("Content-Type", "text/event-stream")
+		e.w.Header().Set("Cache-Control", "no-cache")
+		e.w.WriteHeader(http.StatusOK)
+		e.started = true
+	}
+	return e.writeEvent(mcp.JSONRPCNotification{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		Notification: mcp.Notification{
+			Method: method,
+			Params: mcp.NotificationParams{AdditionalFields: params},
+		},
+	})
+}
+
+// close refuses further notifications and reports whether the stream was
+// started, in which case the responses go out as an event too
+func (e *eventStream) close() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.closed = true
+	return e.started
+}
+
+// writeEvent writes a JSON-RPC message as an event and flushes it
+func (e *eventStream) writeEvent(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(e.w, "event: message\ndata: %s\n\n", data); err != nil {
+		return err
+	}
+	if f, ok := e.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// acceptsEventStream reports whether the client takes a response as an event stream
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// message holds the fields of a JSON-RPC message that decide how it is handled
+type message struct {
+	Method string      `json:"method"`
+	ID     interface{} `json:"id"`
+}
+
+// handlePost handles a JSON-RPC message, or a batch of them, and writes the
+// responses to its requests. An initialize request starts a new session;
+// every other request must carry the ID of a session.
+func (s *Server) handlePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, mcp.INVALID_REQUEST, "request body too large")
+		return
+	}
+
+	raw, batch, err := splitBatch(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, mcp.PARSE_ERROR, "Parse error")
+		return
+	}
+	messages := make([]message, len(raw))
+	initialize := false
+	for i, m := range raw {
+		if err := json.Unmarshal(m, &messages[i]); err != nil {
+			writeError(w, http.StatusBadRequest, mcp.PARSE_ERROR, "Parse error")
+			return
+		}
+		if messages[i].Method == "initialize" {
+			initialize = true
+		}
+	}
+
+	sessionID := r.Header.Get(SessionHeader)
+	switch {
+	case initialize && len(raw) > 1:
+		writeError(w, http.StatusBadRequest, mcp.INVALID_REQUEST, "initialize must not be part of a batch")
+		return
+	case initialize:
+		sessionID = newSessionID()
+	case sessionID == "":
+		writeError(w, http.StatusBadRequest, mcp.INVALID_REQUEST, "missing "+SessionHeader+" header")
+		return
+	default:
+		if _, ok := s.sessions.Load(sessionID); !ok {
+			writeError(w, http.StatusNotFound, mcp.INVALID_REQUEST, "unknown or expired session")
+			return
+		}
+	}
+
+	ctx := s.mcpServer.WithContext(r.Context(), server.NotificationContext{ClientID: sessionID, SessionID: sessionID})
+	stream := &eventStream{w: w}
+	if !initialize && acceptsEventStream(r) {
+		ctx = context.WithValue(ctx, notifierKey{}, Notifier(stream.notify))
+	}
+	var responses []mcp.JSONRPCMessage
+	for i, m := range raw {
+		if messages[i].Method == "" {
+			// A response to a request of the server, which sends none
+			continue
+		}
+		if response := s.mcpServer.HandleMessage(ctx, m); response != nil {
+			responses = append(responses, response)
+		}
+	}
+
+	var payload interface{} = responses
+	if !batch && len(responses) > 0 {
+		payload = responses[0]
+	}
+	if stream.close() {
+		// The status line went out with the first notification
+		if len(responses) > 0 {
+			stream.writeEvent(payload)
+		}
+		return
+	}
+
+	if initialize {
+		s.sessions.Store(sessionID, struct{}{})
+		w.Header().Set(SessionHeader, sessionID)
+	}
+	if len(responses) == 0 {
+		// Only notifications and responses were posted
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payload)
+}
+
+// handleDelete ends the session a client no longer needs
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.Header.Get(SessionHeader)
+	if sessionID == "" {
+		http.Error(w, "Bad request: missing "+SessionHeader+" header", http.StatusBadRequest)
+		return
+	}
+	if _, ok := s.sessions.LoadAndDelete(sessionID); !ok {
+		http.Error(w, "Not found: unknown or expired session", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// splitBatch returns the messages of a POST body, which is either a single
+// JSON-RPC message or a batch of them, and whether it was a batch
+func splitBatch(body []byte) ([]json.RawMessage, bool, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, true, err
+		}
+		if len(batch) == 0 {
+			return nil, true, errors.New("empty batch")
+		}
+		return batch, true, nil
+	}
+	var single json.RawMessage
+	if err := json.Unmarshal(body, &single); err != nil {
+		return nil, false, err
+	}
+	return []json.RawMessage{single}, false, nil
+}
+
+// allowedOrigin reports whether a request may be served. Requests without an
+// Origin header come from non-browser clients. Browser requests must come
+// from the server's own host or from localhost.
+func allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if u.Host == r.Host {
+		return true
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// newSessionID returns a random, unguessable session ID
+func newSessionID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// writeError writes a JSON-RPC error that isn't tied to a request
+func writeError(w http.ResponseWriter, status int, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"jsonrpc": mcp.JSONRPC_VERSION,
+		"id":      nil,
+		"error":   map[string]interface{}{"code": code, "message": message},
+	})
+}
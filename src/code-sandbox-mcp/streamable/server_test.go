@@ -0,0 +1,168 @@
+package streamable
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const initializeRequest = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"v0.0.0"}}}`
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	s := server.NewMCPServer("test", "v0.0.0")
+	s.AddTool(mcp.NewTool("echo", mcp.WithString("text")), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		text, _ := request.Params.Arguments["text"].(string)
+		return mcp.NewToolResultText(text), nil
+	})
+	s.AddTool(mcp.NewTool("count"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if notify, ok := NotifierFromContext(ctx); ok {
+			for i := 1; i <= 2; i++ {
+				notify("notifications/message", map[string]interface{}{"level": "info", "data": i})
+			}
+		}
+		return mcp.NewToolResultText("counted"), nil
+	})
+	ts := httptest.NewServer(NewServer(s, DefaultEndpoint))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func post(t *testing.T, ts *httptest.Server, sessionID string, body string, header ...string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, ts.URL+DefaultEndpoint, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if sessionID != "" {
+		req.Header.Set(SessionHeader, sessionID)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestSession(t *testing.T) {
+	ts := newTestServer(t)
+
+	resp := post(t, ts, "", initializeRequest)
+	sessionID := resp.Header.Get(SessionHeader)
+	if resp.StatusCode != http.StatusOK || sessionID == "" {
+		t.Fatalf("initialize = %d with session %q, want 200 and a session ID", resp.StatusCode, sessionID)
+	}
+
+	if resp := post(t, ts, sessionID, `{"jsonrpc":"2.0","method":"notifications/initialized"}`); resp.StatusCode != http.StatusAccepted {
+		t.Errorf("notification = %d, want 202", resp.StatusCode)
+	}
+
+	resp = post(t, ts, sessionID, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo","arguments":{"text":"hello"}}}`)
+	var result struct {
+		ID     int                `json:"id"`
+		Result mcp.CallToolResult `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("tools/call response: %v", err)
+	}
+	if resp.Header.Get("Content-Type") != "application/json" || result.ID != 2 || !strings.Contains(toText(result.Result), "hello") {
+		t.Errorf("tools/call = %+v, want the echoed text for id 2", result)
+	}
+
+	resp = post(t, ts, sessionID, `[{"jsonrpc":"2.0","id":3,"method":"ping"},{"jsonrpc":"2.0","id":4,"method":"tools/list"}]`)
+	var batch []map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil || len(batch) != 2 {
+		t.Errorf("batch = %v, %v, want two responses", batch, err)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL+DefaultEndpoint, nil)
+	req.Header.Set(SessionHeader, sessionID)
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("DELETE = %v, %v, want 204", resp, err)
+	}
+	if resp := post(t, ts, sessionID, `{"jsonrpc":"2.0","id":5,"method":"ping"}`); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("request after DELETE = %d, want 404", resp.StatusCode)
+	}
+}
+
+func toText(result mcp.CallToolResult) string {
+	data, _ := json.Marshal(result)
+	return string(data)
+}
+
+func TestNotificationStream(t *testing.T) {
+	ts := newTestServer(t)
+	sessionID := post(t, ts, "", initializeRequest).Header.Get(SessionHeader)
+	call := `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"count"}}`
+
+	resp := post(t, ts, sessionID, call)
+	body, _ := io.ReadAll(resp.Body)
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", resp.Header.Get("Content-Type"))
+	}
+	events := strings.Split(strings.TrimSpace(string(body)), "\n\n")
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want two notifications and the response:\n%s", len(events), body)
+	}
+	for i, want := range []string{`"method":"notifications/message"`, `"method":"notifications/message"`, `"id":2`} {
+		if !strings.HasPrefix(events[i], "event: message\ndata: ") || !strings.Contains(events[i], want) {
+			t.Errorf("event %d = %q, want a message with %s", i, events[i], want)
+		}
+	}
+
+	// A client that only accepts JSON gets the response alone
+	resp = post(t, ts, sessionID, call, "Accept", "application/json")
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("JSON-only call = %v (%v), want a JSON response", result, err)
+	}
+}
+
+func TestRejectedRequests(t *testing.T) {
+	ts := newTestServer(t)
+
+	tests := []struct {
+		name      string
+		sessionID string
+		body      string
+		header    []string
+		want      int
+	}{
+		{name: "missing session", body: `{"jsonrpc":"2.0","id":1,"method":"ping"}`, want: http.StatusBadRequest},
+		{name: "unknown session", sessionID: "nope", body: `{"jsonrpc":"2.0","id":1,"method":"ping"}`, want: http.StatusNotFound},
+		{name: "invalid json", body: `{"jsonrpc":`, want: http.StatusBadRequest},
+		{name: "initialize in batch", body: "[" + initializeRequest + `,{"jsonrpc":"2.0","id":2,"method":"ping"}]`, want: http.StatusBadRequest},
+		{name: "foreign origin", body: initializeRequest, header: []string{"Origin", "https://evil.example"}, want: http.StatusForbidden},
+		{name: "localhost origin", body: initializeRequest, header: []string{"Origin", "http://localhost:3000"}, want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := post(t, ts, tt.sessionID, tt.body, tt.header...); resp.StatusCode != tt.want {
+				t.Errorf("POST = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+
+	resp, err := http.Get(ts.URL + DefaultEndpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET = %d, want 405 as no event stream is offered", resp.StatusCode)
+	}
+}
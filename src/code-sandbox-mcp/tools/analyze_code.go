@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// codeAnalysis is what analyze_code reports about a snippet
+type codeAnalysis struct {
+	Language string   `json:"language"`
+	Image    string   `json:"image"`
+	FileName string   `json:"fileName"` // Name the code is saved as in the work directory
+	Packages []string `json:"packages"` // Installed before the code runs, sorted
+	// Entries of "# requirements:" comments, which are among the packages too. Python only.
+	Requirements []string `json:"requirements,omitempty"`
+	Network      string   `json:"network"` // "enabled", "install step only" or "disabled"
+	Command      []string `json:"command"` // Final container command
+}
+
+// AnalyzeCode reports how run_code would run a snippet, the packages it
+// would install, the image and the container command, without creating a container
+func AnalyzeCode(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params runCodeParams
+	if err := decodeParams(request.Params.Arguments, &params); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	language, config, code, opts, err := parseSnippetRequest(params, request.Params.Arguments)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	plan := planSnippet(config.RunCommand, code, language, opts)
+	analysis := codeAnalysis{
+		Language: language.String(),
+		Image:    config.Image,
+		FileName: plan.FileName,
+		Packages: plan.Packages,
+		Command:  plan.Cmd,
+	}
+	if opts.Image != "" {
+		analysis.Image = opts.Image
+	}
+	if analysis.Packages == nil {
+		analysis.Packages = []string{}
+	}
+	if language == languages.Python && !opts.SkipDependencyInstall {
+		analysis.Requirements = languages.ParseRequirementsComments(snippetSources(code, opts.Files, language))
+	}
+	switch _, isolate := networkPlan(opts.AllowNetwork, plan.Cmd); {
+	case opts.AllowNetwork:
+		analysis.Network = "enabled"
+	case isolate:
+		analysis.Network = "install step only"
+	default:
+		analysis.Network = "disabled"
+	}
+
+	result, err := json.MarshalIndent(analysis, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode analysis: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(result)), nil
+}
@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestAnalyzeCode(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments map[string]interface{}
+		want      codeAnalysis
+	}{
+		{
+			name: "python with requirements comment",
+			arguments: map[string]interface{}{
+				"language": "python",
+				"code":     "# requirements: pandas>=2.0\nimport os\nimport requests\nimport pandas as pd\n",
+			},
+			want: codeAnalysis{
+				Language:     "python",
+				Image:        languages.SupportedLanguages[languages.Python].Image,
+				FileName:     "main.py",
+				Packages:     []string{"pandas>=2.0", "requests"},
+				Requirements: []string{"pandas>=2.0"},
+				Network:      "install step only",
+				Command:      []string{"/bin/sh", "-c", guardInstall("uv pip install --system 'pandas>=2.0' requests", false) + " && " + waitForIsolation + " && python3 main.py"},
+			},
+		},
+		{
+			name: "node with network",
+			arguments: map[string]interface{}{
+				"language":     "js",
+				"code":         "const _ = require('lodash');\nconst fs = require('node:fs');\n",
+				"allowNetwork": true,
+			},
+			want: codeAnalysis{
+				Language: "nodejs",
+				Image:    languages.SupportedLanguages[languages.NodeJS].Image,
+				FileName: "main.ts",
+				Packages: []string{"lodash"},
+				Network:  "enabled",
+				Command:  []string{"/bin/sh", "-c", guardInstall("bun add lodash", false) + " && bun run main.ts"},
+			},
+		},
+		{
+			name: "go without third-party imports in a custom image",
+			arguments: map[string]interface{}{
+				"language": "go",
+				"code":     "package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println(1) }\n",
+				"image":    "golang:1.22",
+			},
+			want: codeAnalysis{
+				Language: "go",
+				Image:    "golang:1.22",
+				FileName: "main.go",
+				Packages: []string{},
+				Network:  "disabled",
+				Command:  []string{"go", "run", "main.go"},
+			},
+		},
+		{
+			// Pooled containers skip the image's entrypoint, so the command
+			// goes through opam itself
+			name: "ocaml",
+			arguments: map[string]interface{}{
+				"language": "ocaml",
+				"code":     "print_endline \"hi\"\n",
+			},
+			want: codeAnalysis{
+				Language: "ocaml",
+				Image:    languages.SupportedLanguages[languages.OCaml].Image,
+				FileName: "main.ml",
+				Packages: []string{},
+				Network:  "disabled",
+				Command:  []string{"opam", "exec", "--", "ocaml", "/app/main.ml"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := mcp.CallToolRequest{}
+			request.Params.Arguments = tt.arguments
+			result, err := AnalyzeCode(context.Background(), request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			text := result.Content[0].(mcp.TextContent).Text
+			if result.IsError {
+				t.Fatalf("AnalyzeCode() error: %s", text)
+			}
+			var got codeAnalysis
+			if err := json.Unmarshal([]byte(text), &got); err != nil {
+				t.Fatalf("AnalyzeCode() returned invalid JSON: %v\n%s", err, text)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AnalyzeCode() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzeCodeInvalidArguments(t *testing.T) {
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{"language": "python"}
+	result, err := AnalyzeCode(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !result.IsError || !strings.Contains(text, "exactly one of code or filePath") {
+		t.Errorf("AnalyzeCode() = %q, want an error about the missing code", text)
+	}
+}
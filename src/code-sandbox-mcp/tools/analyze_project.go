@@ -0,0 +1,167 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	deps "github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// extraSourceExtensions are source file extensions counted for a language on
+// top of the one its snippets are written with
+var extraSourceExtensions = map[deps.Language][]string{
+	deps.NodeJS:     {"js", "mjs", "cjs", "jsx", "tsx"},
+	deps.TypeScript: {"tsx", "mts", "cts"},
+	deps.Fortran:    {"f", "f95", "f03"},
+	deps.Bash:       {"bash"},
+	deps.Cpp:        {"cc", "cxx"},
+}
+
+// skippedProjectDirs are directories left out when counting source files
+var skippedProjectDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"_build":       true,
+	"__pycache__":  true,
+}
+
+// AnalyzeProject reports how run_project would interpret a project directory
+// without running anything
+func AnalyzeProject(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	projectDir, _ := request.Params.Arguments["projectDir"].(string)
+	if projectDir == "" {
+		return mcp.NewToolResultError("projectDir must be a non-empty string"), nil
+	}
+	projectDir = filepath.Clean(projectDir)
+	if info, err := os.Stat(projectDir); err != nil || !info.IsDir() {
+		return mcp.NewToolResultError(fmt.Sprintf("project directory does not exist: %s", projectDir)), nil
+	}
+	if err := checkHostPath("projectDir", projectDir); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	candidates := deps.AllLanguages
+	if language, _ := request.Params.Arguments["language"].(string); language != "" {
+		parsed, err := deps.ParseLanguage(language)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		candidates = deps.LanguageList{parsed}
+	}
+
+	counts, err := countSourceFiles(projectDir)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to scan project: %v", err)), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Project: %s", projectDir)
+	found := false
+	for _, language := range candidates {
+		depFile := detectDependencyFile(projectDir, language)
+		sources := sourceFileCount(counts, language)
+		if depFile == "" && sources == 0 && len(candidates) > 1 {
+			continue
+		}
+		found = true
+		b.WriteString(analyzeLanguage(projectDir, language, depFile, sources))
+	}
+	if !found {
+		b.WriteString("\n\nNo supported language detected. Pass language to see how run_project would treat the project anyway")
+	}
+
+	return mcp.NewToolResultText(b.String()), nil
+}
+
+// analyzeLanguage describes how run_project would run the project as language
+func analyzeLanguage(projectDir string, language deps.Language, depFile string, sources int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n\n%s:", language)
+	fmt.Fprintf(&b, "\n  Source files: %d", sources)
+
+	if depFile != "" {
+		fmt.Fprintf(&b, "\n  Dependency file: %s", depFile)
+	} else if len(deps.SupportedLanguages[language].DependencyFiles) == 0 {
+		fmt.Fprintf(&b, "\n  Dependency file: none, %s has no dependency files", language)
+	} else {
+		fmt.Fprintf(&b, "\n  Dependency file: none (looked for %s)", strings.Join(deps.SupportedLanguages[language].DependencyFiles, ", "))
+	}
+
+	if language == deps.Python {
+		requirements, err := extractRequirementsFromPythonFiles(projectDir)
+		switch {
+		case err != nil:
+			fmt.Fprintf(&b, "\n  Requirements from comments: unavailable (%v)", err)
+		case len(requirements) > 0:
+			fmt.Fprintf(&b, "\n  Requirements from comments: %s", strings.Join(requirements, ", "))
+			if depFile == "" || depFile == "requirements.txt" {
+				// run_project merges them into requirements.txt before installing
+				depFile = "requirements.txt"
+			}
+		}
+	}
+
+	entrypoint, source, err := inferEntrypoint(projectDir, language)
+	if err != nil {
+		b.WriteString("\n  Entrypoint: none detected, entrypointCmd is required")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "\n  Entrypoint: %s (detected from %s)", entrypoint, source)
+
+	if version, source := detectRuntimeVersion(projectDir, language); version != "" {
+		fmt.Fprintf(&b, "\n  Runtime version: %s (from %s)", version, source)
+	}
+
+	var packageManager string
+	if language.UsesNPM() {
+		var managerSource string
+		packageManager, managerSource = detectNodePackageManager(projectDir, strings.Fields(entrypoint))
+		fmt.Fprintf(&b, "\n  Package manager: %s (from %s), image %s", packageManager, managerSource, nodeProjectImage(packageManager))
+	}
+
+	command, _ := json.Marshal(BuildContainerCommand(CommandSpec{
+		Language:       language,
+		Cmd:            strings.Fields(entrypoint),
+		WorkDir:        DefaultWorkDir,
+		DependencyFile: depFile,
+		PackageManager: packageManager,
+	}))
+	fmt.Fprintf(&b, "\n  Command: %s", command)
+	return b.String()
+}
+
+// countSourceFiles counts the files in a project by extension, skipping
+// hidden and dependency directories
+func countSourceFiles(projectDir string) (map[string]int, error) {
+	counts := make(map[string]int)
+	err := filepath.WalkDir(projectDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != projectDir && (strings.HasPrefix(d.Name(), ".") || skippedProjectDirs[d.Name()]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ext := strings.TrimPrefix(filepath.Ext(d.Name()), "."); ext != "" {
+			counts[strings.ToLower(ext)]++
+		}
+		return nil
+	})
+	return counts, err
+}
+
+// sourceFileCount returns how many of the counted files belong to language
+func sourceFileCount(counts map[string]int, language deps.Language) int {
+	n := counts[deps.SupportedLanguages[language].FileExtension]
+	for _, ext := range extraSourceExtensions[language] {
+		n += counts[ext]
+	}
+	return n
+}
@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestAnalyzeProject(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.py":                   "# requirements: numpy, pandas\nimport numpy\n",
+		"util.py":                   "",
+		"node_modules/lib/index.js": "",
+		"web/app.js":                "",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{"projectDir": dir}
+	result, err := AnalyzeProject(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+
+	for _, want := range []string{
+		"python:\n  Source files: 2",
+		"Requirements from comments: numpy, pandas",
+		"Entrypoint: python3 main.py (detected from main.py)",
+		"uv pip install --system -r requirements.txt",
+		"nodejs:\n  Source files: 1",
+		"entrypointCmd is required",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("AnalyzeProject() result is missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "go:") {
+		t.Errorf("AnalyzeProject() reported a language with no files:\n%s", text)
+	}
+}
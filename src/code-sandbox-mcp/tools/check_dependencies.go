@@ -0,0 +1,142 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	deps "github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+	"github.com/Automata-Labs-team/code-sandbox-mcp/resources"
+	"github.com/docker/docker/api/types/container"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/moby/moby/client"
+	"github.com/moby/moby/pkg/stdcopy"
+)
+
+// CheckDependencies resolves a Python dependency set without running any user code
+func CheckDependencies(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	requirements, _ := request.Params.Arguments["requirements"].(string)
+	projectDir, _ := request.Params.Arguments["projectDir"].(string)
+	if (requirements == "") == (projectDir == "") {
+		return mcp.NewToolResultError("exactly one of requirements or projectDir must be provided"), nil
+	}
+
+	var dir, target string
+	if requirements != "" {
+		tmpDir, err := os.MkdirTemp("", "docker-sandbox-deps-*")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to create temporary directory: %v", err)), nil
+		}
+		defer os.RemoveAll(tmpDir)
+
+		if err := os.WriteFile(filepath.Join(tmpDir, "requirements.txt"), []byte(requirements), 0644); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to write requirements file: %v", err)), nil
+		}
+		dir, target = tmpDir, "-r requirements.txt"
+	} else {
+		projectDir = filepath.Clean(projectDir)
+		if _, err := os.Stat(projectDir); os.IsNotExist(err) {
+			return mcp.NewToolResultError(fmt.Sprintf("project directory does not exist: %s", projectDir)), nil
+		}
+		if err := checkHostPath("projectDir", projectDir); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		dir = projectDir
+		for _, file := range deps.SupportedLanguages[deps.Python].DependencyFiles {
+			if _, err := os.Stat(filepath.Join(projectDir, file)); err == nil {
+				if file == "requirements.txt" {
+					target = "-r requirements.txt"
+				} else {
+					target = "."
+				}
+				break
+			}
+		}
+		if target == "" {
+			return mcp.NewToolResultError(fmt.Sprintf("no Python dependency file found in %s", projectDir)), nil
+		}
+	}
+
+	output, exitCode, err := checkDependenciesInDocker(ctx, dir, target)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+	}
+
+	if exitCode != 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("Dependencies failed to resolve (exit code %d)\n\nResolver output: %s", exitCode, output)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Dependencies resolved successfully\n\nResolver output: %s", output)), nil
+}
+
+// checkDependenciesInDocker runs a dry-run install of target inside dir and
+// returns the resolver output together with its exit code
+func checkDependenciesInDocker(ctx context.Context, dir string, target string) (string, int64, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create Docker client: %w", err)
+	}
+	defer cli.Close()
+
+	dockerImage := deps.SupportedLanguages[deps.Python].Image
+	if err := pullImage(ctx, cli, dockerImage, false); err != nil {
+		return "", 0, err
+	}
+
+	containerConfig := &container.Config{
+		Image:      dockerImage,
+		Cmd:        []string{"/bin/sh", "-c", "uv pip install --system --dry-run " + target},
+		WorkingDir: "/app",
+		Tty:        false,
+		Env:        registryEnv(deps.Python),
+		Labels:     containerLabels("", deps.Python.String()),
+	}
+	remote := isRemoteDocker(cli)
+	hostConfig := &container.HostConfig{}
+	if !remote {
+		hostConfig.Binds = []string{fmt.Sprintf("%s:/app", dir)}
+	}
+
+	resp, err := cli.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, "")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create container: %w", err)
+	}
+	resources.RegisterContainer(resources.ActiveContainer{ContainerID: resp.ID, Language: deps.Python.String(), Image: dockerImage})
+	defer removeContainer(context.Background(), cli, resp.ID)
+
+	if remote {
+		if err := copyIntoContainer(ctx, cli, resp.ID, dir, "/app"); err != nil {
+			return "", 0, err
+		}
+	}
+
+	if err := cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return "", 0, fmt.Errorf("failed to start container: %w", err)
+	}
+
+	var exitCode int64
+	statusCh, errCh := cli.ContainerWait(ctx, resp.ID, container.WaitConditionNotRunning)
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to wait for container: %w", err)
+		}
+	case status := <-statusCh:
+		exitCode = status.StatusCode
+	}
+
+	out, err := cli.ContainerLogs(ctx, resp.ID, container.LogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get container logs: %w", err)
+	}
+	defer out.Close()
+
+	var b strings.Builder
+	if _, err := stdcopy.StdCopy(&b, &b, out); err != nil {
+		return "", 0, fmt.Errorf("failed to copy container output: %w", err)
+	}
+
+	return resources.MaskSecrets(b.String()), exitCode, nil
+}
@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/resources"
+	"github.com/docker/docker/api/types/container"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/moby/moby/client"
+)
+
+// CleanupContainer releases everything kept for a run: its artifacts, its
+// saved output and, if it still exists, its container
+func CleanupContainer(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	containerID, ok := request.Params.Arguments["containerId"].(string)
+	if !ok || containerID == "" {
+		return mcp.NewToolResultError("containerId must be a non-empty string"), nil
+	}
+	// A pooled container runs the snippets of every client, so only an idle
+	// one may be removed. Runs in it are cleaned up by their own ID.
+	if isPooledContainer(containerID) && !forgetPooledContainer(containerID) {
+		return mcp.NewToolResultError(fmt.Sprintf("container %s is a pooled container running a snippet, pass the ID of a run instead", containerID)), nil
+	}
+
+	artifacts, bytesFreed, err := resources.CleanupContainerArtifacts(containerID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to create Docker client: %v", err)), nil
+	}
+	defer cli.Close()
+
+	// A run still going is stopped, which its waiter should report as a cancellation
+	if run, ok := resources.GetRun(containerID); ok && run.Status == resources.RunStatusRunning {
+		resources.SetRunStatus(containerID, resources.RunStatusCancelled)
+	}
+	containerStatus := "Container removed"
+	if err := cli.ContainerRemove(ctx, containerID, container.RemoveOptions{Force: true}); err != nil {
+		if !client.IsErrNotFound(err) {
+			return mcp.NewToolResultError(fmt.Sprintf("Removed %d artifact(s), freeing %d bytes, but failed to remove container: %v", artifacts, bytesFreed, err)), nil
+		}
+		containerStatus = "Container was already removed"
+	}
+	resources.UnregisterContainer(containerID)
+
+	return mcp.NewToolResultText(fmt.Sprintf("Cleaned up container %s\n\nArtifacts removed: %d\nBytes freed: %d\n%s",
+		containerID, artifacts, bytesFreed, containerStatus)), nil
+}
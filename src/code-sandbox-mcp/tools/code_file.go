@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+)
+
+// maxCodeFileBytes bounds the size of a file run_code reads its code from
+const maxCodeFileBytes = 10 * 1024 * 1024
+
+// readCodeFile reads the code of a run_code call from a host file. It also
+// returns the file's extension when the language accepts it, e.g. "tsx" or
+// "f95", so the code is saved and run as that kind of file. Other extensions
+// give "", keeping the language's default.
+func readCodeFile(filePath string, language languages.Language) (code string, extension string, err error) {
+	if err := checkHostPath("filePath", filePath); err != nil {
+		return "", "", err
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return "", "", fmt.Errorf("file does not exist: %s", filePath)
+	}
+	if !info.Mode().IsRegular() {
+		return "", "", fmt.Errorf("filePath must be a regular file: %s", filePath)
+	}
+	if info.Size() > maxCodeFileBytes {
+		return "", "", fmt.Errorf("file %s is larger than %d MB", filePath, maxCodeFileBytes/(1024*1024))
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filePath), "."))
+	accepted := append([]string{languages.SupportedLanguages[language].FileExtension}, extraSourceExtensions[language]...)
+	for _, candidate := range accepted {
+		if ext == candidate {
+			return string(data), ext, nil
+		}
+	}
+	return string(data), "", nil
+}
+
+// withFileExtension points a language's run command at main.<extension>
+// instead of the main file with the default extension
+func withFileExtension(cmd []string, language languages.Language, extension string) []string {
+	defaultFile := "main." + languages.SupportedLanguages[language].FileExtension
+	rewritten := make([]string, len(cmd))
+	for i, arg := range cmd {
+		rewritten[i] = strings.ReplaceAll(arg, defaultFile, "main."+extension)
+	}
+	return rewritten
+}
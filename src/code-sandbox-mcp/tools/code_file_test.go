@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestReadCodeFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		language    languages.Language
+		wantCode    string
+		wantExt     string
+		errContains string
+	}{
+		{name: "default extension", path: write("script.py", "print(1)"), language: languages.Python, wantCode: "print(1)", wantExt: "py"},
+		{name: "extra extension", path: write("App.TSX", "export {}"), language: languages.TypeScript, wantCode: "export {}", wantExt: "tsx"},
+		{name: "javascript file", path: write("script.js", "console.log(1)"), language: languages.NodeJS, wantCode: "console.log(1)", wantExt: "js"},
+		{name: "typescript file as nodejs", path: write("Page.tsx", "export {}"), language: languages.NodeJS, wantCode: "export {}", wantExt: "tsx"},
+		{name: "fixed form fortran", path: write("solver.f", "      END"), language: languages.Fortran, wantCode: "      END", wantExt: "f"},
+		{name: "unknown extension", path: write("notes.txt", "print(2)"), language: languages.Python, wantCode: "print(2)"},
+		{name: "missing file", path: filepath.Join(dir, "missing.py"), language: languages.Python, errContains: "does not exist"},
+		{name: "directory", path: dir, language: languages.Python, errContains: "regular file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, ext, err := readCodeFile(tt.path, tt.language)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("readCodeFile() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readCodeFile() unexpected error: %v", err)
+			}
+			if code != tt.wantCode || ext != tt.wantExt {
+				t.Errorf("readCodeFile() = %q, %q, want %q, %q", code, ext, tt.wantCode, tt.wantExt)
+			}
+		})
+	}
+}
+
+func TestWithFileExtension(t *testing.T) {
+	got := withFileExtension(languages.SupportedLanguages[languages.Fortran].RunCommand, languages.Fortran, "f95")
+	want := []string{"/bin/sh", "-c", "gfortran -o /tmp/a.out main.f95 && /tmp/a.out"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("withFileExtension() = %q, want %q", got, want)
+	}
+}
+
+func TestRunCodeCodeOrFilePath(t *testing.T) {
+	for name, arguments := range map[string]map[string]interface{}{
+		"both":    {"language": "python", "code": "print(1)", "filePath": "/tmp/main.py"},
+		"neither": {"language": "python"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			request := mcp.CallToolRequest{}
+			request.Params.Arguments = arguments
+			result, err := RunCodeSandbox(context.Background(), request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if text := result.Content[0].(mcp.TextContent).Text; !result.IsError || !strings.Contains(text, "exactly one of code or filePath") {
+				t.Errorf("RunCodeSandbox() = %q, want an error asking for exactly one of code or filePath", text)
+			}
+		})
+	}
+}
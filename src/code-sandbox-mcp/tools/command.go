@@ -0,0 +1,192 @@
+package tools
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	deps "github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+)
+
+// CommandSpec describes what a container has to run: the program itself and
+// whatever dependency install step has to happen before it
+type CommandSpec struct {
+	Language deps.Language
+	Cmd      []string // Run command or project entrypoint
+	WorkDir  string   // In-container directory the code or project is mounted at
+
+	DependencyFile string   // Project dependency file relative to WorkDir, empty when there is none
+	PackageManager string   // Node.js package manager installing project dependencies, Bun when empty
+	Packages       []string // Packages detected in a code snippet, see BuildContainerCommand for when they are installed
+	InstallCommand string   // Custom install command replacing the generated one
+
+	// Wait for the server to take the network away between the install step
+	// and the run, so dependencies can be downloaded but the program is offline
+	IsolateAfterInstall bool
+	// Run the program even if the install step fails
+	ContinueOnInstallError bool
+}
+
+// BuildContainerCommand returns the final container command for a run,
+// chaining the dependency install step, if any, in front of the run command
+func BuildContainerCommand(spec CommandSpec) []string {
+	cmd := spec.Cmd
+	run := strings.Join(cmd, " ")
+
+	if spec.InstallCommand != "" {
+		// A custom install command replaces the generated one. It runs in the
+		// directory holding the dependency file, or the work directory without one.
+		if depDir := path.Dir(spec.DependencyFile); spec.DependencyFile != "" && depDir != "." {
+			return installThenRun(spec, depDir, []string{spec.InstallCommand}, cmd)
+		}
+		return wrapInstall(spec, spec.InstallCommand, run)
+	}
+
+	if spec.Language == deps.Python && len(spec.Packages) > 0 {
+		// Install dependencies first using uv (faster than pip), then run the code
+		return wrapInstall(spec, "uv pip install --system "+packageArgs(spec.Packages), run)
+	}
+
+	if spec.Language == deps.Go && len(spec.Packages) > 0 && spec.DependencyFile == "" {
+		// A snippet importing third-party packages needs a module to resolve them into
+		return wrapInstall(spec, goSnippetInstall, run)
+	}
+
+	if spec.Language.UsesNPM() && len(spec.Packages) > 0 && spec.DependencyFile == "" {
+		// Bun only installs packages on the fly for ES imports, not require(),
+		// and not at all once the run is offline, so a snippet's packages are
+		// added up front
+		return wrapInstall(spec, "bun add "+packageArgs(spec.Packages), run)
+	}
+
+	// cargo fetches a snippet's crates while building it, which an isolated
+	// run can't do, so fetch them up front instead
+	if spec.IsolateAfterInstall && spec.Language == deps.Rust && len(spec.Packages) > 0 {
+		return wrapInstall(spec, "cargo fetch", run)
+	}
+
+	if spec.DependencyFile == "" {
+		// Handle the case where there are no dependency files
+		if spec.Language == deps.Python {
+			// For Python without dependencies, use shell to execute the command
+			return []string{"/bin/sh", "-c", run}
+		}
+		// For other languages, use the command as is
+		return cmd
+	}
+
+	// Dependency files may live in a subdirectory of the project
+	depFile := spec.DependencyFile
+	depDir, depName := path.Dir(depFile), path.Base(depFile)
+	installCommand := deps.SupportedLanguages[spec.Language].InstallCommand
+	switch spec.Language {
+	case deps.Python:
+		if depName == "requirements.txt" {
+			return wrapInstall(spec, pythonInstall("-r "+depFile), run)
+		}
+		return wrapInstall(spec, pythonInstall("./"+depDir), run)
+	case deps.Go:
+		// Fetch the project's modules next to its go.mod, then run the entrypoint
+		return installThenRun(spec, depDir, []string{goModInstall}, cmd)
+	case deps.NodeJS, deps.TypeScript:
+		// Install with the project's own package manager, then run the entrypoint as given
+		install, ok := nodeInstallCommands[spec.PackageManager]
+		if !ok {
+			install = nodeInstallCommands[nodeBun]
+		}
+		return installThenRun(spec, depDir, install, cmd)
+	case deps.Fortran, deps.C, deps.Cpp:
+		if depName == "CMakeLists.txt" {
+			// CMake needs an image that provides it, the default gcc image only has make
+			installCommand = []string{"cmake", "-S", ".", "-B", "build", "&&", "cmake", "--build", "build"}
+		}
+		return installThenRun(spec, depDir, installCommand, cmd)
+	default:
+		// Fetch packages or build in the directory holding the manifest, then run from the project root
+		return installThenRun(spec, depDir, installCommand, cmd)
+	}
+}
+
+// plainShellWord matches package names that need no quoting in a shell command
+var plainShellWord = regexp.MustCompile(`^[A-Za-z0-9._@/:=+-]+$`)
+
+// packageArgs joins packages into shell arguments, quoting those with
+// characters the shell would interpret, such as the ">" of "pandas>=2.0"
+func packageArgs(packages []string) string {
+	args := make([]string, len(packages))
+	for i, pkg := range packages {
+		if plainShellWord.MatchString(pkg) {
+			args[i] = pkg
+		} else {
+			args[i] = shellQuote(pkg)
+		}
+	}
+	return strings.Join(args, " ")
+}
+
+// pythonInstall returns a shell command installing args with uv, or with pip
+// in images that don't ship uv, such as custom or official Python images
+func pythonInstall(args string) string {
+	return fmt.Sprintf("if command -v uv >/dev/null 2>&1; then uv pip install --system %s; else python3 -m pip install %s; fi", args, args)
+}
+
+// goModInstall fetches the modules of a Go project. go mod download can't
+// record checksums, so a project without a go.sum is tidied instead, which
+// writes one.
+const goModInstall = "if [ -f go.sum ]; then go mod download; else go mod tidy; fi"
+
+// goSnippetInstall creates a module for a Go snippet, unless its files include
+// a go.mod, and resolves the snippet's imports into it. go mod init writes the
+// go directive of the image's own toolchain.
+const goSnippetInstall = "if [ ! -f go.mod ]; then go mod init sandbox; fi && go mod tidy"
+
+// installThenRun builds a shell command that runs the install step in depDir
+// and then the entrypoint from the project root at the spec's work directory
+func installThenRun(spec CommandSpec, depDir string, installCmd []string, cmd []string) []string {
+	install := fmt.Sprintf("cd %s && %s", depDir, strings.Join(installCmd, " "))
+	run := fmt.Sprintf("cd %s && %s", spec.WorkDir, strings.Join(cmd, " "))
+	return wrapInstall(spec, install, run)
+}
+
+// Placeholders of a command template
+const (
+	installPlaceholder = "{install}"
+	runPlaceholder     = "{run}"
+)
+
+// DefaultCommandTemplate runs the install step and then, if it succeeded, the program
+const DefaultCommandTemplate = installPlaceholder + " && " + runPlaceholder
+
+// commandTemplates holds the operator's command templates by language
+var commandTemplates = map[deps.Language]string{}
+
+// SetCommandTemplate sets the shell template used to chain a language's
+// install step and run command, e.g. "set -eu; {install}; {run}". The template
+// must contain {install} and {run} exactly once each.
+func SetCommandTemplate(language deps.Language, template string) error {
+	for _, placeholder := range []string{installPlaceholder, runPlaceholder} {
+		if n := strings.Count(template, placeholder); n != 1 {
+			return fmt.Errorf("command template for %s must contain %s exactly once, found %d", language, placeholder, n)
+		}
+	}
+	commandTemplates[language] = template
+	return nil
+}
+
+// wrapInstall renders the language's command template into a shell command
+// running install and then run. A failing install step is reported in the
+// output, see guardInstall.
+func wrapInstall(spec CommandSpec, install string, run string) []string {
+	install = guardInstall(install, spec.ContinueOnInstallError)
+	template, ok := commandTemplates[spec.Language]
+	if !ok {
+		template = DefaultCommandTemplate
+	}
+	if spec.IsolateAfterInstall {
+		run = waitForIsolation + " && " + run
+	}
+	// Replace both at once so an install step containing "{run}" isn't expanded
+	r := strings.NewReplacer(installPlaceholder, install, runPlaceholder, run)
+	return []string{"/bin/sh", "-c", r.Replace(template)}
+}
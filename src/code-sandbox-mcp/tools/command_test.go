@@ -0,0 +1,301 @@
+package tools
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+)
+
+func TestBuildContainerCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		spec CommandSpec
+		want []string
+	}{
+		{
+			name: "python snippet without packages",
+			spec: CommandSpec{Language: languages.Python, Cmd: []string{"python3", "main.py"}, WorkDir: "/app"},
+			want: []string{"/bin/sh", "-c", "python3 main.py"},
+		},
+		{
+			name: "python snippet with packages",
+			spec: CommandSpec{Language: languages.Python, Cmd: []string{"python3", "main.py"}, WorkDir: "/app", Packages: []string{"requests", "numpy"}},
+			want: []string{"/bin/sh", "-c", guardInstall("uv pip install --system requests numpy", false) + " && python3 main.py"},
+		},
+		{
+			name: "python snippet with pinned packages",
+			spec: CommandSpec{Language: languages.Python, Cmd: []string{"python3", "main.py"}, WorkDir: "/app", Packages: []string{"pandas>=2.0", "numpy==1.26.4"}},
+			want: []string{"/bin/sh", "-c", guardInstall("uv pip install --system 'pandas>=2.0' numpy==1.26.4", false) + " && python3 main.py"},
+		},
+		{
+			name: "go snippet with packages gets a module",
+			spec: CommandSpec{Language: languages.Go, Cmd: []string{"go", "run", "main.go"}, WorkDir: "/app", Packages: []string{"github.com/google/uuid"}},
+			want: []string{"/bin/sh", "-c", guardInstall("if [ ! -f go.mod ]; then go mod init sandbox; fi && go mod tidy", false) + " && go run main.go"},
+		},
+		{
+			name: "go snippet without packages runs as is",
+			spec: CommandSpec{Language: languages.Go, Cmd: []string{"go", "run", "main.go"}, WorkDir: "/app"},
+			want: []string{"go", "run", "main.go"},
+		},
+		{
+			name: "node snippet with packages adds them first",
+			spec: CommandSpec{Language: languages.NodeJS, Cmd: []string{"bun", "run", "main.js"}, WorkDir: "/app", Packages: []string{"lodash", "@scope/pkg"}},
+			want: []string{"/bin/sh", "-c", guardInstall("bun add lodash @scope/pkg", false) + " && bun run main.js"},
+		},
+		{
+			name: "node snippet without packages runs as is",
+			spec: CommandSpec{Language: languages.NodeJS, Cmd: []string{"bun", "run", "main.js"}, WorkDir: "/app"},
+			want: []string{"bun", "run", "main.js"},
+		},
+		{
+			name: "go project fetches modules before the run",
+			spec: CommandSpec{Language: languages.Go, Cmd: []string{"go", "run", "."}, WorkDir: "/app", DependencyFile: "go.mod"},
+			want: []string{"/bin/sh", "-c", guardInstall("cd . && if [ -f go.sum ]; then go mod download; else go mod tidy; fi", false) + " && cd /app && go run ."},
+		},
+		{
+			name: "python project with requirements in subdirectory",
+			spec: CommandSpec{Language: languages.Python, Cmd: []string{"python", "app.py"}, WorkDir: "/app", DependencyFile: "backend/requirements.txt"},
+			want: []string{"/bin/sh", "-c", guardInstall("if command -v uv >/dev/null 2>&1; then uv pip install --system -r backend/requirements.txt; else python3 -m pip install -r backend/requirements.txt; fi", false) + " && python app.py"},
+		},
+		{
+			name: "python project with pyproject",
+			spec: CommandSpec{Language: languages.Python, Cmd: []string{"python", "app.py"}, WorkDir: "/app", DependencyFile: "pyproject.toml"},
+			want: []string{"/bin/sh", "-c", guardInstall("if command -v uv >/dev/null 2>&1; then uv pip install --system ./.; else python3 -m pip install ./.; fi", false) + " && python app.py"},
+		},
+		{
+			name: "bun project runs entrypoint verbatim",
+			spec: CommandSpec{Language: languages.NodeJS, Cmd: []string{"bun", "run", "start"}, WorkDir: "/app", DependencyFile: "package.json"},
+			want: []string{"/bin/sh", "-c", guardInstall("cd . && bun install", false) + " && cd /app && bun run start"},
+		},
+		{
+			name: "npm project",
+			spec: CommandSpec{Language: languages.NodeJS, Cmd: []string{"npm", "run", "build"}, WorkDir: "/app", DependencyFile: "package.json", PackageManager: "npm"},
+			want: []string{"/bin/sh", "-c", guardInstall("cd . && npm install", false) + " && cd /app && npm run build"},
+		},
+		{
+			name: "yarn project in subdirectory",
+			spec: CommandSpec{Language: languages.NodeJS, Cmd: []string{"yarn", "start"}, WorkDir: "/app", DependencyFile: "web/package.json", PackageManager: "yarn"},
+			want: []string{"/bin/sh", "-c", guardInstall("cd web && yarn install", false) + " && cd /app && yarn start"},
+		},
+		{
+			name: "typescript project",
+			spec: CommandSpec{Language: languages.TypeScript, Cmd: []string{"npm", "start"}, WorkDir: "/app", DependencyFile: "package.json", PackageManager: "npm"},
+			want: []string{"/bin/sh", "-c", guardInstall("cd . && npm install", false) + " && cd /app && npm start"},
+		},
+		{
+			name: "pnpm project",
+			spec: CommandSpec{Language: languages.NodeJS, Cmd: []string{"pnpm", "test"}, WorkDir: "/app", DependencyFile: "package.json", PackageManager: "pnpm"},
+			want: []string{"/bin/sh", "-c", guardInstall("cd . && corepack enable pnpm && pnpm install", false) + " && cd /app && pnpm test"},
+		},
+		{
+			name: "dart project in subdirectory",
+			spec: CommandSpec{Language: languages.Dart, Cmd: []string{"dart", "run", "bin/main.dart"}, WorkDir: "/src", DependencyFile: "app/pubspec.yaml"},
+			want: []string{"/bin/sh", "-c", guardInstall("cd app && dart pub get", false) + " && cd /src && dart run bin/main.dart"},
+		},
+		{
+			name: "fortran snippet",
+			spec: CommandSpec{Language: languages.Fortran, Cmd: languages.SupportedLanguages[languages.Fortran].RunCommand, WorkDir: "/app"},
+			want: []string{"/bin/sh", "-c", "gfortran -o /tmp/a.out main.f90 && /tmp/a.out"},
+		},
+		{
+			name: "fortran project with makefile",
+			spec: CommandSpec{Language: languages.Fortran, Cmd: []string{"./solver"}, WorkDir: "/app", DependencyFile: "Makefile"},
+			want: []string{"/bin/sh", "-c", guardInstall("cd . && make", false) + " && cd /app && ./solver"},
+		},
+		{
+			name: "fortran project with cmake",
+			spec: CommandSpec{Language: languages.Fortran, Cmd: []string{"./build/solver"}, WorkDir: "/app", DependencyFile: "CMakeLists.txt"},
+			want: []string{"/bin/sh", "-c", guardInstall("cd . && cmake -S . -B build && cmake --build build", false) + " && cd /app && ./build/solver"},
+		},
+		{
+			name: "c snippet compiles then runs",
+			spec: CommandSpec{Language: languages.C, Cmd: languages.SupportedLanguages[languages.C].RunCommand, WorkDir: "/app"},
+			want: []string{"/bin/sh", "-c", "gcc -o /tmp/a.out main.c -lm && /tmp/a.out"},
+		},
+		{
+			name: "c++ cmake project",
+			spec: CommandSpec{Language: languages.Cpp, Cmd: []string{"./build/app"}, WorkDir: "/app", DependencyFile: "CMakeLists.txt"},
+			want: []string{"/bin/sh", "-c", guardInstall("cd . && cmake -S . -B build && cmake --build build", false) + " && cd /app && ./build/app"},
+		},
+		{
+			name: "java snippet",
+			spec: CommandSpec{Language: languages.Java, Cmd: javaRun("Hello"), WorkDir: "/app"},
+			want: []string{"/bin/sh", "-c", "javac -d /tmp/classes Hello.java && java -cp /tmp/classes Hello"},
+		},
+		{
+			name: "java maven project",
+			spec: CommandSpec{Language: languages.Java, Cmd: []string{"java", "-jar", "target/app.jar"}, WorkDir: "/app", DependencyFile: "pom.xml"},
+			want: []string{"/bin/sh", "-c", guardInstall("cd . && mvn -q package", false) + " && cd /app && java -jar target/app.jar"},
+		},
+		{
+			name: "bash script has no install step",
+			spec: CommandSpec{Language: languages.Bash, Cmd: []string{"bash", "main.sh"}, WorkDir: "/app", IsolateAfterInstall: true},
+			want: []string{"bash", "main.sh"},
+		},
+		{
+			name: "bash script with custom install command",
+			spec: CommandSpec{Language: languages.Bash, Cmd: []string{"bash", "main.sh"}, WorkDir: "/app", InstallCommand: "apk add --no-cache jq"},
+			want: []string{"/bin/sh", "-c", guardInstall("apk add --no-cache jq", false) + " && bash main.sh"},
+		},
+		{
+			name: "failed install can be ignored",
+			spec: CommandSpec{Language: languages.Python, Cmd: []string{"python3", "main.py"}, WorkDir: "/app", Packages: []string{"requests"}, ContinueOnInstallError: true},
+			want: []string{"/bin/sh", "-c", guardInstall("uv pip install --system requests", true) + " && python3 main.py"},
+		},
+		{
+			name: "custom install command replaces generated one",
+			spec: CommandSpec{Language: languages.Python, Cmd: []string{"python3", "main.py"}, WorkDir: "/app", Packages: []string{"requests"}, InstallCommand: "uv pip install --system --no-deps requests"},
+			want: []string{"/bin/sh", "-c", guardInstall("uv pip install --system --no-deps requests", false) + " && python3 main.py"},
+		},
+		{
+			name: "custom install command runs next to dependency file",
+			spec: CommandSpec{Language: languages.Go, Cmd: []string{"go", "run", "./cmd/server"}, WorkDir: "/app", DependencyFile: "server/go.mod", InstallCommand: "go mod download"},
+			want: []string{"/bin/sh", "-c", guardInstall("cd server && go mod download", false) + " && cd /app && go run ./cmd/server"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BuildContainerCommand(tt.spec); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildContainerCommand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandTemplate(t *testing.T) {
+	t.Cleanup(func() { delete(commandTemplates, languages.Python) })
+
+	if err := SetCommandTemplate(languages.Python, "set -e; {install}"); err == nil {
+		t.Errorf("SetCommandTemplate() without {run} succeeded, want error")
+	}
+	if err := SetCommandTemplate(languages.Python, "{install} && {run} && {run}"); err == nil {
+		t.Errorf("SetCommandTemplate() with {run} twice succeeded, want error")
+	}
+	if err := SetCommandTemplate(languages.Python, "set -eu; echo installing; {install}; {run}"); err != nil {
+		t.Fatalf("SetCommandTemplate() unexpected error: %v", err)
+	}
+
+	got := BuildContainerCommand(CommandSpec{Language: languages.Python, Cmd: []string{"python3", "main.py"}, WorkDir: "/app", Packages: []string{"requests"}})
+	want := []string{"/bin/sh", "-c", "set -eu; echo installing; " + guardInstall("uv pip install --system requests", false) + "; python3 main.py"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildContainerCommand() = %q, want %q", got, want)
+	}
+
+	// Other languages keep the default template
+	got = BuildContainerCommand(CommandSpec{Language: languages.Dart, Cmd: []string{"dart", "run"}, WorkDir: "/app", DependencyFile: "pubspec.yaml"})
+	want = []string{"/bin/sh", "-c", guardInstall("cd . && dart pub get", false) + " && cd /app && dart run"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildContainerCommand() = %q, want %q", got, want)
+	}
+}
+
+func TestPythonInstallFallback(t *testing.T) {
+	// Fake python3 and uv that report how they were called
+	bin := t.TempDir()
+	fake := "#!/bin/sh\necho \"${0##*/} $*\"\n"
+	for _, name := range []string{"python3", "uv"} {
+		if err := os.WriteFile(filepath.Join(bin, name), []byte(fake), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		hasUV bool
+		want  string
+	}{
+		{name: "image with uv", hasUV: true, want: "uv pip install --system -r requirements.txt"},
+		{name: "image without uv", want: "python3 -m pip install -r requirements.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.hasUV {
+				os.Remove(filepath.Join(bin, "uv"))
+			}
+			cmd := exec.Command("/bin/sh", "-c", pythonInstall("-r requirements.txt"))
+			cmd.Env = []string{"PATH=" + bin}
+			out, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("install command failed: %v\n%s", err, out)
+			}
+			if got := strings.TrimSpace(string(out)); got != tt.want {
+				t.Errorf("install ran %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildContainerCommandIsolated(t *testing.T) {
+	tests := []struct {
+		name        string
+		spec        CommandSpec
+		wantInstall string
+		wantRun     string
+	}{
+		{
+			name:        "python snippet with packages",
+			spec:        CommandSpec{Language: languages.Python, Cmd: []string{"python3", "main.py"}, WorkDir: "/app", Packages: []string{"requests"}},
+			wantInstall: "uv pip install --system requests",
+			wantRun:     "python3 main.py",
+		},
+		{
+			name:        "node snippet fetches packages before the run",
+			spec:        CommandSpec{Language: languages.NodeJS, Cmd: []string{"bun", "run", "main.js"}, WorkDir: "/app", Packages: []string{"lodash"}},
+			wantInstall: "bun add lodash",
+			wantRun:     "bun run main.js",
+		},
+		{
+			name:        "typescript snippet fetches packages before the run",
+			spec:        CommandSpec{Language: languages.TypeScript, Cmd: []string{"bun", "run", "main.ts"}, WorkDir: "/app", Packages: []string{"zod"}},
+			wantInstall: "bun add zod",
+			wantRun:     "bun run main.ts",
+		},
+		{
+			name:        "rust snippet fetches crates before the run",
+			spec:        CommandSpec{Language: languages.Rust, Cmd: rustCargoRun, WorkDir: "/app", Packages: []string{"serde"}},
+			wantInstall: "cargo fetch",
+		},
+		{
+			name:        "go project fetches modules before isolation",
+			spec:        CommandSpec{Language: languages.Go, Cmd: []string{"go", "run", "."}, WorkDir: "/app", DependencyFile: "go.mod"},
+			wantInstall: "cd . && if [ -f go.sum ]; then go mod download; else go mod tidy; fi",
+			wantRun:     "cd /app && go run .",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.spec.IsolateAfterInstall = true
+			got := BuildContainerCommand(tt.spec)
+			if !hasIsolationStep(got) {
+				t.Fatalf("BuildContainerCommand() = %q, want a wait for isolation", got)
+			}
+			script := got[len(got)-1]
+			install := strings.Index(script, tt.wantInstall)
+			wait := strings.Index(script, installedMarker)
+			if install < 0 || wait < install {
+				t.Errorf("BuildContainerCommand() = %q, want %q before the wait for isolation", script, tt.wantInstall)
+			}
+			if tt.wantRun != "" && !strings.HasSuffix(script, "&& "+tt.wantRun) {
+				t.Errorf("BuildContainerCommand() = %q, want it to end with %q", script, tt.wantRun)
+			}
+		})
+	}
+
+	// Without an install step there is nothing to wait for, so the run starts offline
+	spec := CommandSpec{Language: languages.Python, Cmd: []string{"python3", "main.py"}, WorkDir: "/app", IsolateAfterInstall: true}
+	cmd := BuildContainerCommand(spec)
+	if mode, isolate := networkPlan(false, cmd); mode != "none" || isolate {
+		t.Errorf("networkPlan() = %q, %t, want none without isolation", mode, isolate)
+	}
+	if mode, isolate := networkPlan(true, cmd); mode != "" || isolate {
+		t.Errorf("networkPlan() with allowNetwork = %q, %t, want default networking", mode, isolate)
+	}
+}
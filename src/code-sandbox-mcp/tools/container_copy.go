@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/moby/moby/client"
+)
+
+// copyOutOfContainer copies the regular files below containerPath out of the
+// container into destDir over the Docker API. Files are stored under their
+// base name, or under their path relative to containerPath with keepDirs; a
+// file whose name is already in destDir is skipped, as is a file over
+// fileLimit bytes and anything past limit bytes in total. It returns the
+// skipped files with the reason, and separately the names of those skipped
+// for size. A containerPath that doesn't exist copies nothing.
+func copyOutOfContainer(ctx context.Context, cli *client.Client, containerID string, containerPath string, destDir string, keepDirs bool, limit int64, fileLimit int64) ([]string, []string, error) {
+	rc, _, err := cli.CopyFromContainer(ctx, containerID, containerPath)
+	if client.IsErrNotFound(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to copy %s from container: %w", containerPath, err)
+	}
+	defer rc.Close()
+
+	return extractArtifactTar(rc, containerPath, destDir, keepDirs, limit, fileLimit)
+}
+
+// extractArtifactTar writes the regular files of a tar stream copied from
+// containerPath into destDir, flattened to their base names unless keepDirs
+// is set
+func extractArtifactTar(r io.Reader, containerPath string, destDir string, keepDirs bool, limit int64, fileLimit int64) ([]string, []string, error) {
+	var skipped, oversized []string
+	var total int64
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return skipped, oversized, nil
+		}
+		if err != nil {
+			return skipped, oversized, fmt.Errorf("failed to read %s from container: %w", containerPath, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := path.Base(hdr.Name)
+		if keepDirs {
+			name = tarEntryPath(hdr.Name)
+		}
+		if name == "" {
+			continue
+		}
+		if hdr.Size > fileLimit {
+			skipped = append(skipped, fmt.Sprintf("%s (from %s): larger than the artifact size limit of %d bytes", name, containerPath, fileLimit))
+			oversized = append(oversized, name)
+			continue
+		}
+		if total+hdr.Size > limit {
+			skipped = append(skipped, fmt.Sprintf("%s (from %s): total artifact size limit of %d bytes exceeded", name, containerPath, limit))
+			oversized = append(oversized, name)
+			continue
+		}
+
+		target := filepath.Join(destDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return skipped, oversized, fmt.Errorf("failed to write artifact %s: %w", name, err)
+		}
+		f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, os.ErrExist) {
+			skipped = append(skipped, fmt.Sprintf("%s (from %s): an artifact with the same name was already collected", name, containerPath))
+			continue
+		}
+		if err != nil {
+			return skipped, oversized, fmt.Errorf("failed to write artifact %s: %w", name, err)
+		}
+		_, err = io.Copy(f, tr)
+		f.Close()
+		if err != nil {
+			return skipped, oversized, fmt.Errorf("failed to write artifact %s: %w", name, err)
+		}
+		total += hdr.Size
+	}
+}
+
+// tarEntryPath returns the path of a tar entry copied from a directory
+// relative to that directory. The archive's entries start with the
+// directory's own name, which is dropped. Entries that would land outside
+// the directory return an empty path.
+func tarEntryPath(name string) string {
+	_, rel, ok := strings.Cut(path.Clean(name), "/")
+	if !ok {
+		// A single file was copied rather than a directory
+		return path.Base(name)
+	}
+	if rel == ".." || strings.HasPrefix(rel, "../") || path.IsAbs(rel) {
+		return ""
+	}
+	return rel
+}
@@ -0,0 +1,141 @@
+package tools
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtractArtifactTar(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	entries := []struct {
+		name string
+		body string
+		dir  bool
+	}{
+		{name: "output/", dir: true},
+		{name: "output/report.csv", body: "a,b"},
+		{name: "output/plot.png", body: "new plot"},
+		{name: "output/charts/", dir: true},
+		{name: "output/charts/big.bin", body: strings.Repeat("x", 100)},
+	}
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+		if e.dir {
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "plot.png"), []byte("original plot"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	skipped, oversized, err := extractArtifactTar(&buf, "/output", dir, false, 50, 1024)
+	if err != nil {
+		t.Fatalf("extractArtifactTar() unexpected error: %v", err)
+	}
+
+	if content, err := os.ReadFile(filepath.Join(dir, "report.csv")); err != nil || string(content) != "a,b" {
+		t.Errorf("report.csv = %q (%v), want %q", content, err, "a,b")
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "plot.png")); string(content) != "original plot" {
+		t.Errorf("plot.png = %q, want the existing file kept", content)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "big.bin")); err == nil {
+		t.Errorf("big.bin was extracted past the size limit")
+	}
+	if len(skipped) != 2 || !strings.Contains(skipped[0], "same name") || !strings.Contains(skipped[1], "size limit") {
+		t.Errorf("extractArtifactTar() skipped = %v, want plot.png and big.bin", skipped)
+	}
+	if len(oversized) != 1 || oversized[0] != "big.bin" {
+		t.Errorf("extractArtifactTar() oversized = %v, want big.bin", oversized)
+	}
+}
+
+func TestExtractArtifactTarKeepDirs(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, body := range map[string]string{"artifacts/top.txt": "top", "artifacts/plots/a.png": "png", "artifacts/../escape.txt": "x"} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if _, _, err := extractArtifactTar(&buf, "/artifacts", dir, true, 1024, 1024); err != nil {
+		t.Fatalf("extractArtifactTar() unexpected error: %v", err)
+	}
+	for name, want := range map[string]string{"top.txt": "top", "plots/a.png": "png"} {
+		if content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name))); err != nil || string(content) != want {
+			t.Errorf("%s = %q (%v), want %q", name, content, err, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "escape.txt")); err == nil {
+		t.Errorf("escape.txt was extracted outside the destination")
+	}
+}
+
+func TestExtractArtifactTarFileLimit(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range []struct{ name, body string }{{"out/big.bin", strings.Repeat("x", 100)}, {"out/small.txt", "ok"}} {
+		if err := tw.WriteHeader(&tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	skipped, oversized, err := extractArtifactTar(&buf, "/out", dir, false, 1024, 10)
+	if err != nil {
+		t.Fatalf("extractArtifactTar() unexpected error: %v", err)
+	}
+	if len(skipped) != 1 || !strings.Contains(skipped[0], "larger than the artifact size limit of 10 bytes") {
+		t.Errorf("extractArtifactTar() skipped = %v, want big.bin over the file limit", skipped)
+	}
+	if len(oversized) != 1 || oversized[0] != "big.bin" {
+		t.Errorf("extractArtifactTar() oversized = %v, want big.bin", oversized)
+	}
+	// Files after the oversized one are still extracted
+	if content, err := os.ReadFile(filepath.Join(dir, "small.txt")); err != nil || string(content) != "ok" {
+		t.Errorf("small.txt = %q (%v), want %q", content, err, "ok")
+	}
+}
+
+func TestParseArtifactPaths(t *testing.T) {
+	got, err := parseArtifactPaths("/output, /app/dist/ ,/artifacts")
+	if err != nil {
+		t.Fatalf("parseArtifactPaths() unexpected error: %v", err)
+	}
+	if strings.Join(got, ",") != "/output,/app/dist" {
+		t.Errorf("parseArtifactPaths() = %v, want [/output /app/dist]", got)
+	}
+	if _, err := parseArtifactPaths("dist"); err == nil || !strings.Contains(err.Error(), "absolute") {
+		t.Errorf("parseArtifactPaths(relative) error = %v, want absolute path error", err)
+	}
+}
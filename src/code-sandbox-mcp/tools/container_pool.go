@@ -0,0 +1,390 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+	"github.com/Automata-Labs-team/code-sandbox-mcp/resources"
+	"github.com/docker/docker/api/types/container"
+	"github.com/moby/moby/client"
+)
+
+// poolLabel marks containers started for the container pool. They hold no
+// run of their own, so they are always removed at startup.
+const poolLabel = "code-sandbox-mcp.pool"
+
+// DefaultContainerPoolIdle is how long an idle pooled container is kept
+// before it is removed
+const DefaultContainerPoolIdle = 5 * time.Minute
+
+// containerPoolEvictInterval is how often idle pooled containers are checked
+const containerPoolEvictInterval = time.Minute
+
+// execKilledExitCode is reported for a pooled run stopped at its timeout,
+// the exit code of a process killed with SIGKILL
+const execKilledExitCode = 137
+
+// pooledContainer is a running container that executes run_code snippets one
+// after the other. Its work directory and /artifacts are bind mounts of Dir
+// and Dir/artifacts, which are emptied between runs. The rest of its
+// filesystem is read-only apart from /tmp and /dev/shm, tmpfs mounts that are
+// emptied as well.
+type pooledContainer struct {
+	ID        string
+	Dir       string
+	Key       string
+	IdleSince time.Time
+}
+
+var (
+	poolMu   sync.Mutex
+	poolSize int // Maximum number of idle containers kept, 0 disables the pool
+	poolIdle = DefaultContainerPoolIdle
+	// idleContainers holds the idle pooled containers by poolKey
+	idleContainers = make(map[string][]*pooledContainer)
+	poolEvicting   bool
+)
+
+// SetContainerPool keeps up to size idle containers for run_code snippets,
+// removing those idle for longer than idle. A size of 0 disables the pool.
+func SetContainerPool(size int, idle time.Duration) {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	poolSize, poolIdle = size, idle
+	if size > 0 && !poolEvicting {
+		poolEvicting = true
+		go evictIdleContainers()
+	}
+}
+
+// poolable reports whether a run_code run can use a pooled container. Only
+// runs that install nothing, stay offline and need no per-run mounts, input,
+// secrets or session qualify, so a run finds nothing of the previous one but the
+// emptied directories.
+func poolable(opts runOptions, packages []string, remote bool) bool {
+	poolMu.Lock()
+	enabled := poolSize > 0
+	poolMu.Unlock()
+	return enabled && !remote &&
+		len(packages) == 0 && opts.InstallCommand == "" &&
+		!opts.AllowNetwork && !opts.AllowDockerAccess &&
+		opts.Stdin == "" && opts.SessionID == "" && len(opts.Secrets) == 0 &&
+		len(opts.ReadOnlyFiles) == 0 && len(opts.ArtifactPaths) == 0 &&
+		os.Getenv("ARTIFACTS_DIR") == ""
+}
+
+// poolKey identifies the pooled containers a run can use: those of the same
+// image, work directory, limits and security options
+func poolKey(image string, language languages.Language, opts runOptions) string {
+	limits := opts.resources(language)
+	return fmt.Sprintf("%s|%s|%s|%d|%d|%s", image, language, opts.WorkDir,
+		limits.Memory, limits.NanoCPUs, strings.Join(opts.securityOpts(), ","))
+}
+
+// takeIdleContainer removes an idle container for key from the pool, or
+// returns nil when there is none
+func takeIdleContainer(key string) *pooledContainer {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	idle := idleContainers[key]
+	if len(idle) == 0 {
+		return nil
+	}
+	pc := idle[len(idle)-1]
+	idleContainers[key] = idle[:len(idle)-1]
+	return pc
+}
+
+// putIdleContainer adds a container to the pool and reports whether there
+// was room for it
+func putIdleContainer(pc *pooledContainer, now time.Time) bool {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	idle := 0
+	for _, containers := range idleContainers {
+		idle += len(containers)
+	}
+	if idle >= poolSize {
+		return false
+	}
+	pc.IdleSince = now
+	idleContainers[pc.Key] = append(idleContainers[pc.Key], pc)
+	return true
+}
+
+// takeExpiredContainers removes the containers idle for longer than the pool's
+// idle timeout from the pool and returns them
+func takeExpiredContainers(now time.Time) []*pooledContainer {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	var expired []*pooledContainer
+	for key, containers := range idleContainers {
+		var kept []*pooledContainer
+		for _, pc := range containers {
+			if now.Sub(pc.IdleSince) > poolIdle {
+				expired = append(expired, pc)
+			} else {
+				kept = append(kept, pc)
+			}
+		}
+		if len(kept) == 0 {
+			delete(idleContainers, key)
+		} else {
+			idleContainers[key] = kept
+		}
+	}
+	return expired
+}
+
+// takeAllIdleContainers empties the pool and returns its containers
+func takeAllIdleContainers() []*pooledContainer {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	var all []*pooledContainer
+	for key, containers := range idleContainers {
+		all = append(all, containers...)
+		delete(idleContainers, key)
+	}
+	return all
+}
+
+// evictIdleContainers removes expired idle containers for as long as the server runs
+func evictIdleContainers() {
+	for range time.Tick(containerPoolEvictInterval) {
+		expired := takeExpiredContainers(time.Now())
+		if len(expired) == 0 {
+			continue
+		}
+		cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+		if err != nil {
+			continue
+		}
+		for _, pc := range expired {
+			discardContainer(context.Background(), cli, pc)
+		}
+		cli.Close()
+	}
+}
+
+// checkoutContainer takes an idle container for the run out of the pool, or
+// starts a new one
+func checkoutContainer(ctx context.Context, cli *client.Client, image string, language languages.Language, opts runOptions) (*pooledContainer, error) {
+	key := poolKey(image, language, opts)
+	if pc := takeIdleContainer(key); pc != nil {
+		return pc, nil
+	}
+
+	dir, err := os.MkdirTemp("", "docker-sandbox-pool-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pooled container directory: %w", err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "artifacts"), 0755); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to create artifacts directory: %w", err)
+	}
+
+	labels := containerLabels("", language.String())
+	labels[poolLabel] = "true"
+	created, err := cli.ContainerCreate(ctx, &container.Config{
+		Image:      image,
+		Entrypoint: []string{"sleep", "infinity"},
+		WorkingDir: opts.WorkDir,
+		User:       languages.SupportedLanguages[language].User,
+		Labels:     labels,
+	}, &container.HostConfig{
+		Binds: []string{
+			fmt.Sprintf("%s:%s", dir, opts.WorkDir),
+			fmt.Sprintf("%s:/artifacts", filepath.Join(dir, "artifacts")),
+		},
+		NetworkMode:    "none",
+		ReadonlyRootfs: true,
+		Tmpfs:          map[string]string{"/tmp": "rw,exec"},
+		SecurityOpt:    opts.securityOpts(),
+		Resources:      opts.resources(language),
+	}, nil, nil, "")
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to create pooled container: %w", err)
+	}
+	resources.RegisterContainer(resources.ActiveContainer{ContainerID: created.ID, Language: language.String(), Image: image, Pooled: true})
+	pc := &pooledContainer{ID: created.ID, Dir: dir, Key: key}
+	if err := cli.ContainerStart(ctx, created.ID, container.StartOptions{}); err != nil {
+		discardContainer(context.WithoutCancel(ctx), cli, pc)
+		return nil, fmt.Errorf("failed to start pooled container: %w", err)
+	}
+	return pc, nil
+}
+
+// execPooled runs cmd in a pooled container. The run is recorded under the
+// ID of its exec, so its artifacts, output and registry entry are its own and
+// never those of another run in the same container. It returns that run ID,
+// the exit code and the multiplexed output of the command. An exec can't be
+// stopped on its own, so a command still running at timeout is ended by
+// removing the container, and timedOut is set. The container's directory is
+// kept, so the caller can still collect the artifacts.
+func execPooled(ctx context.Context, cli *client.Client, pc *pooledContainer, cmd []string, env []string, run resources.Run, timeout time.Duration) (runID string, exitCode int64, timedOut bool, output *bytes.Buffer, err error) {
+	exec, err := cli.ContainerExecCreate(ctx, pc.ID, container.ExecOptions{
+		Cmd: cmd,
+		// Caches that tools write to the home directory go to /tmp, the
+		// only writable place besides the work directory
+		Env:          append([]string{"HOME=/tmp"}, env...),
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return "", 0, false, nil, fmt.Errorf("failed to create exec in pooled container: %w", err)
+	}
+	run.ContainerID, run.PooledContainerID = exec.ID, pc.ID
+	run.StartedAt = time.Now()
+	resources.RegisterRun(run)
+
+	attach, err := cli.ContainerExecAttach(ctx, exec.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return exec.ID, 0, false, nil, fmt.Errorf("failed to start exec in pooled container: %w", err)
+	}
+	defer attach.Close()
+
+	output = &bytes.Buffer{}
+	copied := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(output, attach.Reader)
+		copied <- err
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-copied:
+		if err != nil {
+			return exec.ID, 0, false, nil, fmt.Errorf("failed to copy exec output: %w", err)
+		}
+	case <-timer.C:
+		// Keep the output written so far, once the copy has stopped
+		_ = removeContainer(context.WithoutCancel(ctx), cli, pc.ID)
+		attach.Close()
+		<-copied
+		return exec.ID, execKilledExitCode, true, output, nil
+	case <-ctx.Done():
+		discardContainer(context.WithoutCancel(ctx), cli, pc)
+		attach.Close()
+		<-copied
+		return exec.ID, 0, false, nil, ctx.Err()
+	}
+
+	inspect, err := cli.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return exec.ID, 0, false, nil, fmt.Errorf("failed to inspect exec: %w", err)
+	}
+	return exec.ID, int64(inspect.ExitCode), false, output, nil
+}
+
+// stopPooledProcesses kills whatever a run left running in a pooled
+// container and reports whether that succeeded. It runs before the run's
+// artifacts are collected, so nothing can change them during collection.
+// kill -1 signals every process but the container's init and the shell
+// running it.
+func stopPooledProcesses(ctx context.Context, cli *client.Client, pc *pooledContainer) bool {
+	return execAndWait(ctx, cli, pc.ID, "kill -9 -1 2>/dev/null; true")
+}
+
+// releaseContainer empties the directories of a pooled container whose
+// processes were stopped and returns it to the pool. A container that can't
+// be cleaned, or that doesn't fit in the pool, is removed instead.
+func releaseContainer(ctx context.Context, cli *client.Client, pc *pooledContainer) {
+	if !cleanPooledContainer(ctx, cli, pc) || !putIdleContainer(pc, time.Now()) {
+		discardContainer(ctx, cli, pc)
+	}
+}
+
+// forgetPooledContainer drops a container removed by cleanup_container from
+// the idle pool along with its directory, and reports whether it was idle
+func forgetPooledContainer(containerID string) bool {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	for key, containers := range idleContainers {
+		for i, pc := range containers {
+			if pc.ID == containerID {
+				idleContainers[key] = append(containers[:i:i], containers[i+1:]...)
+				os.RemoveAll(pc.Dir)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isPooledContainer reports whether containerID is a container of the pool
+// rather than the ID of a run
+func isPooledContainer(containerID string) bool {
+	for _, c := range resources.ActiveContainers() {
+		if c.ContainerID == containerID {
+			return c.Pooled
+		}
+	}
+	return false
+}
+
+// cleanPooledContainer resets a pooled container for the next run and reports
+// whether that succeeded
+func cleanPooledContainer(ctx context.Context, cli *client.Client, pc *pooledContainer) bool {
+	if !execAndWait(ctx, cli, pc.ID, "rm -rf /tmp/* /tmp/.[!.]* /tmp/..?* /dev/shm/* /dev/shm/.[!.]* /dev/shm/..?*") {
+		return false
+	}
+
+	// The artifacts directory is the source of the /artifacts mount, so it
+	// is emptied rather than removed
+	for _, dir := range []string{pc.Dir, filepath.Join(pc.Dir, "artifacts")} {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return false
+		}
+		for _, entry := range entries {
+			if dir == pc.Dir && entry.Name() == "artifacts" {
+				continue
+			}
+			if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// execAndWait runs a shell script in a container and reports whether it
+// exited successfully
+func execAndWait(ctx context.Context, cli *client.Client, containerID string, script string) bool {
+	exec, err := cli.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		Cmd: []string{"/bin/sh", "-c", script},
+	})
+	if err != nil {
+		return false
+	}
+	if err := cli.ContainerExecStart(ctx, exec.ID, container.ExecStartOptions{}); err != nil {
+		return false
+	}
+	// The exec runs detached, so wait for it to finish
+	for {
+		inspect, err := cli.ContainerExecInspect(ctx, exec.ID)
+		if err != nil || (!inspect.Running && inspect.ExitCode != 0) {
+			return false
+		}
+		if !inspect.Running {
+			return true
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// discardContainer removes a pooled container and its directory
+func discardContainer(ctx context.Context, cli *client.Client, pc *pooledContainer) {
+	_ = removeContainer(ctx, cli, pc.ID)
+	os.RemoveAll(pc.Dir)
+}
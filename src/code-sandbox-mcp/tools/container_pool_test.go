@@ -0,0 +1,145 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+	"github.com/Automata-Labs-team/code-sandbox-mcp/resources"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// setPoolSize sets the pool size for a test without starting the evictor
+func setPoolSize(t *testing.T, size int) {
+	poolMu.Lock()
+	poolSize = size
+	poolMu.Unlock()
+	t.Cleanup(func() {
+		poolMu.Lock()
+		poolSize = 0
+		idleContainers = make(map[string][]*pooledContainer)
+		poolMu.Unlock()
+	})
+}
+
+func TestPoolable(t *testing.T) {
+	plain := runOptions{WorkDir: DefaultWorkDir}
+	tests := []struct {
+		name     string
+		size     int
+		opts     runOptions
+		packages []string
+		remote   bool
+		want     bool
+	}{
+		{name: "plain snippet", size: 2, opts: plain, want: true},
+		{name: "pool disabled", opts: plain},
+		{name: "packages to install", size: 2, opts: plain, packages: []string{"requests"}},
+		{name: "install command", size: 2, opts: runOptions{InstallCommand: "pip install requests"}},
+		{name: "network access", size: 2, opts: runOptions{AllowNetwork: true}},
+		{name: "stdin", size: 2, opts: runOptions{Stdin: "1 2"}},
+		{name: "session", size: 2, opts: runOptions{SessionID: "s1"}},
+		{name: "secrets", size: 2, opts: runOptions{Secrets: map[string]string{"api_key": "k"}}},
+		{name: "read-only files", size: 2, opts: runOptions{ReadOnlyFiles: []string{"/etc/hosts:/hosts:ro"}}},
+		{name: "remote daemon", size: 2, opts: plain, remote: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setPoolSize(t, tt.size)
+			if got := poolable(tt.opts, tt.packages, tt.remote); got != tt.want {
+				t.Errorf("poolable() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPoolKey(t *testing.T) {
+	image := languages.SupportedLanguages[languages.Python].Image
+	base := poolKey(image, languages.Python, runOptions{WorkDir: DefaultWorkDir})
+	if got := poolKey(image, languages.Python, runOptions{WorkDir: DefaultWorkDir}); got != base {
+		t.Errorf("same run settings gave keys %q and %q", base, got)
+	}
+	for name, opts := range map[string]runOptions{
+		"work directory": {WorkDir: "/src"},
+		"memory limit":   {WorkDir: DefaultWorkDir, MemoryLimit: 256},
+		"cpu limit":      {WorkDir: DefaultWorkDir, CPULimit: 0.5},
+	} {
+		if poolKey(image, languages.Python, opts) == base {
+			t.Errorf("different %s gave the same key %q", name, base)
+		}
+	}
+}
+
+func TestIdleContainers(t *testing.T) {
+	setPoolSize(t, 2)
+	now := time.Now()
+
+	if pc := takeIdleContainer("python"); pc != nil {
+		t.Fatalf("takeIdleContainer() on an empty pool = %+v", pc)
+	}
+	if !putIdleContainer(&pooledContainer{ID: "a", Key: "python"}, now.Add(-time.Hour)) ||
+		!putIdleContainer(&pooledContainer{ID: "b", Key: "go"}, now) {
+		t.Fatal("putIdleContainer() refused a container below the pool size")
+	}
+	if putIdleContainer(&pooledContainer{ID: "c", Key: "python"}, now) {
+		t.Error("putIdleContainer() accepted a container beyond the pool size")
+	}
+
+	expired := takeExpiredContainers(now)
+	if len(expired) != 1 || expired[0].ID != "a" {
+		t.Errorf("takeExpiredContainers() = %+v, want only a", expired)
+	}
+	if pc := takeIdleContainer("python"); pc != nil {
+		t.Errorf("expired container %s is still in the pool", pc.ID)
+	}
+	if pc := takeIdleContainer("go"); pc == nil || pc.ID != "b" {
+		t.Errorf("takeIdleContainer(go) = %+v, want b", pc)
+	}
+}
+
+func TestForgetPooledContainer(t *testing.T) {
+	setPoolSize(t, 3)
+	dir := t.TempDir()
+	for _, id := range []string{"a", "b"} {
+		putIdleContainer(&pooledContainer{ID: id, Key: "python", Dir: filepath.Join(dir, id)}, time.Now())
+		if err := os.Mkdir(filepath.Join(dir, id), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !forgetPooledContainer("a") {
+		t.Errorf("forgetPooledContainer() of an idle container = false, want true")
+	}
+	if forgetPooledContainer("busy") {
+		t.Errorf("forgetPooledContainer() of a container not in the pool = true, want false")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a")); !os.IsNotExist(err) {
+		t.Errorf("directory of the forgotten container was kept")
+	}
+	if pc := takeIdleContainer("python"); pc == nil || pc.ID != "b" {
+		t.Errorf("takeIdleContainer() = %+v, want b", pc)
+	}
+	if pc := takeIdleContainer("python"); pc != nil {
+		t.Errorf("forgotten container %s is still in the pool", pc.ID)
+	}
+}
+
+func TestCleanupContainerRefusesBusyPooledContainer(t *testing.T) {
+	resources.RegisterContainer(resources.ActiveContainer{ContainerID: "pooled-busy", Language: "python", Pooled: true})
+	defer resources.UnregisterContainer("pooled-busy")
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{"containerId": "pooled-busy"}
+	result, err := CleanupContainer(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !result.IsError || !strings.Contains(text, "pooled container running a snippet") {
+		t.Errorf("CleanupContainer() of a busy pooled container = %q, want it refused", text)
+	}
+}
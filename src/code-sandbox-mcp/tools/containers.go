@@ -0,0 +1,331 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/logging"
+	"github.com/Automata-Labs-team/code-sandbox-mcp/resources"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/moby/moby/client"
+)
+
+// Labels applied to every container started by the sandbox so they can be
+// found again later, e.g. to cancel everything belonging to a session
+const (
+	sandboxLabel  = "code-sandbox-mcp"
+	sessionLabel  = "code-sandbox-mcp.session"
+	languageLabel = "code-sandbox-mcp.language"
+	instanceLabel = "code-sandbox-mcp.instance"
+	// deadlineLabel holds when a run's timeout ends, in RFC 3339 format
+	deadlineLabel = "code-sandbox-mcp.deadline"
+)
+
+// instanceID identifies the containers of this server among those of other
+// servers sharing the Docker daemon
+var instanceID = "code-sandbox-mcp"
+
+// SetInstanceID sets the ID the server's containers are labelled with.
+// Startup cleanup only touches containers with the same ID.
+func SetInstanceID(id string) {
+	instanceID = id
+}
+
+// containerLabels returns the labels to set on a newly created container
+func containerLabels(sessionID string, language string) map[string]string {
+	labels := map[string]string{
+		sandboxLabel:  "true",
+		languageLabel: language,
+		instanceLabel: instanceID,
+	}
+	if sessionID != "" {
+		labels[sessionLabel] = sessionID
+	}
+	return labels
+}
+
+// runLabels returns the labels of a container started for a run. They also
+// record when the run's timeout ends, so an instance restarted while the run
+// is going can still enforce it.
+func runLabels(sessionID string, language string, timeout time.Duration) map[string]string {
+	labels := containerLabels(sessionID, language)
+	labels[deadlineLabel] = time.Now().Add(timeout).UTC().Format(time.RFC3339)
+	return labels
+}
+
+// stopAndRemoveContainer stops a container, giving it the stop timeout it was
+// created with to exit gracefully, and then removes it
+func stopAndRemoveContainer(ctx context.Context, cli *client.Client, containerID string) error {
+	if err := cli.ContainerStop(ctx, containerID, container.StopOptions{}); err != nil && !client.IsErrNotFound(err) {
+		return err
+	}
+	return removeContainer(ctx, cli, containerID)
+}
+
+// waitWithTimeout waits for a container to exit and returns its exit code. If
+// it is still running after timeout it is stopped and timedOut is true.
+func waitWithTimeout(ctx context.Context, cli *client.Client, containerID string, timeout time.Duration) (exitCode int64, timedOut bool, err error) {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	statusCh, errCh := cli.ContainerWait(waitCtx, containerID, container.WaitConditionNotRunning)
+	select {
+	case err := <-errCh:
+		if err == nil {
+			return 0, false, nil
+		}
+		if ctx.Err() != nil || !errors.Is(waitCtx.Err(), context.DeadlineExceeded) {
+			return 0, false, fmt.Errorf("failed to wait for container: %w", err)
+		}
+	case status := <-statusCh:
+		return status.StatusCode, false, nil
+	}
+
+	if err := cli.ContainerStop(ctx, containerID, container.StopOptions{}); err != nil && !client.IsErrNotFound(err) {
+		return 0, true, fmt.Errorf("failed to stop timed out container: %w", err)
+	}
+	// Report the exit code the stopped container ended up with
+	if inspect, err := cli.ContainerInspect(ctx, containerID); err == nil && inspect.State != nil {
+		exitCode = int64(inspect.State.ExitCode)
+	}
+	return exitCode, true, nil
+}
+
+// wasOOMKilled reports whether a stopped container was killed for exceeding
+// its memory limit. The OOM killer usually leaves exit code 137, but only the
+// container state tells it apart from other kills.
+func wasOOMKilled(ctx context.Context, cli *client.Client, containerID string) bool {
+	inspect, err := cli.ContainerInspect(ctx, containerID)
+	return err == nil && inspect.State != nil && inspect.State.OOMKilled
+}
+
+// watchBackgroundRun follows a run that keeps going after its tool call has
+// returned. It stops the container once it runs longer than timeout and
+// records the run when it finishes, including the results of its entrypoint
+// steps if it has any, and the artifacts it wrote to artifacts. The container
+// is then removed, keeping its output for the logs resource. onDone, if set,
+// is called at the end. The caller adds the run to backgroundRuns.
+func watchBackgroundRun(ctx context.Context, containerID string, command []string, steps []string, timeout time.Duration, artifacts runArtifacts, onDone func()) {
+	defer backgroundRuns.Done()
+	if onDone != nil {
+		defer onDone()
+	}
+
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return
+	}
+	defer cli.Close()
+
+	exitCode, timedOut, err := waitWithTimeout(ctx, cli, containerID, timeout)
+	if err != nil {
+		return
+	}
+	if len(steps) > 0 {
+		_ = collectStepResults(ctx, cli, containerID, steps)
+	}
+	oomKilled := !timedOut && wasOOMKilled(ctx, cli, containerID)
+	if oomKilled {
+		resources.SetRunOOMKilled(containerID)
+	}
+	resources.SetRunExited(containerID, exitCode)
+
+	artifactURIs := collectRunArtifacts(ctx, cli, containerID, artifacts)
+	logs, stdout, stderr, _ := resources.ReadContainerOutput(ctx, containerID)
+	_ = recordRun(ctx, cli, resources.RunRecord{
+		ContainerID: containerID,
+		Command:     command,
+		ExitCode:    exitCode,
+		TimedOut:    timedOut,
+		OOMKilled:   oomKilled,
+		Logs:        logs,
+		Artifacts:   artifactURIs,
+	})
+	_ = resources.SaveContainerOutput(containerID, exitCode, logs, stdout, stderr)
+	resources.ForgetRunSecrets(containerID)
+	_ = stopAndRemoveContainer(ctx, cli, containerID)
+}
+
+// runArtifacts says where a background run's artifacts are collected from.
+// The zero value collects none.
+type runArtifacts struct {
+	HostDir string // Directory on the host the run writes its artifacts to
+	// ContainerDir is the artifacts directory in the container, for a daemon
+	// that can't mount HostDir. It is copied out over the API before the
+	// container is removed, and its files are written to HostDir as a mount
+	// would have.
+	ContainerDir string
+}
+
+// collectRunArtifacts registers the artifacts a background run wrote as the
+// run's artifacts and returns their URIs
+func collectRunArtifacts(ctx context.Context, cli *client.Client, containerID string, artifacts runArtifacts) []string {
+	if artifacts.ContainerDir == "" {
+		if artifacts.HostDir == "" {
+			return nil
+		}
+		return registerRunArtifacts(containerID, artifacts.HostDir, resources.ArtifactOutput{})
+	}
+
+	copiedDir, err := os.MkdirTemp("", "run-artifacts-")
+	if err != nil {
+		logging.Warnf("Artifacts of container %s could not be copied out: %v", containerID, err)
+		return nil
+	}
+	defer os.RemoveAll(copiedDir)
+	_, oversized, err := copyOutOfContainer(ctx, cli, containerID, artifacts.ContainerDir, copiedDir, true, resources.MaxTotalArtifactBytes(), resources.MaxArtifactFileBytes())
+	if err != nil {
+		logging.Warnf("Some artifacts of container %s could not be copied out: %v", containerID, err)
+	}
+	if len(oversized) > 0 {
+		logging.Warnf("Artifacts of container %s skipped for exceeding the size limits: %s", containerID, strings.Join(oversized, ", "))
+	}
+	return registerRunArtifacts(containerID, copiedDir, resources.ArtifactOutput{Dir: artifacts.HostDir, Overwrite: resources.OverwriteReplace})
+}
+
+// registerRunArtifacts registers the files in artifactsDir as the artifacts
+// of a run, copying them to output, and returns their URIs. A run that wrote
+// no artifacts directory has none.
+func registerRunArtifacts(containerID string, artifactsDir string, output resources.ArtifactOutput) []string {
+	if _, err := os.Stat(artifactsDir); os.IsNotExist(err) {
+		return nil
+	}
+	collection, err := resources.CollectArtifactsFromDir(containerID, artifactsDir, output)
+	if err != nil {
+		logging.Warnf("Some artifacts of container %s could not be collected: %v", containerID, err)
+	}
+	if len(collection.Oversized) > 0 {
+		logging.Warnf("Artifacts of container %s skipped for exceeding the size limits: %s", containerID, strings.Join(collection.Oversized, ", "))
+	}
+	return collection.URIs
+}
+
+// CancelSession stops and removes every container started for a session
+func CancelSession(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sessionID, ok := request.Params.Arguments["sessionId"].(string)
+	if !ok || sessionID == "" {
+		return mcp.NewToolResultError("sessionId must be a non-empty string"), nil
+	}
+
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to create Docker client: %v", err)), nil
+	}
+	defer cli.Close()
+
+	// Containers are looked up by label so runs started before a restart are found too
+	containers, err := cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", fmt.Sprintf("%s=%s", sessionLabel, sessionID))),
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to list session containers: %v", err)), nil
+	}
+
+	ids := make(map[string]bool)
+	for _, c := range containers {
+		ids[c.ID] = true
+	}
+	for _, run := range resources.ListSessionRuns(sessionID) {
+		if run.Status == resources.RunStatusRunning {
+			ids[run.ContainerID] = true
+		}
+	}
+
+	var cancelled, failed []string
+	for id := range ids {
+		resources.SetRunStatus(id, resources.RunStatusCancelled)
+		if err := stopAndRemoveContainer(ctx, cli, id); err != nil {
+			failed = append(failed, fmt.Sprintf("%s (%v)", id, err))
+			continue
+		}
+		cancelled = append(cancelled, id)
+	}
+
+	if len(cancelled) == 0 && len(failed) == 0 {
+		return mcp.NewToolResultText(fmt.Sprintf("No runs found for session %s", sessionID)), nil
+	}
+
+	resultText := fmt.Sprintf("Cancelled %d run(s) for session %s", len(cancelled), sessionID)
+	if len(cancelled) > 0 {
+		resultText += fmt.Sprintf("\n\nCancelled: %s", strings.Join(cancelled, ", "))
+	}
+	if len(failed) > 0 {
+		resultText += fmt.Sprintf("\n\nFailed to cancel: %s", strings.Join(failed, ", "))
+	}
+	return mcp.NewToolResultText(resultText), nil
+}
+
+// ReconcileContainers cleans up containers left behind by a previous instance
+// with the same instance ID. Containers of other instances sharing the Docker
+// daemon are left alone. Exited containers are always removed. Running
+// containers are removed when reapAll is set, otherwise they are registered
+// again and followed like background runs until their timeout, so their logs
+// and outcome stay accessible. Running containers whose timeout is unknown or
+// has passed are removed too. Every container is tried, even after a failure.
+func ReconcileContainers(ctx context.Context, reapAll bool) error {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return fmt.Errorf("failed to create Docker client: %w", err)
+	}
+	defer cli.Close()
+
+	containers, err := cli.ContainerList(ctx, container.ListOptions{
+		All: true,
+		Filters: filters.NewArgs(
+			filters.Arg("label", sandboxLabel),
+			filters.Arg("label", fmt.Sprintf("%s=%s", instanceLabel, instanceID)),
+		),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list sandbox containers: %w", err)
+	}
+
+	var errs []error
+	for _, c := range containers {
+		if remaining, ok := reattachable(c, reapAll, time.Now()); ok {
+			resources.RegisterContainer(resources.ActiveContainer{
+				ContainerID: c.ID,
+				Language:    c.Labels[languageLabel],
+				Image:       c.Image,
+				StartedAt:   time.Unix(c.Created, 0),
+			})
+			resources.RegisterRun(resources.Run{
+				ContainerID: c.ID,
+				SessionID:   c.Labels[sessionLabel],
+				Language:    c.Labels[languageLabel],
+				Image:       c.Image,
+				StartedAt:   time.Unix(c.Created, 0),
+			})
+			backgroundRuns.Add(1)
+			go watchBackgroundRun(context.WithoutCancel(ctx), c.ID, nil, nil, remaining, runArtifacts{}, nil)
+			continue
+		}
+		if err := stopAndRemoveContainer(ctx, cli, c.ID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove container %s: %w", c.ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// reattachable reports whether a container found at startup is kept and
+// followed, and how long it may still run. Pooled containers run nothing
+// between runs, so they are never kept.
+func reattachable(c types.Container, reapAll bool, now time.Time) (time.Duration, bool) {
+	if reapAll || c.State != "running" || c.Labels[poolLabel] != "" {
+		return 0, false
+	}
+	deadline, err := time.Parse(time.RFC3339, c.Labels[deadlineLabel])
+	if err != nil || !deadline.After(now) {
+		return 0, false
+	}
+	return deadline.Sub(now), true
+}
@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/resources"
+	"github.com/docker/docker/api/types"
+)
+
+func TestRunLabels(t *testing.T) {
+	defer SetInstanceID(instanceID)
+	SetInstanceID("instance-a")
+
+	before := time.Now().Truncate(time.Second)
+	labels := runLabels("s1", "python", time.Minute)
+	if labels[instanceLabel] != "instance-a" || labels[sessionLabel] != "s1" || labels[sandboxLabel] != "true" {
+		t.Errorf("runLabels() = %v, want the instance, session and sandbox labels", labels)
+	}
+	deadline, err := time.Parse(time.RFC3339, labels[deadlineLabel])
+	if err != nil || deadline.Before(before.Add(time.Minute)) || deadline.After(time.Now().Add(time.Minute)) {
+		t.Errorf("deadline label = %q (%v), want a minute from now", labels[deadlineLabel], err)
+	}
+}
+
+func TestReattachable(t *testing.T) {
+	now := time.Now()
+	deadline := now.Add(time.Minute).UTC().Format(time.RFC3339)
+	past := now.Add(-time.Minute).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name    string
+		state   string
+		labels  map[string]string
+		reapAll bool
+		want    bool
+	}{
+		{name: "running with time left", state: "running", labels: map[string]string{deadlineLabel: deadline}, want: true},
+		{name: "reap all", state: "running", labels: map[string]string{deadlineLabel: deadline}, reapAll: true},
+		{name: "exited", state: "exited", labels: map[string]string{deadlineLabel: deadline}},
+		{name: "pooled", state: "running", labels: map[string]string{deadlineLabel: deadline, poolLabel: "true"}},
+		{name: "timeout passed", state: "running", labels: map[string]string{deadlineLabel: past}},
+		{name: "no deadline", state: "running", labels: map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remaining, ok := reattachable(types.Container{State: tt.state, Labels: tt.labels}, tt.reapAll, now)
+			if ok != tt.want {
+				t.Fatalf("reattachable() = %t, want %t", ok, tt.want)
+			}
+			if ok && (remaining <= 0 || remaining > time.Minute) {
+				t.Errorf("reattachable() remaining = %v, want up to a minute", remaining)
+			}
+		})
+	}
+}
+
+func TestCollectRunArtifacts(t *testing.T) {
+	projectDir := t.TempDir()
+	artifactsDir := filepath.Join(projectDir, projectArtifactsDir)
+	if err := os.MkdirAll(filepath.Join(artifactsDir, "plots"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"report.txt", "plots/a.csv"} {
+		if err := os.WriteFile(filepath.Join(artifactsDir, filepath.FromSlash(name)), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	containerID := "test-project-artifacts"
+	defer resources.CleanupContainerArtifacts(containerID)
+
+	got := collectRunArtifacts(context.Background(), nil, containerID, runArtifacts{HostDir: artifactsDir})
+	sort.Strings(got)
+	want := []string{"artifacts://" + containerID + "/plots/a.csv", "artifacts://" + containerID + "/report.txt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("collectRunArtifacts() = %v, want %v", got, want)
+	}
+	listed, err := resources.ListContainerArtifacts(context.Background(), containerID+"/")
+	if err != nil || len(listed) != 2 {
+		t.Errorf("ListContainerArtifacts() = %v, %v, want the 2 collected artifacts", listed, err)
+	}
+
+	// A project that wrote no artifacts directory has no artifacts
+	if got := collectRunArtifacts(context.Background(), nil, "test-no-project-artifacts", runArtifacts{HostDir: filepath.Join(t.TempDir(), projectArtifactsDir)}); len(got) != 0 {
+		t.Errorf("collectRunArtifacts() without an artifacts directory = %v, want none", got)
+	}
+}
+
+func TestRegisterRunArtifactsCopiedOut(t *testing.T) {
+	// Artifacts copied out of a container on a remote daemon are written to
+	// the project on the host, as a mount would have
+	copiedDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(copiedDir, "out.txt"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hostDir := filepath.Join(t.TempDir(), projectArtifactsDir)
+	if err := os.MkdirAll(hostDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(hostDir, "out.txt"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	containerID := "test-copied-artifacts"
+	defer resources.CleanupContainerArtifacts(containerID)
+
+	got := registerRunArtifacts(containerID, copiedDir, resources.ArtifactOutput{Dir: hostDir, Overwrite: resources.OverwriteReplace})
+	if len(got) != 1 || got[0] != "artifacts://"+containerID+"/out.txt" {
+		t.Errorf("registerRunArtifacts() = %v, want out.txt", got)
+	}
+	if data, err := os.ReadFile(filepath.Join(hostDir, "out.txt")); err != nil || string(data) != "new" {
+		t.Errorf("project artifact = %q, %v, want the copied out file", data, err)
+	}
+}
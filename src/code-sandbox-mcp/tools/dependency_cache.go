@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/moby/moby/client"
+)
+
+// dependencyCacheEnabled controls whether package manager caches are kept in
+// Docker volumes shared between runs. Shared caches speed up installs but let
+// one run's code tamper with packages later runs install, so it is opt-in.
+var dependencyCacheEnabled bool
+
+// SetDependencyCache enables or disables shared dependency cache volumes
+func SetDependencyCache(enabled bool) {
+	dependencyCacheEnabled = enabled
+}
+
+// dependencyCacheMount returns the cache volume mount for a language, creating
+// the volume if needed. cached reports whether an existing volume was reused.
+// ok is false when caching is disabled or the language has no cache directory.
+func dependencyCacheMount(ctx context.Context, cli *client.Client, language languages.Language) (m mount.Mount, cached bool, ok bool, err error) {
+	cacheDir := languages.SupportedLanguages[language].CacheDir
+	if !dependencyCacheEnabled || cacheDir == "" {
+		return mount.Mount{}, false, false, nil
+	}
+
+	name := fmt.Sprintf("code-sandbox-mcp-cache-%s", language)
+	if _, err := cli.VolumeInspect(ctx, name); err == nil {
+		cached = true
+	} else if client.IsErrNotFound(err) {
+		if _, err := cli.VolumeCreate(ctx, volume.CreateOptions{
+			Name:   name,
+			Labels: map[string]string{sandboxLabel: "true"},
+		}); err != nil {
+			return mount.Mount{}, false, false, fmt.Errorf("failed to create dependency cache volume: %w", err)
+		}
+	} else {
+		return mount.Mount{}, false, false, fmt.Errorf("failed to inspect dependency cache volume: %w", err)
+	}
+
+	return mount.Mount{
+		Type:   mount.TypeVolume,
+		Source: name,
+		Target: cacheDir,
+	}, cached, true, nil
+}
@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/resources"
+)
+
+// runDurations splits the wall-clock time of a run into its phases, so a slow
+// install can be told apart from slow code
+type runDurations struct {
+	Pull    time.Duration // Pulling or checking the image
+	Install time.Duration // Dependency install step, zero when its end isn't known
+	Run     time.Duration // From container start to exit, the install step included
+}
+
+// durationsOf returns the durations of a run, or false while it hasn't exited.
+// The end of the install step is only known for runs isolated after it.
+func durationsOf(run resources.Run) (runDurations, bool) {
+	if run.FinishedAt.IsZero() {
+		return runDurations{}, false
+	}
+	d := runDurations{Pull: run.PullDuration, Run: run.FinishedAt.Sub(run.StartedAt)}
+	if !run.InstalledAt.IsZero() {
+		d.Install = run.InstalledAt.Sub(run.StartedAt)
+	}
+	return d, true
+}
+
+// summary renders the total duration followed by its phases. A run that
+// hasn't finished only reports the image pull.
+func (d runDurations) summary() string {
+	if d.Run == 0 {
+		return fmt.Sprintf("\n\nImage pull: %s", d.Pull.Round(time.Millisecond))
+	}
+	phases := []string{fmt.Sprintf("image pull %s", d.Pull.Round(time.Millisecond))}
+	if d.Install > 0 {
+		phases = append(phases, fmt.Sprintf("dependency install %s", d.Install.Round(time.Millisecond)))
+	}
+	phases = append(phases, fmt.Sprintf("execution %s", (d.Run-d.Install).Round(time.Millisecond)))
+	return fmt.Sprintf("\n\nDuration: %s (%s)", (d.Pull + d.Run).Round(time.Millisecond), strings.Join(phases, ", "))
+}
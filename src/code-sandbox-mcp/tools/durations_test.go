@@ -0,0 +1,50 @@
+package tools
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/resources"
+)
+
+func TestDurationsSummary(t *testing.T) {
+	started := time.Now()
+	tests := []struct {
+		name string
+		run  resources.Run
+		want string
+	}{
+		{
+			name: "still running",
+			run:  resources.Run{StartedAt: started, PullDuration: 1500 * time.Millisecond},
+			want: "\n\nImage pull: 1.5s",
+		},
+		{
+			name: "no install step",
+			run:  resources.Run{StartedAt: started, FinishedAt: started.Add(2 * time.Second), PullDuration: 250 * time.Millisecond},
+			want: "\n\nDuration: 2.25s (image pull 250ms, execution 2s)",
+		},
+		{
+			name: "isolated after install",
+			run: resources.Run{
+				StartedAt:    started,
+				InstalledAt:  started.Add(3 * time.Second),
+				FinishedAt:   started.Add(4 * time.Second),
+				PullDuration: time.Second,
+			},
+			want: "\n\nDuration: 5s (image pull 1s, dependency install 3s, execution 1s)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, ok := durationsOf(tt.run)
+			if !ok {
+				d = runDurations{Pull: tt.run.PullDuration}
+			}
+			if got := d.summary(); got != tt.want {
+				t.Errorf("summary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	deps "github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+)
+
+// packageScripts are the package.json scripts tried, in order, when a Node.js
+// project is run without an entrypoint
+var packageScripts = []string{"start", "dev"}
+
+// inferEntrypoint works out how to run a project from its manifest or the
+// usual entry file of its language, for when run_project is called without an
+// entrypoint. It returns the command and where it came from.
+func inferEntrypoint(projectDir string, language deps.Language) (cmd string, source string, err error) {
+	exists := func(name string) bool {
+		info, err := os.Stat(filepath.Join(projectDir, name))
+		return err == nil && !info.IsDir()
+	}
+
+	switch language {
+	case deps.NodeJS, deps.TypeScript:
+		if exists("package.json") {
+			content, err := os.ReadFile(filepath.Join(projectDir, "package.json"))
+			if err != nil {
+				return "", "", fmt.Errorf("failed to read package.json: %w", err)
+			}
+			var manifest struct {
+				Scripts map[string]string `json:"scripts"`
+			}
+			if err := json.Unmarshal(content, &manifest); err != nil {
+				return "", "", fmt.Errorf("failed to parse package.json: %w", err)
+			}
+			for _, script := range packageScripts {
+				if manifest.Scripts[script] != "" {
+					manager, _ := detectNodePackageManager(projectDir, nil)
+					return manager + " run " + script, "package.json scripts." + script, nil
+				}
+			}
+		}
+	case deps.Python:
+		if exists("__main__.py") {
+			return "python3 .", "__main__.py", nil
+		}
+		if exists("main.py") {
+			return "python3 main.py", "main.py", nil
+		}
+	case deps.Go:
+		if exists("main.go") {
+			return "go run .", "main.go", nil
+		}
+	case deps.Rust:
+		if exists("Cargo.toml") {
+			return "cargo run", "Cargo.toml", nil
+		}
+	case deps.Bash:
+		if exists("main.sh") {
+			return "bash main.sh", "main.sh", nil
+		}
+	case deps.Dart:
+		if exists("pubspec.yaml") && exists(filepath.Join("bin", "main.dart")) {
+			return "dart run", "pubspec.yaml and bin/main.dart", nil
+		}
+	}
+
+	return "", "", fmt.Errorf("entrypointCmd is required: no start script or entry file found for %s in the project", language)
+}
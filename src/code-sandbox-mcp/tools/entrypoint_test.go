@@ -0,0 +1,100 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	deps "github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+)
+
+func TestInferEntrypoint(t *testing.T) {
+	tests := []struct {
+		name        string
+		language    deps.Language
+		files       map[string]string
+		want        string
+		errContains string
+	}{
+		{
+			name:     "node start script",
+			language: deps.NodeJS,
+			files:    map[string]string{"package.json": `{"scripts": {"dev": "vite", "start": "node server.js"}}`},
+			want:     "bun run start",
+		},
+		{
+			name:     "node dev script",
+			language: deps.NodeJS,
+			files:    map[string]string{"package.json": `{"scripts": {"dev": "vite", "test": "vitest"}}`},
+			want:     "bun run dev",
+		},
+		{
+			name:     "node start script with npm lockfile",
+			language: deps.NodeJS,
+			files:    map[string]string{"package.json": `{"scripts": {"start": "node server.js"}}`, "package-lock.json": `{}`},
+			want:     "npm run start",
+		},
+		{
+			name:        "node without scripts",
+			language:    deps.NodeJS,
+			files:       map[string]string{"package.json": `{"name": "app"}`},
+			errContains: "entrypointCmd is required",
+		},
+		{
+			name:        "invalid package.json",
+			language:    deps.NodeJS,
+			files:       map[string]string{"package.json": `{`},
+			errContains: "failed to parse package.json",
+		},
+		{
+			name:     "python main",
+			language: deps.Python,
+			files:    map[string]string{"main.py": "print(1)"},
+			want:     "python3 main.py",
+		},
+		{
+			name:     "go main",
+			language: deps.Go,
+			files:    map[string]string{"go.mod": "module app", "main.go": "package main"},
+			want:     "go run .",
+		},
+		{
+			name:     "bash main",
+			language: deps.Bash,
+			files:    map[string]string{"main.sh": "echo hello"},
+			want:     "bash main.sh",
+		},
+		{
+			name:        "no convention",
+			language:    deps.OCaml,
+			files:       map[string]string{"main.ml": ""},
+			errContains: "entrypointCmd is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, _, err := inferEntrypoint(dir, tt.language)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("inferEntrypoint() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("inferEntrypoint() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("inferEntrypoint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// envNameRe matches well-formed environment variable names
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// reservedEnv are the variables the server sets itself, which a run can't override
+var reservedEnv = map[string]bool{
+	"ARTIFACTS_DIR":      true,
+	"USER_ARTIFACTS_DIR": true,
+}
+
+// secretEnvWords mark variable names whose values are kept out of the server's logs
+var secretEnvWords = []string{"TOKEN", "KEY", "SECRET", "PASSWORD"}
+
+// parseEnv reads the env parameter, an object of variable names to values,
+// and returns the variables as KEY=value sorted by name
+func parseEnv(value interface{}) ([]string, error) {
+	vars, err := stringMap("env", value)
+	if err != nil {
+		return nil, err
+	}
+
+	var env []string
+	for name, value := range vars {
+		if !envNameRe.MatchString(name) {
+			return nil, fmt.Errorf("invalid environment variable name %q", name)
+		}
+		if reservedEnv[name] {
+			return nil, fmt.Errorf("environment variable %s is set by the server and cannot be overridden", name)
+		}
+		env = append(env, name+"="+value)
+	}
+	sort.Strings(env)
+	return env, nil
+}
+
+// isSecretEnv reports whether a variable name looks like it holds a secret
+func isSecretEnv(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, word := range secretEnvWords {
+		if strings.Contains(upper, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskedEnv renders KEY=value variables for the server's logs, masking the
+// values of those that look like secrets
+func maskedEnv(env []string) string {
+	masked := make([]string, len(env))
+	for i, variable := range env {
+		name, _, _ := strings.Cut(variable, "=")
+		if isSecretEnv(name) {
+			variable = name + "=****"
+		}
+		masked[i] = variable
+	}
+	return strings.Join(masked, " ")
+}
@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEnv(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       interface{}
+		want        []string
+		errContains string
+	}{
+		{
+			name:  "object",
+			value: map[string]interface{}{"MY_FLAG": "1", "API_URL": "https://example.com/v1?a=b"},
+			want:  []string{"API_URL=https://example.com/v1?a=b", "MY_FLAG=1"},
+		},
+		{name: "json string", value: `{"_DEBUG": ""}`, want: []string{"_DEBUG="}},
+		{name: "name starting with a digit", value: map[string]interface{}{"1FLAG": "x"}, errContains: "invalid environment variable name"},
+		{name: "name with equals sign", value: map[string]interface{}{"A=B": "x"}, errContains: "invalid environment variable name"},
+		{name: "reserved name", value: map[string]interface{}{"ARTIFACTS_DIR": "/tmp"}, errContains: "set by the server"},
+		{name: "non-string value", value: map[string]interface{}{"PORT": 8080.0}, errContains: "must be a string"},
+		{name: "list", value: []interface{}{"A=1"}, errContains: "env must be an object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEnv(tt.value)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("parseEnv() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseEnv() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseEnv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskedEnv(t *testing.T) {
+	env := []string{"API_URL=https://example.com", "GITHUB_TOKEN=ghp_abc", "aws_secret_access_key=xyz", "DB_PASSWORD=a=b", "MODE=fast"}
+	want := "API_URL=https://example.com GITHUB_TOKEN=**** aws_secret_access_key=**** DB_PASSWORD=**** MODE=fast"
+	if got := maskedEnv(env); got != want {
+		t.Errorf("maskedEnv() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/resources"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxResultLogBytes bounds how much log output get_run_results returns inline
+const maxResultLogBytes = 64 * 1024
+
+// GetRunResults returns the logs and artifacts of a run in a single response
+func GetRunResults(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	containerID, ok := request.Params.Arguments["containerId"].(string)
+	if !ok || containerID == "" {
+		return mcp.NewToolResultError("containerId must be a non-empty string"), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Container: %s", containerID)
+	// A run that exited non-zero is reported as failed, without having to
+	// read through its logs
+	failed := false
+	if run, ok := resources.GetRun(containerID); ok {
+		fmt.Fprintf(&b, "\nLanguage: %s\nImage: %s\nStarted: %s\nStatus: %s",
+			run.Language, run.Image, run.StartedAt.Format(time.RFC3339), run.Status)
+		if run.Status == resources.RunStatusExited {
+			fmt.Fprintf(&b, "\nExit code: %d", run.ExitCode)
+			failed = run.ExitCode != 0
+		}
+		if run.OOMKilled {
+			b.WriteString("\nThe container exceeded its memory limit and was killed")
+		}
+		if durations, ok := durationsOf(run); ok {
+			b.WriteString(durations.summary())
+		}
+	} else if exitCode, ok := resources.SavedExitCode(containerID); ok {
+		// The run was started before the server last restarted
+		fmt.Fprintf(&b, "\nStatus: %s\nExit code: %d", resources.RunStatusExited, exitCode)
+		failed = exitCode != 0
+	}
+	if run, ok := resources.GetRun(containerID); ok && len(run.Steps) > 0 {
+		b.WriteString(stepsSummary(run.Steps))
+	}
+
+	logs, err := resources.ReadContainerLogs(ctx, containerID)
+	failure, rest, installFailed := splitInstallFailure(logs)
+	if installFailed {
+		fmt.Fprintf(&b, "\n\n%s\n\nInstall log:\n%s", failure.summary(), strings.TrimSuffix(truncateTail(failure.Log, maxResultLogBytes), "\n"))
+		logs = rest
+	}
+	logs = stripInstallMarkers(logs)
+	if err != nil {
+		fmt.Fprintf(&b, "\n\nLogs unavailable: %v", err)
+	} else {
+		// Keep the end of the output, which is where errors usually are
+		if len(logs) > maxResultLogBytes {
+			logs = fmt.Sprintf("... [truncated %d bytes, read containers://%s/logs for the full output]\n%s",
+				len(logs)-maxResultLogBytes, containerID, logs[len(logs)-maxResultLogBytes:])
+		}
+		fmt.Fprintf(&b, "\n\nLogs: %s", logs)
+		// The streams of a run that never started only hold the install log
+		if stdout, stderr, err := resources.ReadContainerStreams(ctx, containerID); err == nil && !(installFailed && !failure.Continued) {
+			b.WriteString(logStreams(truncateTail(stripInstallMarkers(stdout), maxResultLogBytes), truncateTail(stripInstallMarkers(stderr), maxResultLogBytes)))
+		}
+	}
+
+	var uris []string
+	artifacts, err := resources.ListContainerArtifacts(ctx, containerID+"/")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to list artifacts: %v", err)), nil
+	}
+	if len(artifacts) > 0 {
+		sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].URI < artifacts[j].URI })
+		b.WriteString("\n\nArtifacts:")
+		for _, artifact := range artifacts {
+			fmt.Fprintf(&b, "\n- %s (%s)", artifact.URI, artifact.MIMEType)
+			uris = append(uris, artifact.URI)
+		}
+		b.WriteString(artifactPreviews(uris))
+	} else if run, ok := resources.GetRun(containerID); ok && run.Status == resources.RunStatusRunning {
+		// Background runs only have their artifacts collected once they exit
+		b.WriteString("\n\nArtifacts: collected once the run has finished")
+	}
+
+	result := resultWithImages(b.String(), uris)
+	result.IsError = failed
+	return result, nil
+}
+
+// truncateTail keeps the last limit bytes of logs, noting how much was cut
+func truncateTail(logs string, limit int) string {
+	if len(logs) > limit {
+		return fmt.Sprintf("... [truncated %d bytes]\n%s", len(logs)-limit, logs[len(logs)-limit:])
+	}
+	return logs
+}
+
+// maxStepLogBytes bounds the output shown for each entrypoint step
+const maxStepLogBytes = 4 * 1024
+
+// stepsSummary renders the outcome and the end of the output of every step
+func stepsSummary(steps []resources.StepResult) string {
+	var b strings.Builder
+	b.WriteString("\n\nSteps:")
+	for i, step := range steps {
+		var outcome string
+		switch {
+		case !step.Started:
+			outcome = "not run"
+		case !step.Finished:
+			outcome = "did not finish"
+		default:
+			outcome = fmt.Sprintf("exit code %d after %s", step.ExitCode, step.Duration.Round(time.Millisecond))
+		}
+		fmt.Fprintf(&b, "\n%d. %s: %s", i+1, step.Command, outcome)
+
+		logs := step.Logs
+		if len(logs) > maxStepLogBytes {
+			logs = fmt.Sprintf("... [truncated %d bytes]\n%s", len(logs)-maxStepLogBytes, logs[len(logs)-maxStepLogBytes:])
+		}
+		if logs != "" {
+			fmt.Fprintf(&b, "\n%s", strings.TrimRight(logs, "\n"))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/resources"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestGetRunResultsSavedExitCode(t *testing.T) {
+	// A run the registry doesn't know about, as after a restart
+	containerID := "test-saved-exit-code"
+	if err := resources.SaveContainerOutput(containerID, 2, "boom\n", "", "boom\n"); err != nil {
+		t.Fatal(err)
+	}
+	defer resources.CleanupContainerArtifacts(containerID)
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{"containerId": containerID}
+	result, err := GetRunResults(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !result.IsError || !strings.Contains(text, "Exit code: 2") || !strings.Contains(text, "boom") {
+		t.Errorf("GetRunResults() = %+v, want the saved exit code and logs reported as an error", result)
+	}
+}
+
+func TestGetRunResultsInstallFailure(t *testing.T) {
+	containerID := "test-install-failure"
+	logs := "ERROR: No matching distribution found for reqests\n" + installFailedMarker + " 1\n"
+	if err := resources.SaveContainerOutput(containerID, 1, logs, "", logs); err != nil {
+		t.Fatal(err)
+	}
+	defer resources.CleanupContainerArtifacts(containerID)
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{"containerId": containerID}
+	result, err := GetRunResults(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !result.IsError || !strings.Contains(text, "installing dependencies failed for reqests") {
+		t.Errorf("GetRunResults() = %q, want the failed install reported", text)
+	}
+	if strings.Contains(text, installFailedMarker) || !strings.Contains(text, "Install log:\nERROR: No matching distribution") {
+		t.Errorf("GetRunResults() = %q, want the install log without the marker", text)
+	}
+}
+
+func TestGetRunResultsProjectArtifacts(t *testing.T) {
+	containerID := "test-project-run-results"
+	resources.RegisterRun(resources.Run{ContainerID: containerID, Language: "python", Image: "python:3.12", StartedAt: time.Now()})
+	defer resources.CleanupContainerArtifacts(containerID)
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{"containerId": containerID}
+	result, err := GetRunResults(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "Artifacts: collected once the run has finished") {
+		t.Errorf("GetRunResults() of a running project = %q, want artifacts reported as pending", text)
+	}
+
+	// The project wrote to artifacts/ and exited
+	artifactsDir := filepath.Join(t.TempDir(), projectArtifactsDir)
+	if err := os.MkdirAll(artifactsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(artifactsDir, "summary.txt"), []byte("done"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	collectRunArtifacts(context.Background(), nil, containerID, runArtifacts{HostDir: artifactsDir})
+	resources.SetRunExited(containerID, 0)
+	if err := resources.SaveContainerOutput(containerID, 0, "ok\n", "ok\n", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err = GetRunResults(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if result.IsError || !strings.Contains(text, "Artifacts:\n- artifacts://"+containerID+"/summary.txt") {
+		t.Errorf("GetRunResults() = %q, want the project's artifact listed", text)
+	}
+}
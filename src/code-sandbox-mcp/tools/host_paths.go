@@ -0,0 +1,170 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// defaultDeniedPaths are host paths safe mode refuses to mount or write to.
+// Entries starting with ~ are relative to the server user's home directory.
+var defaultDeniedPaths = []string{
+	"/etc",
+	"/root",
+	"/boot",
+	"/dev",
+	"/proc",
+	"/sys",
+	"/var/lib/docker",
+	dockerSocket,
+	"~/.ssh",
+	"~/.gnupg",
+	"~/.aws",
+	"~/.kube",
+	"~/.docker",
+	"~/.config/gcloud",
+}
+
+// Host path restrictions. With safe mode on, paths overlapping a denied path
+// are refused. With allowedPaths set, only paths inside one of them are accepted.
+var (
+	safeMode     bool
+	deniedPaths  []string
+	allowedPaths []string
+)
+
+// SetSafeMode enables or disables the host path denylist. extraDenied is added
+// to the built-in list of sensitive paths.
+func SetSafeMode(enabled bool, extraDenied []string) {
+	safeMode = enabled
+	deniedPaths = resolvePathList(append(append([]string{}, defaultDeniedPaths...), extraDenied...))
+}
+
+// SetAllowedPaths restricts host paths to the given directories. An empty list
+// lifts the restriction.
+func SetAllowedPaths(paths []string) {
+	allowedPaths = resolvePathList(paths)
+}
+
+// resolvePathList expands ~ and makes every path absolute, dropping empty entries
+func resolvePathList(paths []string) []string {
+	home, _ := os.UserHomeDir()
+	var resolved []string
+	for _, p := range paths {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if p == "~" || strings.HasPrefix(p, "~/") {
+			if home == "" {
+				continue
+			}
+			p = filepath.Join(home, strings.TrimPrefix(p, "~"))
+		}
+		resolved = append(resolved, realPath(p))
+	}
+	return resolved
+}
+
+// realPath returns the absolute path with symlinks resolved. Paths that don't
+// exist yet are resolved through their closest existing parent.
+func realPath(p string) string {
+	p, err := filepath.Abs(p)
+	if err != nil {
+		return filepath.Clean(p)
+	}
+	var missing []string
+	for {
+		if resolved, err := filepath.EvalSymlinks(p); err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...)
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return filepath.Join(append([]string{p}, missing...)...)
+		}
+		missing = append([]string{filepath.Base(p)}, missing...)
+		p = parent
+	}
+}
+
+// isWithin reports whether p is dir or lies below it
+func isWithin(p string, dir string) bool {
+	rel, err := filepath.Rel(dir, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// checkHostPath validates a host path a tool is about to mount or write to
+// against the configured allowlist and, in safe mode, the denylist. name is
+// the tool parameter the path came from and is used in error messages.
+func checkHostPath(name string, p string) error {
+	resolved := realPath(p)
+
+	if len(allowedPaths) > 0 {
+		allowed := false
+		for _, dir := range allowedPaths {
+			if isWithin(resolved, dir) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("%s %s is outside the allowed paths: %s", name, p, strings.Join(allowedPaths, ", "))
+		}
+	}
+
+	if safeMode {
+		for _, denied := range deniedPaths {
+			// A parent of a sensitive path would expose it too, e.g. mounting
+			// the home directory exposes ~/.ssh
+			if isWithin(resolved, denied) || isWithin(denied, resolved) {
+				return fmt.Errorf("%s %s is not allowed in safe mode because it overlaps the sensitive path %s", name, p, denied)
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseReadOnlyFiles reads the readOnlyFiles option, a comma-separated string
+// or list of hostPath:containerPath pairs, and returns the matching read-only
+// Docker binds. Every host path must be an existing regular file that passes
+// checkHostPath.
+func parseReadOnlyFiles(value interface{}) ([]string, error) {
+	entries, err := stringList("readOnlyFiles", value)
+	if err != nil {
+		return nil, err
+	}
+
+	var binds []string
+	for _, entry := range entries {
+		parts := strings.Split(entry, ":")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("readOnlyFiles entry %q must have the form hostPath:containerPath", entry)
+		}
+		hostPath, containerPath := parts[0], path.Clean(parts[1])
+
+		if err := checkHostPath("readOnlyFiles", hostPath); err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(hostPath)
+		if err != nil {
+			return nil, fmt.Errorf("readOnlyFiles source %s does not exist: %w", hostPath, err)
+		}
+		if !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("readOnlyFiles source %s is not a regular file", hostPath)
+		}
+
+		// Container paths are always Linux paths, so use path rather than filepath
+		if !path.IsAbs(containerPath) || containerPath == "/" {
+			return nil, fmt.Errorf("readOnlyFiles target %s must be an absolute file path", parts[1])
+		}
+		if containerPath == "/artifacts" || strings.HasPrefix(containerPath, "/artifacts/") {
+			return nil, fmt.Errorf("readOnlyFiles target %s cannot be inside /artifacts", containerPath)
+		}
+
+		binds = append(binds, fmt.Sprintf("%s:%s:ro", realPath(hostPath), containerPath))
+	}
+	return binds, nil
+}
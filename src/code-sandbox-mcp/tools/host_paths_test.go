@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckHostPath(t *testing.T) {
+	root := t.TempDir()
+	secrets := filepath.Join(root, "home", ".ssh")
+	projects := filepath.Join(root, "home", "projects")
+	for _, dir := range []string{secrets, projects} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(secrets, filepath.Join(projects, "keys")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		safeMode    bool
+		allowed     []string
+		path        string
+		errContains string
+	}{
+		{name: "no restrictions", path: secrets},
+		{name: "safe mode allows unrelated path", safeMode: true, path: projects},
+		{name: "safe mode allows missing output path", safeMode: true, path: filepath.Join(projects, "out", "new")},
+		{name: "safe mode refuses denied path", safeMode: true, path: secrets, errContains: "safe mode"},
+		{name: "safe mode refuses path inside denied path", safeMode: true, path: filepath.Join(secrets, "keys"), errContains: "safe mode"},
+		{name: "safe mode refuses parent of denied path", safeMode: true, path: filepath.Join(root, "home"), errContains: "safe mode"},
+		{name: "safe mode resolves symlinks", safeMode: true, path: filepath.Join(projects, "keys"), errContains: "safe mode"},
+		{name: "allowlist accepts path inside", allowed: []string{projects}, path: filepath.Join(projects, "app")},
+		{name: "allowlist refuses path outside", allowed: []string{projects}, path: root, errContains: "outside the allowed paths"},
+		{name: "allowlist refuses traversal", allowed: []string{projects}, path: filepath.Join(projects, "..", ".ssh"), errContains: "outside the allowed paths"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetSafeMode(tt.safeMode, []string{secrets})
+			SetAllowedPaths(tt.allowed)
+			t.Cleanup(func() {
+				SetSafeMode(false, nil)
+				SetAllowedPaths(nil)
+			})
+
+			err := checkHostPath("projectDir", tt.path)
+			if tt.errContains == "" {
+				if err != nil {
+					t.Fatalf("checkHostPath() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Fatalf("checkHostPath() error = %v, want error containing %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestParseReadOnlyFiles(t *testing.T) {
+	root := t.TempDir()
+	allowed := filepath.Join(root, "certs")
+	if err := os.MkdirAll(allowed, 0755); err != nil {
+		t.Fatal(err)
+	}
+	cert := filepath.Join(allowed, "ca.pem")
+	outside := filepath.Join(root, "secret.txt")
+	for _, file := range []string{cert, outside} {
+		if err := os.WriteFile(file, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	SetAllowedPaths([]string{allowed})
+	t.Cleanup(func() { SetAllowedPaths(nil) })
+
+	tests := []struct {
+		name        string
+		value       interface{}
+		want        []string
+		errContains string
+	}{
+		{name: "single file", value: cert + ":/etc/ssl/ca.pem", want: []string{realPath(cert) + ":/etc/ssl/ca.pem:ro"}},
+		{name: "list", value: []interface{}{cert + ":/app/ca.pem"}, want: []string{realPath(cert) + ":/app/ca.pem:ro"}},
+		{name: "missing container path", value: cert, errContains: "hostPath:containerPath"},
+		{name: "outside allowlist", value: outside + ":/secret.txt", errContains: "outside the allowed paths"},
+		{name: "missing source", value: filepath.Join(allowed, "missing.pem") + ":/ca.pem", errContains: "does not exist"},
+		{name: "directory source", value: allowed + ":/certs", errContains: "not a regular file"},
+		{name: "relative target", value: cert + ":ca.pem", errContains: "absolute file path"},
+		{name: "artifacts target", value: cert + ":/artifacts/ca.pem", errContains: "inside /artifacts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseReadOnlyFiles(tt.value)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("parseReadOnlyFiles() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseReadOnlyFiles() unexpected error: %v", err)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("parseReadOnlyFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
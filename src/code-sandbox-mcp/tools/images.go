@@ -0,0 +1,177 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/image"
+	"github.com/moby/moby/client"
+)
+
+// DefaultMaxConcurrentPulls is the number of image pulls allowed to run at once
+const DefaultMaxConcurrentPulls = 2
+
+// DefaultImageRefreshInterval is how long a local image is used before it is
+// pulled again, so tags such as :latest pick up new versions
+const DefaultImageRefreshInterval = 10 * time.Minute
+
+// pullCall tracks an in-flight pull that other runs needing the same image can wait on
+type pullCall struct {
+	done chan struct{}
+	err  error
+}
+
+var (
+	pullSem = make(chan struct{}, DefaultMaxConcurrentPulls)
+
+	pullsMu       sync.Mutex
+	inFlightPulls = make(map[string]*pullCall)
+
+	imageRefreshInterval = DefaultImageRefreshInterval
+	// lastPulled records when each image was last pulled, or first found
+	// locally, by this server
+	lastPulled = make(map[string]time.Time)
+)
+
+// SetMaxConcurrentPulls limits how many image pulls may run at the same time.
+// It must be called before any run starts.
+func SetMaxConcurrentPulls(n int) {
+	if n < 1 {
+		n = 1
+	}
+	pullSem = make(chan struct{}, n)
+}
+
+// SetImageRefreshInterval sets how long a local image is used before it is
+// pulled again. Zero never pulls images that are already local. It must be
+// called before any run starts.
+func SetImageRefreshInterval(interval time.Duration) {
+	imageRefreshInterval = interval
+}
+
+// pullNeeded reports whether an image has to be pulled. pulled is when the
+// server last pulled or first found the image locally, zero when never.
+// Images pinned by digest never change, so a local copy is always used.
+func pullNeeded(dockerImage string, local bool, pulled time.Time, now time.Time) bool {
+	if !local {
+		return true
+	}
+	if pulled.IsZero() || strings.Contains(dockerImage, "@") || imageRefreshInterval == 0 {
+		return false
+	}
+	return now.Sub(pulled) >= imageRefreshInterval
+}
+
+// pullImage makes sure an image is available, pulling it when it isn't local,
+// when its local copy is due for a refresh or when force is set. The pull is
+// shared with any concurrent run that needs the same image and waits for a
+// free pull slot before starting.
+func pullImage(ctx context.Context, cli *client.Client, dockerImage string, force bool) error {
+	if !force {
+		_, _, err := cli.ImageInspectWithRaw(ctx, dockerImage)
+		local := err == nil
+		pullsMu.Lock()
+		pulled := lastPulled[dockerImage]
+		if local && pulled.IsZero() {
+			lastPulled[dockerImage] = time.Now()
+		}
+		pullsMu.Unlock()
+		if !pullNeeded(dockerImage, local, pulled, time.Now()) {
+			return nil
+		}
+	}
+
+	pullsMu.Lock()
+	if call, ok := inFlightPulls[dockerImage]; ok {
+		pullsMu.Unlock()
+		select {
+		case <-call.done:
+			return call.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	call := &pullCall{done: make(chan struct{})}
+	inFlightPulls[dockerImage] = call
+	pullsMu.Unlock()
+
+	call.err = doPullImage(ctx, cli, dockerImage)
+
+	pullsMu.Lock()
+	delete(inFlightPulls, dockerImage)
+	if call.err == nil {
+		lastPulled[dockerImage] = time.Now()
+	}
+	pullsMu.Unlock()
+	close(call.done)
+
+	return call.err
+}
+
+func doPullImage(ctx context.Context, cli *client.Client, dockerImage string) error {
+	select {
+	case pullSem <- struct{}{}:
+		defer func() { <-pullSem }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	reader, err := cli.ImagePull(ctx, dockerImage, image.PullOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to pull Docker image %s: %w", dockerImage, err)
+	}
+	defer reader.Close()
+
+	// The pull only completes once its progress output has been fully read
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return fmt.Errorf("failed to copy Docker image pull output: %w", err)
+	}
+	return nil
+}
+
+// allowedImages restricts the images a run may use instead of its language's
+// default image. An empty list allows any image.
+var allowedImages []string
+
+// SetAllowedImages restricts the image parameter to the given images. An
+// entry without a tag or digest allows every tag of that image, and an entry
+// ending in "*" allows every image starting with the rest, e.g. "my-org/*".
+// An empty list lifts the restriction.
+func SetAllowedImages(images []string) {
+	allowedImages = nil
+	for _, image := range images {
+		if image = strings.TrimSpace(image); image != "" {
+			allowedImages = append(allowedImages, image)
+		}
+	}
+}
+
+// validateImage checks that an image given by the caller is a well-formed
+// reference and, when images are restricted, on the allowlist
+func validateImage(name string) error {
+	ref, err := reference.ParseNormalizedNamed(name)
+	if err != nil {
+		return fmt.Errorf("invalid image %q: %w", name, err)
+	}
+	if len(allowedImages) == 0 {
+		return nil
+	}
+
+	// Entries may name the image as given, in its short form or fully
+	// qualified, e.g. "python", "python:3.12" or "docker.io/library/python"
+	candidates := []string{name, reference.FamiliarString(ref), ref.String(), reference.FamiliarName(ref), ref.Name()}
+	for _, allowed := range allowedImages {
+		prefix, wildcard := strings.CutSuffix(allowed, "*")
+		for _, candidate := range candidates {
+			if candidate == allowed || wildcard && strings.HasPrefix(candidate, prefix) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("image %s is not allowed on this server, allowed images are: %s", name, strings.Join(allowedImages, ", "))
+}
@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPullNeeded(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		image    string
+		local    bool
+		pulled   time.Time
+		interval time.Duration
+		want     bool
+	}{
+		{name: "missing image", image: "python:3.12", want: true, interval: DefaultImageRefreshInterval},
+		{name: "local image seen for the first time", image: "python:3.12", local: true, interval: DefaultImageRefreshInterval},
+		{name: "recently pulled", image: "my-org/py-ml:latest", local: true, pulled: now.Add(-time.Minute), interval: DefaultImageRefreshInterval},
+		{name: "due for a refresh", image: "my-org/py-ml:latest", local: true, pulled: now.Add(-time.Hour), interval: DefaultImageRefreshInterval, want: true},
+		{name: "refresh disabled", image: "my-org/py-ml:latest", local: true, pulled: now.Add(-time.Hour)},
+		{
+			name:     "digest never refreshed",
+			image:    "python@sha256:0000000000000000000000000000000000000000000000000000000000000000",
+			local:    true,
+			pulled:   now.Add(-time.Hour),
+			interval: DefaultImageRefreshInterval,
+		},
+	}
+
+	t.Cleanup(func() { SetImageRefreshInterval(DefaultImageRefreshInterval) })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetImageRefreshInterval(tt.interval)
+			if got := pullNeeded(tt.image, tt.local, tt.pulled, now); got != tt.want {
+				t.Errorf("pullNeeded() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	deps "github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+	"github.com/Automata-Labs-team/code-sandbox-mcp/resources"
+	"github.com/docker/docker/api/types/container"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/moby/moby/client"
+	"github.com/moby/moby/pkg/stdcopy"
+)
+
+// installedPackage is a package found installed in an image
+type installedPackage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// packageLister lists the packages of a language's ecosystem installed in an image
+type packageLister struct {
+	Cmd   []string
+	Parse func(output string) ([]installedPackage, error)
+}
+
+// packageListers holds the languages whose installed packages can be listed
+var packageListers = map[deps.Language]packageLister{
+	deps.Python: {
+		Cmd:   []string{"python3", "-m", "pip", "list", "--format=json", "--disable-pip-version-check"},
+		Parse: parsePipList,
+	},
+	// The default image runs Bun, which has no npm
+	deps.NodeJS:     nodePackageLister,
+	deps.TypeScript: nodePackageLister,
+	deps.Dart: {
+		Cmd:   []string{"dart", "pub", "global", "list"},
+		Parse: parseNameVersionLines,
+	},
+	deps.OCaml: {
+		Cmd:   []string{"opam", "list", "--short", "--columns=name,installed-version"},
+		Parse: parseNameVersionLines,
+	},
+}
+
+// nodePackageLister lists the global packages of a JavaScript or TypeScript image
+var nodePackageLister = packageLister{
+	Cmd:   []string{"/bin/sh", "-c", "npm ls -g --json --depth=0 2>/dev/null || bun pm ls -g"},
+	Parse: parseNodeList,
+}
+
+// InspectImage lists the packages installed in a language's image, or in a
+// custom image, by running the ecosystem's package manager in a throwaway container
+func InspectImage(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	language, _ := request.Params.Arguments["language"].(string)
+	parsed, err := deps.ParseLanguage(language)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	lister, ok := packageListers[parsed]
+	if !ok {
+		return mcp.NewToolResultError(fmt.Sprintf("listing installed packages is not supported for %s", parsed)), nil
+	}
+	image, _ := request.Params.Arguments["image"].(string)
+	if image == "" {
+		image = deps.SupportedLanguages[parsed].Image
+	}
+
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to create Docker client: %v", err)), nil
+	}
+	defer cli.Close()
+
+	if err := pullImage(ctx, cli, image, false); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	output, exitCode, err := runThrowawayContainer(ctx, cli, image, lister.Cmd, parsed)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+	}
+	if exitCode != 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("listing packages failed (exit code %d), the image may not have the %s package manager\n\nOutput: %s", exitCode, parsed, output)), nil
+	}
+
+	packages, err := lister.Parse(output)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to parse package list: %v\n\nOutput: %s", err, output)), nil
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Name < packages[j].Name })
+
+	result, err := json.MarshalIndent(struct {
+		Image    string             `json:"image"`
+		Language string             `json:"language"`
+		Packages []installedPackage `json:"packages"`
+	}{image, parsed.String(), packages}, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode package list: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+// runThrowawayContainer runs cmd in a new container of image, removes the
+// container and returns its combined output and exit code
+func runThrowawayContainer(ctx context.Context, cli *client.Client, image string, cmd []string, language deps.Language) (string, int64, error) {
+	resp, err := cli.ContainerCreate(ctx, &container.Config{
+		Image:  image,
+		Cmd:    cmd,
+		Labels: containerLabels("", language.String()),
+	}, &container.HostConfig{}, nil, nil, "")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create container: %w", err)
+	}
+	resources.RegisterContainer(resources.ActiveContainer{ContainerID: resp.ID, Language: language.String(), Image: image})
+	defer removeContainer(context.Background(), cli, resp.ID)
+
+	if err := cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return "", 0, fmt.Errorf("failed to start container: %w", err)
+	}
+
+	var exitCode int64
+	statusCh, errCh := cli.ContainerWait(ctx, resp.ID, container.WaitConditionNotRunning)
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to wait for container: %w", err)
+		}
+	case status := <-statusCh:
+		exitCode = status.StatusCode
+	}
+
+	out, err := cli.ContainerLogs(ctx, resp.ID, container.LogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get container logs: %w", err)
+	}
+	defer out.Close()
+
+	// Only stdout is parsed; stderr is kept for error messages
+	var stdout, stderr strings.Builder
+	if _, err := stdcopy.StdCopy(&stdout, &stderr, out); err != nil {
+		return "", 0, fmt.Errorf("failed to copy container output: %w", err)
+	}
+	if exitCode != 0 {
+		return stdout.String() + stderr.String(), exitCode, nil
+	}
+	return stdout.String(), exitCode, nil
+}
+
+// parsePipList parses the output of pip list --format=json
+func parsePipList(output string) ([]installedPackage, error) {
+	var packages []installedPackage
+	if err := json.Unmarshal([]byte(output), &packages); err != nil {
+		return nil, err
+	}
+	return packages, nil
+}
+
+// parseNodeList parses the JSON output of npm ls, or the tree bun pm ls prints
+func parseNodeList(output string) ([]installedPackage, error) {
+	var npm struct {
+		Dependencies map[string]struct {
+			Version string `json:"version"`
+		} `json:"dependencies"`
+	}
+	if err := json.Unmarshal([]byte(output), &npm); err == nil {
+		var packages []installedPackage
+		for name, dep := range npm.Dependencies {
+			packages = append(packages, installedPackage{Name: name, Version: dep.Version})
+		}
+		return packages, nil
+	}
+
+	// bun prints lines like "├── typescript@5.7.3"
+	var packages []installedPackage
+	for _, line := range strings.Split(output, "\n") {
+		_, spec, ok := strings.Cut(line, "── ")
+		if !ok {
+			continue
+		}
+		// Scoped packages start with @, so split at the last one
+		if i := strings.LastIndex(spec, "@"); i > 0 {
+			packages = append(packages, installedPackage{Name: spec[:i], Version: strings.TrimSpace(spec[i+1:])})
+		}
+	}
+	return packages, nil
+}
+
+// parseNameVersionLines parses lines of a package name followed by its version
+func parseNameVersionLines(output string) ([]installedPackage, error) {
+	var packages []installedPackage
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && !strings.HasPrefix(fields[0], "#") {
+			packages = append(packages, installedPackage{Name: fields[0], Version: fields[1]})
+		}
+	}
+	return packages, nil
+}
@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestParsePackageLists(t *testing.T) {
+	tests := []struct {
+		name   string
+		parse  func(string) ([]installedPackage, error)
+		output string
+		want   []installedPackage
+	}{
+		{
+			name:   "pip json",
+			parse:  parsePipList,
+			output: `[{"name": "pip", "version": "24.0"}, {"name": "numpy", "version": "2.1.0"}]`,
+			want:   []installedPackage{{"numpy", "2.1.0"}, {"pip", "24.0"}},
+		},
+		{
+			name:   "npm json",
+			parse:  parseNodeList,
+			output: `{"name": "lib", "dependencies": {"npm": {"version": "10.8.2"}, "corepack": {"version": "0.29.3"}}}`,
+			want:   []installedPackage{{"corepack", "0.29.3"}, {"npm", "10.8.2"}},
+		},
+		{
+			name:   "bun tree",
+			parse:  parseNodeList,
+			output: "/root/.bun/install/global node_modules (2)\n├── @types/node@22.1.0\n└── typescript@5.7.3\n",
+			want:   []installedPackage{{"@types/node", "22.1.0"}, {"typescript", "5.7.3"}},
+		},
+		{
+			name:   "bun with nothing installed",
+			parse:  parseNodeList,
+			output: "No packages installed\n",
+			want:   nil,
+		},
+		{
+			name:   "opam columns",
+			parse:  parseNameVersionLines,
+			output: "# Packages matching: installed\nbase-bigarray base\nocaml 5.2.0\n\n",
+			want:   []installedPackage{{"base-bigarray", "base"}, {"ocaml", "5.2.0"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parse(tt.output)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i].Name < got[j].Name })
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parsePipList("not json"); err == nil {
+		t.Error("expected an error for invalid pip output")
+	}
+}
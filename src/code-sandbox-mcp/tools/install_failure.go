@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// installFailedMarker is printed to stderr on its own line, followed by the
+// exit code and whether the run went on anyway, when the install step of a
+// run fails
+const installFailedMarker = "__code_sandbox_install_failed__"
+
+// installFailedRe matches the line installFailedMarker is printed on
+var installFailedRe = regexp.MustCompile(`(?m)^` + installFailedMarker + ` (\d+)( continued)?\n?`)
+
+// guardInstall wraps an install step so that its failure is announced with
+// installFailedMarker. The step's exit status is kept, so a run command
+// chained after it with && doesn't start, unless continueOnFailure is set.
+func guardInstall(install string, continueOnFailure bool) string {
+	if continueOnFailure {
+		return fmt.Sprintf(`{ %s; } || { echo "%s $? continued" >&2; }`, install, installFailedMarker)
+	}
+	return fmt.Sprintf(`{ %s; } || { rc=$?; echo "%s $rc" >&2; (exit $rc); }`, install, installFailedMarker)
+}
+
+// installFailure describes an install step that failed
+type installFailure struct {
+	ExitCode  int
+	Packages  []string // Packages the install output names as the cause, if any
+	Log       string   // Output of the install step
+	Continued bool     // The code ran without the dependencies
+}
+
+// splitInstallFailure looks for the report of a failed install step in the
+// combined output of a run. When there is one, it returns the failure, with
+// the output written before it as the install log, and the output the
+// program wrote after it.
+func splitInstallFailure(logs string) (installFailure, string, bool) {
+	loc := installFailedRe.FindStringSubmatchIndex(logs)
+	if loc == nil {
+		return installFailure{}, logs, false
+	}
+	exitCode, _ := strconv.Atoi(logs[loc[2]:loc[3]])
+	installLog := stripInstallMarkers(logs[:loc[0]])
+	return installFailure{
+		ExitCode:  exitCode,
+		Packages:  failedPackages(installLog),
+		Log:       installLog,
+		Continued: loc[4] >= 0,
+	}, logs[loc[1]:], true
+}
+
+// failedPackageRes match the errors package managers report for a package
+// they can't find or resolve, capturing its name
+var failedPackageRes = []*regexp.Regexp{
+	// pip
+	regexp.MustCompile(`No matching distribution found for ([^\s;]+)`),
+	regexp.MustCompile(`Could not find a version that satisfies the requirement ([^\s;]+)`),
+	// uv
+	regexp.MustCompile(`Because ([^\s,]+) was not found in the package registry`),
+	regexp.MustCompile(`Because there are no versions of ([^\s,]+)`),
+	// npm
+	regexp.MustCompile(`'(@?[^'@\s]+)@[^']*' is not in this registry`),
+	// Bun
+	regexp.MustCompile(`package "([^"]+)" not found`),
+	regexp.MustCompile(`error: (@?[^\s@]+)@\S* failed to resolve`),
+	// Go
+	regexp.MustCompile(`cannot find module providing package (\S+)`),
+	// cargo
+	regexp.MustCompile("no matching package named `([^`]+)` found"),
+}
+
+// failedPackages returns the packages an install log names as the cause of
+// the failure, in the order they first appear
+func failedPackages(installLog string) []string {
+	type found struct {
+		name string
+		at   int
+	}
+	var matches []found
+	for _, re := range failedPackageRes {
+		for _, m := range re.FindAllStringSubmatchIndex(installLog, -1) {
+			matches = append(matches, found{installLog[m[2]:m[3]], m[2]})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].at < matches[j].at })
+
+	var packages []string
+	seen := make(map[string]bool)
+	for _, m := range matches {
+		if !seen[m.name] {
+			seen[m.name] = true
+			packages = append(packages, m.name)
+		}
+	}
+	return packages
+}
+
+// summary explains the failure for a run result
+func (f installFailure) summary() string {
+	cause := ""
+	if len(f.Packages) > 0 {
+		cause = fmt.Sprintf(" for %s", strings.Join(f.Packages, ", "))
+	}
+	if f.Continued {
+		return fmt.Sprintf("Warning: installing dependencies failed%s (exit code %d), the code ran without them", cause, f.ExitCode)
+	}
+	return fmt.Sprintf("Error: installing dependencies failed%s (exit code %d), so the code was not run. "+
+		"Check the package names in the install log, or pass continueOnDepError: true to run the code anyway", cause, f.ExitCode)
+}
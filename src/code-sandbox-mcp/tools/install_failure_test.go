@@ -0,0 +1,134 @@
+package tools
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGuardInstall(t *testing.T) {
+	tests := []struct {
+		name              string
+		install           string
+		continueOnFailure bool
+		wantOutput        string
+		wantErr           bool
+	}{
+		{
+			name:       "successful install runs the code",
+			install:    "true",
+			wantOutput: "ran\n",
+		},
+		{
+			name:       "failed install stops the run with its exit code",
+			install:    "exit 3",
+			wantOutput: installFailedMarker + " 3\n",
+			wantErr:    true,
+		},
+		{
+			name:              "failed install can be continued",
+			install:           "exit 3",
+			continueOnFailure: true,
+			wantOutput:        installFailedMarker + " 3 continued\nran\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A subshell, so that "exit" in the install step ends it like a failing command
+			script := guardInstall("("+tt.install+")", tt.continueOnFailure) + " && echo ran"
+			out, err := exec.Command("/bin/sh", "-c", script+" 2>&1").CombinedOutput()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s: error = %v, wantErr %v", script, err, tt.wantErr)
+			}
+			if string(out) != tt.wantOutput {
+				t.Errorf("%s: output = %q, want %q", script, out, tt.wantOutput)
+			}
+			if tt.wantErr && err.(*exec.ExitError).ExitCode() != 3 {
+				t.Errorf("%s: exit code = %d, want 3", script, err.(*exec.ExitError).ExitCode())
+			}
+		})
+	}
+}
+
+func TestSplitInstallFailure(t *testing.T) {
+	logs := "Collecting reqests\nERROR: No matching distribution found for reqests\n" + installFailedMarker + " 1 continued\nhello\n"
+	failure, rest, ok := splitInstallFailure(logs)
+	if !ok {
+		t.Fatalf("splitInstallFailure(%q) found no failure", logs)
+	}
+	want := installFailure{
+		ExitCode:  1,
+		Packages:  []string{"reqests"},
+		Log:       "Collecting reqests\nERROR: No matching distribution found for reqests\n",
+		Continued: true,
+	}
+	if !reflect.DeepEqual(failure, want) {
+		t.Errorf("splitInstallFailure() failure = %+v, want %+v", failure, want)
+	}
+	if rest != "hello\n" {
+		t.Errorf("splitInstallFailure() rest = %q, want the program output", rest)
+	}
+	if !strings.Contains(failure.summary(), "the code ran without them") {
+		t.Errorf("summary() = %q, want it to say the code ran", failure.summary())
+	}
+
+	if _, rest, ok := splitInstallFailure("hello\n"); ok || rest != "hello\n" {
+		t.Errorf("splitInstallFailure() reported a failure for a run without one")
+	}
+}
+
+func TestFailedPackages(t *testing.T) {
+	tests := []struct {
+		name string
+		log  string
+		want []string
+	}{
+		{
+			name: "pip",
+			log: "ERROR: Could not find a version that satisfies the requirement reqests (from versions: none)\n" +
+				"ERROR: No matching distribution found for reqests\n",
+			want: []string{"reqests"},
+		},
+		{
+			name: "uv",
+			log: "  × No solution found when resolving dependencies:\n" +
+				"  ╰─▶ Because nonexistent-pkg was not found in the package registry and you require nonexistent-pkg, we can conclude that your requirements are unsatisfiable.\n",
+			want: []string{"nonexistent-pkg"},
+		},
+		{
+			name: "npm",
+			log:  "npm error 404 Not Found - GET https://registry.npmjs.org/lodahs - Not found\nnpm error 404  'lodahs@*' is not in this registry.\n",
+			want: []string{"lodahs"},
+		},
+		{
+			name: "scoped npm package",
+			log:  "npm error 404  '@types/nope@^1.0.0' is not in this registry.\n",
+			want: []string{"@types/nope"},
+		},
+		{
+			name: "cargo",
+			log:  "error: no matching package named `serdee` found\n",
+			want: []string{"serdee"},
+		},
+		{
+			name: "several packages in order",
+			log:  "No matching distribution found for b\nNo matching distribution found for a\nNo matching distribution found for b\n",
+			want: []string{"b", "a"},
+		},
+		{
+			name: "unrecognised failure",
+			log:  "error: externally-managed-environment\n",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := failedPackages(tt.log); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("failedPackages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
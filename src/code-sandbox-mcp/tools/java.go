@@ -0,0 +1,31 @@
+package tools
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var (
+	// javaPublicClassRe matches the top-level public type a source file must be named after
+	javaPublicClassRe = regexp.MustCompile(`(?m)^public\s+(?:(?:final|abstract|sealed|strictfp)\s+)*(?:class|interface|enum|record)\s+(\w+)`)
+	// javaClassRe matches any top-level class, for snippets without a public one
+	javaClassRe = regexp.MustCompile(`(?m)^(?:(?:final|abstract)\s+)*class\s+(\w+)`)
+)
+
+// javaMainClass returns the class a Java snippet's file has to be named after
+// and that is run: its public class, else its first class, else Main
+func javaMainClass(code string) string {
+	if match := javaPublicClassRe.FindStringSubmatch(code); match != nil {
+		return match[1]
+	}
+	if match := javaClassRe.FindStringSubmatch(code); match != nil {
+		return match[1]
+	}
+	return "Main"
+}
+
+// javaRun compiles a snippet saved as <class>.java and runs the class. Class
+// files are written outside the work directory.
+func javaRun(class string) []string {
+	return []string{"/bin/sh", "-c", fmt.Sprintf("javac -d /tmp/classes %s.java && java -cp /tmp/classes %s", class, class)}
+}
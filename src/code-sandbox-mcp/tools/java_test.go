@@ -0,0 +1,45 @@
+package tools
+
+import "testing"
+
+func TestJavaMainClass(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{
+			name: "public class",
+			code: "import java.util.*;\n\npublic class Hello {\n    public static void main(String[] args) {}\n}\n",
+			want: "Hello",
+		},
+		{
+			name: "public final class after a helper class",
+			code: "class Helper {}\n\npublic final class App {\n    public static void main(String[] args) {}\n}\n",
+			want: "App",
+		},
+		{
+			name: "no public class",
+			code: "class Solution {\n    public static void main(String[] args) {}\n}\n",
+			want: "Solution",
+		},
+		{
+			name: "nested public class is ignored",
+			code: "class Outer {\n    public class Inner {}\n}\n",
+			want: "Outer",
+		},
+		{
+			name: "no class",
+			code: "void main() {}\n",
+			want: "Main",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := javaMainClass(tt.code); got != tt.want {
+				t.Errorf("javaMainClass() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	deps "github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// languageInfo describes a supported language to clients
+type languageInfo struct {
+	Name            string   `json:"name"`
+	Image           string   `json:"image"`
+	FileExtension   string   `json:"fileExtension"`
+	DependencyFiles []string `json:"dependencyFiles"`
+}
+
+// supportedLanguages returns every configured language, sorted by name
+func supportedLanguages() []languageInfo {
+	infos := make([]languageInfo, 0, len(deps.SupportedLanguages))
+	for language, config := range deps.SupportedLanguages {
+		// Languages without dependency files list none rather than null
+		dependencyFiles := config.DependencyFiles
+		if dependencyFiles == nil {
+			dependencyFiles = []string{}
+		}
+		infos = append(infos, languageInfo{
+			Name:            language.String(),
+			Image:           config.Image,
+			FileExtension:   config.FileExtension,
+			DependencyFiles: dependencyFiles,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// ListSupportedLanguages returns the languages run_code and run_project
+// accept, with the image, file extension and dependency files of each
+func ListSupportedLanguages(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	result, err := json.MarshalIndent(struct {
+		Languages []languageInfo `json:"languages"`
+	}{supportedLanguages()}, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode languages: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(result)), nil
+}
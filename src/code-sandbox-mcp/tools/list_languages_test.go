@@ -0,0 +1,49 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	deps "github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestListSupportedLanguages(t *testing.T) {
+	result, err := ListSupportedLanguages(context.Background(), mcp.CallToolRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %v", result.Content)
+	}
+
+	var got struct {
+		Languages []languageInfo `json:"languages"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := deps.AllLanguages.ToArray()
+	sort.Strings(want)
+	var names []string
+	for _, info := range got.Languages {
+		names = append(names, info.Name)
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("languages = %v, want %v", names, want)
+	}
+
+	python := deps.SupportedLanguages[deps.Python]
+	for _, info := range got.Languages {
+		if info.Name != "python" {
+			continue
+		}
+		if info.Image != python.Image || info.FileExtension != "py" || !reflect.DeepEqual(info.DependencyFiles, python.DependencyFiles) {
+			t.Errorf("python = %+v, want image %s, extension py and dependency files %v", info, python.Image, python.DependencyFiles)
+		}
+	}
+}
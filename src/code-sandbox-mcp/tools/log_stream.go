@@ -0,0 +1,184 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/resources"
+	"github.com/docker/docker/api/types/container"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/moby/moby/client"
+	"github.com/moby/moby/pkg/stdcopy"
+)
+
+// streamLogLines controls whether container output is sent to the client line
+// by line while a run is in progress. It is only enabled for transports that
+// can deliver events in real time; other clients get the batched result only.
+var streamLogLines bool
+
+// SetLogStreaming enables or disables per-line log streaming
+func SetLogStreaming(enabled bool) {
+	streamLogLines = enabled
+}
+
+// TransportStreamsLogs reports whether a transport can deliver log
+// notifications while a tool call is still running. SSE and Streamable HTTP
+// clients can; stdio clients only get the batched result.
+func TransportStreamsLogs(transport string) bool {
+	return transport == "sse" || transport == "http"
+}
+
+// Output is sent in chunks so chatty programs don't flood the client: a
+// stream's pending lines go out every logFlushInterval, or sooner once
+// logChunkBytes have built up
+const (
+	logFlushInterval = 250 * time.Millisecond
+	logChunkBytes    = 4 * 1024
+)
+
+// logBatcher collects complete lines of output and sends them in chunks
+type logBatcher struct {
+	mu    sync.Mutex
+	lines []string
+	size  int
+	send  func(text string)
+}
+
+// add queues a line, sending the chunk right away if it has grown large enough
+func (b *logBatcher) add(line string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lines = append(b.lines, line)
+	b.size += len(line) + 1
+	if b.size >= logChunkBytes {
+		b.flushLocked()
+	}
+}
+
+// flush sends the queued lines, if any
+func (b *logBatcher) flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flushLocked()
+}
+
+func (b *logBatcher) flushLocked() {
+	if len(b.lines) == 0 {
+		return
+	}
+	b.send(strings.Join(b.lines, "\n"))
+	b.lines, b.size = nil, 0
+}
+
+// lineWriter splits written output into lines and emits each complete line
+type lineWriter struct {
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	emit func(line string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// Keep the incomplete line until the rest of it arrives
+			w.buf.Reset()
+			w.buf.WriteString(line)
+			return len(p), nil
+		}
+		w.emit(strings.TrimRight(line, "\r\n"))
+	}
+}
+
+// Flush emits any trailing output that did not end with a newline
+func (w *lineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.buf.Len() > 0 {
+		w.emit(w.buf.String())
+		w.buf.Reset()
+	}
+}
+
+// streamContainerLogs follows a container's output and sends it to the client
+// in chunks of lines as log message notifications keyed by container ID. The
+// returned channel is closed once the container exits or ctx is cancelled.
+func streamContainerLogs(ctx context.Context, mcpServer *server.MCPServer, containerID string) <-chan struct{} {
+	done := make(chan struct{})
+	if !streamLogLines || mcpServer == nil {
+		close(done)
+		return done
+	}
+
+	go func() {
+		defer close(done)
+
+		// The stream may outlive the caller, so it uses its own client
+		cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+		if err != nil {
+			return
+		}
+		defer cli.Close()
+
+		out, err := cli.ContainerLogs(ctx, containerID, container.LogsOptions{ShowStdout: true, ShowStderr: true, Follow: true})
+		if err != nil {
+			return
+		}
+		defer out.Close()
+
+		newBatcher := func(stream string) *logBatcher {
+			return &logBatcher{send: func(text string) {
+				_ = notifyClient(ctx, mcpServer, "notifications/message", map[string]interface{}{
+					"level":  "info",
+					"logger": containerID,
+					"data": map[string]interface{}{
+						"containerId": containerID,
+						"stream":      stream,
+						"text":        text,
+					},
+				})
+			}}
+		}
+		stdoutBatch, stderrBatch := newBatcher("stdout"), newBatcher("stderr")
+		newWriter := func(batch *logBatcher) *lineWriter {
+			return &lineWriter{emit: func(line string) {
+				// The end of the install step is bookkeeping, not output
+				if line != installedMarker && !strings.HasPrefix(line, installFailedMarker+" ") {
+					batch.add(resources.MaskRunSecrets(containerID, line))
+				}
+			}}
+		}
+		stdout, stderr := newWriter(stdoutBatch), newWriter(stderrBatch)
+
+		// Send whatever has built up at a steady pace until the output ends
+		copied := make(chan struct{})
+		go func() {
+			ticker := time.NewTicker(logFlushInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-copied:
+					return
+				case <-ticker.C:
+					stdoutBatch.flush()
+					stderrBatch.flush()
+				}
+			}
+		}()
+
+		_, _ = stdcopy.StdCopy(stdout, stderr, out)
+		close(copied)
+		stdout.Flush()
+		stderr.Flush()
+		stdoutBatch.flush()
+		stderrBatch.flush()
+	}()
+
+	return done
+}
@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLogBatcher(t *testing.T) {
+	var sent []string
+	b := &logBatcher{send: func(text string) { sent = append(sent, text) }}
+	w := &lineWriter{emit: b.add}
+
+	w.Write([]byte("first\nsec"))
+	w.Write([]byte("ond\nthird"))
+	if len(sent) != 0 {
+		t.Fatalf("sent %q before a flush, want lines held back", sent)
+	}
+	w.Flush()
+	b.flush()
+	if len(sent) != 1 || sent[0] != "first\nsecond\nthird" {
+		t.Fatalf("sent %q, want one chunk of all three lines", sent)
+	}
+
+	// A large burst goes out without waiting for the next flush
+	sent = nil
+	line := strings.Repeat("x", 1000)
+	for i := 0; i < 6; i++ {
+		b.add(line)
+	}
+	if len(sent) != 1 || strings.Count(sent[0], "\n") != 4 {
+		t.Errorf("sent %d chunks, want the burst sent as one chunk once it reached %d bytes", len(sent), logChunkBytes)
+	}
+	b.flush()
+	if len(sent) != 2 {
+		t.Errorf("sent %d chunks, want the remaining line flushed", len(sent))
+	}
+}
+
+func TestTransportStreamsLogs(t *testing.T) {
+	for transport, want := range map[string]bool{"sse": true, "http": true, "stdio": false} {
+		if got := TransportStreamsLogs(transport); got != want {
+			t.Errorf("TransportStreamsLogs(%q) = %t, want %t", transport, got, want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/resources"
+	"github.com/docker/docker/api/types/container"
+	"github.com/moby/moby/client"
+	"github.com/moby/moby/pkg/stdcopy"
+)
+
+// installedMarker is printed on its own line once the install step of a run
+// that is isolated afterwards has finished
+const installedMarker = "__code_sandbox_installed__"
+
+// waitForIsolation announces the end of the install step and then waits until
+// the server has disconnected every network interface but loopback. It gives
+// up after 30 seconds rather than run the program with network access.
+const waitForIsolation = "echo " + installedMarker + "; " +
+	"isolated() { for i in /sys/class/net/*; do [ \"${i##*/}\" = lo ] || return 1; done; }; " +
+	"n=0; until isolated; do n=$((n+1)); " +
+	"[ $n -le 300 ] || { echo 'failed to disable networking after installing dependencies' >&2; exit 1; }; " +
+	"sleep 0.1; done"
+
+// hasIsolationStep reports whether a container command waits to be isolated
+// after its install step
+func hasIsolationStep(cmd []string) bool {
+	return len(cmd) > 0 && strings.Contains(cmd[len(cmd)-1], installedMarker)
+}
+
+// isolateAfterInstall follows a container's output and disconnects it from
+// all its networks as soon as its install step reports that it has finished.
+// The time it did is recorded on the run, to tell install time from run time.
+func isolateAfterInstall(ctx context.Context, containerID string) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return
+	}
+	defer cli.Close()
+
+	out, err := cli.ContainerLogs(ctx, containerID, container.LogsOptions{ShowStdout: true, Follow: true})
+	if err != nil {
+		return
+	}
+	defer out.Close()
+
+	// Closing the reader stops the copy once the marker has been seen
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	go func() {
+		_, err := stdcopy.StdCopy(pw, io.Discard, out)
+		pw.CloseWithError(err)
+	}()
+
+	scanner := bufio.NewScanner(pr)
+	for scanner.Scan() {
+		if scanner.Text() == installedMarker {
+			resources.SetRunInstalled(containerID, time.Now())
+			disconnectNetworks(ctx, cli, containerID)
+			return
+		}
+	}
+}
+
+// disconnectNetworks disconnects a container from every network it is attached to
+func disconnectNetworks(ctx context.Context, cli *client.Client, containerID string) {
+	inspect, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil || inspect.NetworkSettings == nil {
+		return
+	}
+	for name := range inspect.NetworkSettings.Networks {
+		_ = cli.NetworkDisconnect(ctx, name, containerID, true)
+	}
+}
+
+// networkPlan picks the network mode of a run and whether it has to be
+// isolated once its install step is done. Runs without network access that
+// install dependencies start networked and are cut off before the program runs.
+func networkPlan(allowNetwork bool, cmd []string) (mode container.NetworkMode, isolate bool) {
+	if allowNetwork {
+		return "", false
+	}
+	if hasIsolationStep(cmd) {
+		return "", true
+	}
+	return "none", false
+}
+
+// networkErrorRe matches the errors common runtimes report when they can't
+// resolve or reach a host
+var networkErrorRe = regexp.MustCompile(`(?i)temporary failure in name resolution|name or service not known|no such host|getaddrinfo (ENOTFOUND|EAI_AGAIN)|network is unreachable|could not resolve host|failed host lookup|ConnectionRefused|Failed to establish a new connection`)
+
+// networkDisabledHint explains a network error in the output of a run
+// without network access, or returns "" when there is none
+func networkDisabledHint(logs string) string {
+	if !networkErrorRe.MatchString(logs) {
+		return ""
+	}
+	return "\n\nNetwork access is disabled for this run, which is likely why it couldn't reach a host. Pass allowNetwork: true if the code needs the network"
+}
+
+// stripInstallMarkers removes the lines marking the end of the install step,
+// or its failure, from logs
+func stripInstallMarkers(logs string) string {
+	logs = strings.Replace(logs, installedMarker+"\n", "", 1)
+	return installFailedRe.ReplaceAllString(logs, "")
+}
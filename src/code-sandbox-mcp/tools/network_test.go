@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWaitForIsolation(t *testing.T) {
+	// Fake network interface directories stand in for /sys/class/net
+	netDir := t.TempDir()
+	for _, iface := range []string{"lo", "eth0"} {
+		if err := os.Mkdir(filepath.Join(netDir, iface), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	script := strings.ReplaceAll(waitForIsolation, "/sys/class/net", netDir) + " && echo running"
+
+	cmd := exec.Command("/bin/sh", "-c", script)
+	var out strings.Builder
+	cmd.Stdout = &out
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	// The server disconnects the network once the marker shows up
+	if err := os.Remove(filepath.Join(netDir, "eth0")); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("script failed: %v", err)
+	}
+	if got := out.String(); got != installedMarker+"\nrunning\n" {
+		t.Errorf("output = %q, want the marker and then the run", got)
+	}
+}
+
+func TestNetworkDisabledHint(t *testing.T) {
+	failures := []string{
+		"requests.exceptions.ConnectionError: HTTPSConnectionPool(host='example.com', port=443): Max retries exceeded with url: / (Caused by NameResolutionError(\"Failed to resolve 'example.com' ([Errno -3] Temporary failure in name resolution)\"))",
+		"Error: getaddrinfo EAI_AGAIN registry.npmjs.org",
+		`Get "https://example.com": dial tcp: lookup example.com on 127.0.0.11:53: no such host`,
+	}
+	for _, logs := range failures {
+		if networkDisabledHint(logs) == "" {
+			t.Errorf("networkDisabledHint(%q) = \"\", want a hint", logs)
+		}
+	}
+	if hint := networkDisabledHint("Traceback: ZeroDivisionError: division by zero"); hint != "" {
+		t.Errorf("networkDisabledHint() = %q for an unrelated error", hint)
+	}
+
+	if got := stripInstallMarkers("installing\n" + installedMarker + "\nhello\n"); got != "installing\nhello\n" {
+		t.Errorf("stripInstallMarkers() = %q", got)
+	}
+}
@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	deps "github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+)
+
+// Node.js package managers a project can install its dependencies with
+const (
+	nodeBun  = "bun"
+	nodeNPM  = "npm"
+	nodeYarn = "yarn"
+	nodePNPM = "pnpm"
+)
+
+// NodeImage is the image Node.js projects using npm, yarn or pnpm run in,
+// since the default Bun image ships none of them
+const NodeImage = "node:22-bookworm-slim"
+
+// nodeLockfiles maps lockfiles to the package manager that writes them, in
+// the order they are checked
+var nodeLockfiles = []struct{ file, manager string }{
+	{"bun.lockb", nodeBun},
+	{"bun.lock", nodeBun},
+	{"pnpm-lock.yaml", nodePNPM},
+	{"yarn.lock", nodeYarn},
+	{"package-lock.json", nodeNPM},
+}
+
+// nodeEntrypointTools maps the first word of an entrypoint to the package
+// manager it belongs to
+var nodeEntrypointTools = map[string]string{
+	"bun":  nodeBun,
+	"bunx": nodeBun,
+	"npm":  nodeNPM,
+	"npx":  nodeNPM,
+	"yarn": nodeYarn,
+	"pnpm": nodePNPM,
+	"pnpx": nodePNPM,
+}
+
+// nodeInstallCommands install a Node.js project's dependencies with each package manager.
+// The Node image only ships pnpm through corepack.
+var nodeInstallCommands = map[string][]string{
+	nodeBun:  {"bun", "install"},
+	nodeNPM:  {"npm", "install"},
+	nodeYarn: {"yarn", "install"},
+	nodePNPM: {"corepack", "enable", "pnpm", "&&", "pnpm", "install"},
+}
+
+// detectNodePackageManager works out which package manager the Node.js
+// project in dir uses, from the packageManager field of its package.json,
+// then its lockfile, then the tool the entrypoint calls. Projects without any
+// of these use Bun. It also returns what the choice was based on.
+func detectNodePackageManager(dir string, entrypoint []string) (manager string, source string) {
+	if content, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+		var manifest struct {
+			PackageManager string `json:"packageManager"`
+		}
+		// The field looks like "pnpm@9.1.0"
+		if json.Unmarshal(content, &manifest) == nil && manifest.PackageManager != "" {
+			name, _, _ := strings.Cut(manifest.PackageManager, "@")
+			if _, ok := nodeInstallCommands[name]; ok {
+				return name, "package.json packageManager"
+			}
+		}
+	}
+
+	for _, lockfile := range nodeLockfiles {
+		if _, err := os.Stat(filepath.Join(dir, lockfile.file)); err == nil {
+			return lockfile.manager, lockfile.file
+		}
+	}
+
+	if len(entrypoint) > 0 {
+		if manager, ok := nodeEntrypointTools[entrypoint[0]]; ok {
+			return manager, "entrypoint"
+		}
+	}
+	return nodeBun, "default"
+}
+
+// nodeProjectImage returns the image a Node.js project using manager runs in
+func nodeProjectImage(manager string) string {
+	if manager == nodeBun || manager == "" {
+		return deps.SupportedLanguages[deps.NodeJS].Image
+	}
+	return NodeImage
+}
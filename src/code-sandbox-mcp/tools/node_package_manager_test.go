@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectNodePackageManager(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		entrypoint  []string
+		wantManager string
+		wantSource  string
+	}{
+		{
+			name:        "npm lockfile",
+			files:       map[string]string{"package.json": `{}`, "package-lock.json": `{}`},
+			entrypoint:  []string{"npm", "run", "build"},
+			wantManager: "npm",
+			wantSource:  "package-lock.json",
+		},
+		{
+			name:        "yarn lockfile",
+			files:       map[string]string{"package.json": `{}`, "yarn.lock": ""},
+			wantManager: "yarn",
+			wantSource:  "yarn.lock",
+		},
+		{
+			name:        "pnpm lockfile",
+			files:       map[string]string{"package.json": `{}`, "pnpm-lock.yaml": ""},
+			wantManager: "pnpm",
+			wantSource:  "pnpm-lock.yaml",
+		},
+		{
+			name:        "bun lockfile wins over the entrypoint",
+			files:       map[string]string{"package.json": `{}`, "bun.lockb": ""},
+			entrypoint:  []string{"npm", "start"},
+			wantManager: "bun",
+			wantSource:  "bun.lockb",
+		},
+		{
+			name:        "packageManager field wins over lockfiles",
+			files:       map[string]string{"package.json": `{"packageManager": "pnpm@9.1.0"}`, "package-lock.json": `{}`},
+			wantManager: "pnpm",
+			wantSource:  "package.json packageManager",
+		},
+		{
+			name:        "entrypoint without lockfile",
+			files:       map[string]string{"package.json": `{}`},
+			entrypoint:  []string{"npx", "vitest"},
+			wantManager: "npm",
+			wantSource:  "entrypoint",
+		},
+		{
+			name:        "defaults to bun",
+			files:       map[string]string{"package.json": `{}`},
+			entrypoint:  []string{"node", "index.js"},
+			wantManager: "bun",
+			wantSource:  "default",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			manager, source := detectNodePackageManager(dir, tt.entrypoint)
+			if manager != tt.wantManager || source != tt.wantSource {
+				t.Errorf("detectNodePackageManager() = %q, %q, want %q, %q", manager, source, tt.wantManager, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestNodeProjectImage(t *testing.T) {
+	if got := nodeProjectImage("bun"); got != "oven/bun:debian" {
+		t.Errorf("nodeProjectImage(bun) = %q, want the default Bun image", got)
+	}
+	for _, manager := range []string{"npm", "yarn", "pnpm"} {
+		if got := nodeProjectImage(manager); got != NodeImage {
+			t.Errorf("nodeProjectImage(%s) = %q, want %q", manager, got, NodeImage)
+		}
+	}
+}
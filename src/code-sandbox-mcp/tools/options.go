@@ -0,0 +1,410 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+	"github.com/Automata-Labs-team/code-sandbox-mcp/resources"
+	"github.com/docker/docker/api/types/container"
+)
+
+// DefaultStopTimeout is the number of seconds a stopping container gets to
+// shut down gracefully before it is killed
+const DefaultStopTimeout = 2
+
+// DefaultWorkDir is the in-container directory code and projects are mounted at
+const DefaultWorkDir = "/app"
+
+// dockerSocket is the host Docker socket mounted into containers that request Docker access
+const dockerSocket = "/var/run/docker.sock"
+
+// lockdown makes the server refuse options that weaken the sandbox
+var lockdown bool
+
+// SetLockdown enables or disables lockdown mode
+func SetLockdown(enabled bool) {
+	lockdown = enabled
+}
+
+// lockdownRefused names the first option in params that weakens the sandbox:
+// access to the host Docker socket, network access, a seccomp profile other
+// than the default or built-in restrictive one, or host files mounted into the
+// container. It returns "" when there is none.
+func lockdownRefused(params runOptionParams) string {
+	switch {
+	case params.AllowDockerAccess:
+		return "allowDockerAccess"
+	case params.AllowNetwork:
+		return "allowNetwork"
+	case params.SeccompProfile != "" && params.SeccompProfile != "default" && params.SeccompProfile != "restrictive":
+		return "a custom seccompProfile"
+	case params.ReadOnlyFiles != nil:
+		return "readOnlyFiles"
+	}
+	return ""
+}
+
+// runOptions holds the optional settings shared by run_code and run_project
+type runOptions struct {
+	OutputPath  string                    // Host directory artifacts are copied to
+	Overwrite   resources.OverwritePolicy // What to do with existing files in OutputPath
+	OutputGlobs []string                  // Artifacts copied to OutputPath, all when empty
+	SessionID   string                    // Session the run belongs to
+	StopTimeout int                       // Seconds between SIGTERM and SIGKILL when the container is stopped
+	Timeout     time.Duration             // Time the run may take, zero for the language's default
+	MemoryLimit int64                     // Memory cap in MB, zero for the language's default
+	CPULimit    float64                   // CPU cap in (fractional) CPUs, zero for the language's default
+	WorkDir     string                    // In-container directory the code or project is mounted at
+
+	ReadOnlyFiles []string // Read-only binds of single host files, in Docker bind format
+
+	SeccompProfile string // Seccomp profile JSON, empty for Docker's default profile
+	InstallCommand string // Replaces the generated dependency install step when set
+	ReturnCommand  bool   // Include the final container command in the result
+	OmitWarnings   bool   // Leave compiler and runtime warnings out of the result
+
+	// Mounts the host Docker socket into the container. This gives the code
+	// full control of the host's Docker daemon, which is equivalent to root on the host.
+	AllowDockerAccess bool
+
+	AllowNetwork bool // Give the container default networking instead of none
+
+	Image                 string // Image replacing the language's default image
+	SkipDependencyInstall bool   // The image already has the dependencies, so nothing is installed
+	ForcePull             bool   // Pull the image even when a local copy is recent enough
+	ContinueOnDepError    bool   // Run the code even when installing its dependencies fails
+
+	Env     []string          // Variables set in the container on top of the server's, as KEY=value
+	Secrets map[string]string // Written to files in a tmpfs at /run/secrets before the program starts
+
+	// run_code only
+	ArtifactPaths      []string // In-container directories collected from in addition to /artifacts
+	EphemeralArtifacts bool     // Keep artifacts out of the persistent store and expire them after a TTL
+	Stdin              string   // Written to the program's standard input, which is closed afterwards
+	FileExtension      string   // Extension the code is saved with, empty for the language's default
+	// Files written to the work directory next to the code, by relative name.
+	// Entrypoint is the one of them that is run, empty to run the code.
+	Files      map[string]string
+	Entrypoint string
+
+	// run_project only
+	DependencyFile  string   // Dependency manifest relative to the project, overrides auto-detection
+	PackageManager  string   // Node.js package manager the project's dependencies are installed with
+	FallbackImage   string   // Image used when the image of a pinned runtime version can't be pulled
+	EntrypointSteps []string // Commands run one after the other in place of a single entrypoint
+	ContinueOnError bool     // Keep running entrypoint steps after one fails
+}
+
+// parseRunOptions reads the optional run settings from the tool arguments
+func parseRunOptions(arguments map[string]interface{}) (runOptions, error) {
+	opts := runOptions{
+		StopTimeout: DefaultStopTimeout,
+		WorkDir:     DefaultWorkDir,
+	}
+	var params runOptionParams
+	if err := decodeParams(arguments, &params); err != nil {
+		return opts, err
+	}
+	if lockdown {
+		if option := lockdownRefused(params); option != "" {
+			return opts, fmt.Errorf("%s is disabled on this server", option)
+		}
+	}
+
+	opts.OutputPath = params.OutputPath
+	if opts.OutputPath != "" {
+		if err := checkHostPath("outputPath", opts.OutputPath); err != nil {
+			return opts, err
+		}
+	}
+	policy, err := resources.ParseOverwritePolicy(params.Overwrite)
+	if err != nil {
+		return opts, err
+	}
+	opts.Overwrite = policy
+
+	if params.OutputGlobs != nil {
+		if opts.OutputGlobs, err = parseOutputGlobs(params.OutputGlobs); err != nil {
+			return opts, err
+		}
+	}
+	if params.ArtifactPaths != nil {
+		if opts.ArtifactPaths, err = parseArtifactPaths(params.ArtifactPaths); err != nil {
+			return opts, err
+		}
+	}
+	if params.ReadOnlyFiles != nil {
+		if opts.ReadOnlyFiles, err = parseReadOnlyFiles(params.ReadOnlyFiles); err != nil {
+			return opts, err
+		}
+	}
+	if params.EntrypointSteps != nil {
+		if opts.EntrypointSteps, err = parseEntrypointSteps(params.EntrypointSteps); err != nil {
+			return opts, err
+		}
+	}
+	opts.ContinueOnError = params.ContinueOnError
+	if params.RetainArtifacts != nil {
+		opts.EphemeralArtifacts = !*params.RetainArtifacts
+	}
+	opts.SessionID = params.SessionID
+
+	if params.StopTimeout != nil {
+		if *params.StopTimeout < 0 {
+			return opts, fmt.Errorf("stopTimeout must be a non-negative number of seconds")
+		}
+		opts.StopTimeout = int(*params.StopTimeout)
+	}
+
+	if params.Timeout != nil {
+		if *params.Timeout <= 0 {
+			return opts, fmt.Errorf("timeout must be a positive number of seconds")
+		}
+		opts.Timeout = time.Duration(*params.Timeout * float64(time.Second))
+	}
+
+	if params.MemoryLimitMB != nil {
+		// Docker refuses memory limits below 6MB
+		if *params.MemoryLimitMB < 6 {
+			return opts, fmt.Errorf("memoryLimitMB must be at least 6")
+		}
+		opts.MemoryLimit = int64(*params.MemoryLimitMB)
+	}
+	if params.CPULimit != nil {
+		if *params.CPULimit <= 0 {
+			return opts, fmt.Errorf("cpuLimit must be a positive number of CPUs")
+		}
+		opts.CPULimit = *params.CPULimit
+	}
+
+	if workDir := params.WorkDir; workDir != "" {
+		// Container paths are always Linux paths, so use path rather than filepath
+		workDir = path.Clean(workDir)
+		if !path.IsAbs(workDir) {
+			return opts, fmt.Errorf("workDir must be an absolute path: %s", workDir)
+		}
+		if workDir == "/" || workDir == "/artifacts" {
+			return opts, fmt.Errorf("workDir cannot be %s", workDir)
+		}
+		opts.WorkDir = workDir
+	}
+
+	if params.SeccompProfile != "" {
+		if opts.SeccompProfile, err = loadSeccompProfile(params.SeccompProfile); err != nil {
+			return opts, err
+		}
+	}
+
+	if params.InstallCommand != nil {
+		if err := validateInstallCommand(*params.InstallCommand); err != nil {
+			return opts, err
+		}
+		opts.InstallCommand = strings.TrimSpace(*params.InstallCommand)
+	}
+
+	opts.ReturnCommand = params.ReturnCommand
+	if params.IncludeWarnings != nil {
+		opts.OmitWarnings = !*params.IncludeWarnings
+	}
+
+	opts.AllowDockerAccess = params.AllowDockerAccess
+	opts.AllowNetwork = params.AllowNetwork
+	opts.ForcePull = params.ForcePull
+	opts.ContinueOnDepError = params.ContinueOnDepError
+	if params.Env != nil {
+		if opts.Env, err = parseEnv(params.Env); err != nil {
+			return opts, err
+		}
+	}
+	if params.Secrets != nil {
+		if opts.Secrets, err = parseSecrets(params.Secrets); err != nil {
+			return opts, err
+		}
+	}
+
+	if params.Image != "" {
+		if err := validateImage(params.Image); err != nil {
+			return opts, err
+		}
+		opts.Image = params.Image
+	}
+	if params.SkipInstall {
+		if opts.Image == "" {
+			return opts, fmt.Errorf("skipDependencyInstall needs an image that already has the dependencies")
+		}
+		if opts.InstallCommand != "" {
+			return opts, fmt.Errorf("installCommand and skipDependencyInstall cannot be used together")
+		}
+		opts.SkipDependencyInstall = true
+	}
+
+	return opts, nil
+}
+
+// stringList reads a list parameter given either as a comma-separated string
+// or as a list of strings. Entries are trimmed and empty entries dropped.
+func stringList(name string, value interface{}) ([]string, error) {
+	var items []string
+	switch v := value.(type) {
+	case string:
+		items = strings.Split(v, ",")
+	case []interface{}:
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be strings", name)
+			}
+			items = append(items, s)
+		}
+	default:
+		return nil, fmt.Errorf("%s must be a comma-separated string or a list of strings", name)
+	}
+
+	var list []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list, nil
+}
+
+// stringMap reads an object parameter of string values, given either as an
+// object or as a JSON-encoded object for clients that only send strings
+func stringMap(name string, value interface{}) (map[string]string, error) {
+	var raw map[string]interface{}
+	switch v := value.(type) {
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return nil, nil
+		}
+		if err := json.Unmarshal([]byte(v), &raw); err != nil {
+			return nil, fmt.Errorf("%s must be a JSON object: %v", name, err)
+		}
+	case map[string]interface{}:
+		raw = v
+	default:
+		return nil, fmt.Errorf("%s must be an object", name)
+	}
+
+	m := make(map[string]string, len(raw))
+	for key, value := range raw {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s value of %s must be a string", name, key)
+		}
+		m[key] = s
+	}
+	return m, nil
+}
+
+// parseOutputGlobs accepts output globs as a comma-separated string or a list
+// of strings and checks that every pattern is valid
+func parseOutputGlobs(value interface{}) ([]string, error) {
+	patterns, err := stringList("outputGlobs", value)
+	if err != nil {
+		return nil, err
+	}
+
+	var globs []string
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid output glob %q: %w", pattern, err)
+		}
+		globs = append(globs, pattern)
+	}
+	return globs, nil
+}
+
+// parseArtifactPaths reads the extra in-container directories artifacts are
+// collected from, which must be absolute
+func parseArtifactPaths(value interface{}) ([]string, error) {
+	dirs, err := stringList("artifactPaths", value)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, dir := range dirs {
+		// Container paths are always Linux paths, so use path rather than filepath
+		dir = path.Clean(dir)
+		if !path.IsAbs(dir) {
+			return nil, fmt.Errorf("artifactPaths must be absolute paths: %s", dir)
+		}
+		if dir == "/" || dir == "/artifacts" {
+			continue
+		}
+		paths = append(paths, dir)
+	}
+	return paths, nil
+}
+
+// installCommandForbidden lists characters that would let an install command
+// chain, substitute or redirect other commands in the shell it runs in
+const installCommandForbidden = ";&|`$<>\\\n\r\x00"
+
+// validateInstallCommand checks that a custom install command is a single
+// plain command, since it is run through a shell ahead of the run command
+func validateInstallCommand(installCommand string) error {
+	if strings.TrimSpace(installCommand) == "" {
+		return fmt.Errorf("installCommand must not be empty")
+	}
+	if i := strings.IndexAny(installCommand, installCommandForbidden); i >= 0 {
+		return fmt.Errorf("installCommand contains a disallowed character %q", installCommand[i])
+	}
+	return nil
+}
+
+// rebaseWorkDir rewrites command arguments that refer to the default work
+// directory so they point at workDir instead
+func rebaseWorkDir(cmd []string, workDir string) []string {
+	if workDir == DefaultWorkDir {
+		return cmd
+	}
+	rebased := make([]string, len(cmd))
+	for i, arg := range cmd {
+		if arg == DefaultWorkDir || strings.HasPrefix(arg, DefaultWorkDir+"/") {
+			arg = workDir + strings.TrimPrefix(arg, DefaultWorkDir)
+		}
+		rebased[i] = arg
+	}
+	return rebased
+}
+
+// timeout returns how long a run in language may take. An explicit timeout
+// always wins over the language default.
+func (o runOptions) timeout(language languages.Language) time.Duration {
+	if o.Timeout > 0 {
+		return o.Timeout
+	}
+	return languages.SupportedLanguages[language].DefaultTimeout
+}
+
+// resources returns the memory and CPU limits for a run in language. Limits
+// set by the caller win over the language defaults.
+func (o runOptions) resources(language languages.Language) container.Resources {
+	config := languages.SupportedLanguages[language]
+	memoryMB, cpus := config.DefaultMemoryMB, config.DefaultCPU
+	if o.MemoryLimit > 0 {
+		memoryMB = o.MemoryLimit
+	}
+	if o.CPULimit > 0 {
+		cpus = o.CPULimit
+	}
+	return container.Resources{
+		Memory:   memoryMB * 1024 * 1024,
+		NanoCPUs: int64(cpus * 1e9),
+	}
+}
+
+// securityOpts returns the HostConfig security options for a run
+func (o runOptions) securityOpts() []string {
+	if o.SeccompProfile == "" {
+		return nil
+	}
+	return []string{"seccomp=" + o.SeccompProfile}
+}
@@ -0,0 +1,267 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+)
+
+func TestParseRunOptionsInstallCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       interface{}
+		want        string
+		errContains string
+	}{
+		{
+			name:  "plain command",
+			value: "  uv pip install --system --no-deps -r requirements.txt ",
+			want:  "uv pip install --system --no-deps -r requirements.txt",
+		},
+		{
+			name:  "constraints file",
+			value: "pip install -c constraints.txt -r requirements.txt",
+			want:  "pip install -c constraints.txt -r requirements.txt",
+		},
+		{
+			name:        "empty",
+			value:       "   ",
+			errContains: "must not be empty",
+		},
+		{
+			name:        "not a string",
+			value:       42.0,
+			errContains: "must be a string",
+		},
+		{
+			name:        "chained command",
+			value:       "pip install requests && curl example.com",
+			errContains: "disallowed character",
+		},
+		{
+			name:        "command substitution",
+			value:       "pip install $(cat reqs)",
+			errContains: "disallowed character",
+		},
+		{
+			name:        "newline",
+			value:       "pip install requests\nrm -rf /",
+			errContains: "disallowed character",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseRunOptions(map[string]interface{}{"installCommand": tt.value})
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("parseRunOptions() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRunOptions() unexpected error: %v", err)
+			}
+			if opts.InstallCommand != tt.want {
+				t.Errorf("InstallCommand = %q, want %q", opts.InstallCommand, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRunOptionsImage(t *testing.T) {
+	SetAllowedImages([]string{"python", "my-org/*", "node:22-bookworm-slim"})
+	t.Cleanup(func() { SetAllowedImages(nil) })
+
+	tests := []struct {
+		name        string
+		arguments   map[string]interface{}
+		wantSkip    bool
+		errContains string
+	}{
+		{name: "any tag of an allowed image", arguments: map[string]interface{}{"image": "python:3.13-slim"}},
+		{name: "fully qualified allowed image", arguments: map[string]interface{}{"image": "docker.io/library/python:3.12"}},
+		{name: "prefix entry", arguments: map[string]interface{}{"image": "my-org/py-ml:latest", "skipDependencyInstall": true}, wantSkip: true},
+		{name: "exact tag entry", arguments: map[string]interface{}{"image": "node:22-bookworm-slim"}},
+		{
+			name:        "other tag of an exact tag entry",
+			arguments:   map[string]interface{}{"image": "node:23"},
+			errContains: "not allowed on this server",
+		},
+		{
+			name:        "image not on the allowlist",
+			arguments:   map[string]interface{}{"image": "ubuntu:24.04"},
+			errContains: "not allowed on this server",
+		},
+		{
+			name:        "malformed image",
+			arguments:   map[string]interface{}{"image": "Python:3.12"},
+			errContains: "invalid image",
+		},
+		{
+			name:        "skip install without an image",
+			arguments:   map[string]interface{}{"skipDependencyInstall": true},
+			errContains: "needs an image",
+		},
+		{
+			name:        "skip install with an install command",
+			arguments:   map[string]interface{}{"image": "python:3.12", "skipDependencyInstall": true, "installCommand": "pip install requests"},
+			errContains: "cannot be used together",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseRunOptions(tt.arguments)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("parseRunOptions() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRunOptions() unexpected error: %v", err)
+			}
+			if opts.Image != tt.arguments["image"] || opts.SkipDependencyInstall != tt.wantSkip {
+				t.Errorf("Image = %q, SkipDependencyInstall = %t, want %q, %t", opts.Image, opts.SkipDependencyInstall, tt.arguments["image"], tt.wantSkip)
+			}
+		})
+	}
+}
+
+func TestRunOptionsTimeout(t *testing.T) {
+	opts, err := parseRunOptions(map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := opts.timeout(languages.Go), languages.SupportedLanguages[languages.Go].DefaultTimeout; got != want {
+		t.Errorf("default timeout = %v, want language default %v", got, want)
+	}
+
+	opts, err = parseRunOptions(map[string]interface{}{"timeout": 1.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := opts.timeout(languages.Go), 1500*time.Millisecond; got != want {
+		t.Errorf("explicit timeout = %v, want %v", got, want)
+	}
+
+	for _, value := range []interface{}{0.0, -1.0, "30"} {
+		if _, err := parseRunOptions(map[string]interface{}{"timeout": value}); err == nil {
+			t.Errorf("parseRunOptions(timeout=%v) expected an error", value)
+		}
+	}
+}
+
+func TestParseRunOptionsOutputGlobs(t *testing.T) {
+	opts, err := parseRunOptions(map[string]interface{}{"outputGlobs": " *.png, report.csv ,"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"*.png", "report.csv"}; strings.Join(opts.OutputGlobs, "|") != strings.Join(want, "|") {
+		t.Errorf("OutputGlobs = %q, want %q", opts.OutputGlobs, want)
+	}
+
+	opts, err = parseRunOptions(map[string]interface{}{"outputGlobs": []interface{}{"*.svg"}})
+	if err != nil || len(opts.OutputGlobs) != 1 || opts.OutputGlobs[0] != "*.svg" {
+		t.Errorf("list form: OutputGlobs = %q, err = %v", opts.OutputGlobs, err)
+	}
+
+	if _, err := parseRunOptions(map[string]interface{}{"outputGlobs": "[a-"}); err == nil || !strings.Contains(err.Error(), "invalid output glob") {
+		t.Errorf("expected invalid glob error, got %v", err)
+	}
+}
+
+func TestRunOptionsResources(t *testing.T) {
+	opts, err := parseRunOptions(map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := opts.resources(languages.Go)
+	config := languages.SupportedLanguages[languages.Go]
+	if got.Memory != config.DefaultMemoryMB*1024*1024 || got.NanoCPUs != int64(config.DefaultCPU*1e9) {
+		t.Errorf("default resources = %d bytes, %d nano CPUs, want the Go defaults", got.Memory, got.NanoCPUs)
+	}
+
+	opts, err = parseRunOptions(map[string]interface{}{"memoryLimitMB": 256.0, "cpuLimit": 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = opts.resources(languages.Go)
+	if got.Memory != 256*1024*1024 || got.NanoCPUs != 500000000 {
+		t.Errorf("explicit resources = %d bytes, %d nano CPUs, want 256MB and 0.5 CPUs", got.Memory, got.NanoCPUs)
+	}
+
+	for _, arguments := range []map[string]interface{}{{"memoryLimitMB": 1.0}, {"cpuLimit": 0.0}, {"cpuLimit": "2"}} {
+		if _, err := parseRunOptions(arguments); err == nil {
+			t.Errorf("parseRunOptions(%v) succeeded, want error", arguments)
+		}
+	}
+}
+
+func TestParseRunOptionsLockdown(t *testing.T) {
+	defer SetLockdown(false)
+	SetLockdown(true)
+
+	tests := []struct {
+		name        string
+		arguments   map[string]interface{}
+		errContains string
+	}{
+		{
+			name:      "no weakening options",
+			arguments: map[string]interface{}{"timeout": 5.0},
+		},
+		{
+			name:      "restrictive seccomp profile",
+			arguments: map[string]interface{}{"seccompProfile": "restrictive"},
+		},
+		{
+			name:      "default seccomp profile",
+			arguments: map[string]interface{}{"seccompProfile": "default"},
+		},
+		{
+			name:        "docker access",
+			arguments:   map[string]interface{}{"allowDockerAccess": true},
+			errContains: "allowDockerAccess is disabled",
+		},
+		{
+			name:        "network access",
+			arguments:   map[string]interface{}{"allowNetwork": true},
+			errContains: "allowNetwork is disabled",
+		},
+		{
+			name:        "custom seccomp profile",
+			arguments:   map[string]interface{}{"seccompProfile": "/etc/seccomp/allow-all.json"},
+			errContains: "custom seccompProfile is disabled",
+		},
+		{
+			name:        "host file mounts",
+			arguments:   map[string]interface{}{"readOnlyFiles": "/etc/hosts:/etc/hosts"},
+			errContains: "readOnlyFiles is disabled",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseRunOptions(tt.arguments)
+			if tt.errContains == "" {
+				if err != nil {
+					t.Errorf("parseRunOptions() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("parseRunOptions() error = %v, want it to contain %q", err, tt.errContains)
+			}
+		})
+	}
+
+	// Without lockdown the same options are accepted
+	SetLockdown(false)
+	if _, err := parseRunOptions(map[string]interface{}{"allowNetwork": true}); err != nil {
+		t.Errorf("parseRunOptions() without lockdown: %v", err)
+	}
+}
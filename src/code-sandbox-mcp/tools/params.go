@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+)
+
+// runCodeParams are the run_code arguments not shared with run_project
+type runCodeParams struct {
+	Code       string      `json:"code"`
+	FilePath   string      `json:"filePath"`
+	Files      interface{} `json:"files"` // Object or JSON-encoded object, see parseSnippetFiles
+	Entrypoint string      `json:"entrypoint"`
+	Language   string      `json:"language" required:"true"`
+	Steps      float64     `json:"steps"` // Total reported in progress notifications
+	Stdin      string      `json:"stdin"`
+}
+
+// runProjectParams are the run_project arguments not shared with run_code
+type runProjectParams struct {
+	Language              string `json:"language" required:"true"`
+	EntrypointCmd         string `json:"entrypointCmd"`
+	ProjectDir            string `json:"projectDir"`
+	ProjectArchive        string `json:"projectArchive"`
+	DependencyFile        string `json:"dependencyFile"`
+	CleanProjectArtifacts bool   `json:"cleanProjectArtifacts"`
+}
+
+// runOptionParams are the optional arguments shared by run_code and
+// run_project. Pointers tell an omitted argument apart from its zero value,
+// and parameters that accept a string or a list are decoded later.
+type runOptionParams struct {
+	OutputPath         string      `json:"outputPath"`
+	Overwrite          string      `json:"overwrite"`
+	OutputGlobs        interface{} `json:"outputGlobs"`
+	ArtifactPaths      interface{} `json:"artifactPaths"`
+	RetainArtifacts    *bool       `json:"retainArtifacts"`
+	ReadOnlyFiles      interface{} `json:"readOnlyFiles"`
+	SessionID          string      `json:"sessionId"`
+	StopTimeout        *float64    `json:"stopTimeout"`
+	Timeout            *float64    `json:"timeout"`
+	MemoryLimitMB      *float64    `json:"memoryLimitMB"`
+	CPULimit           *float64    `json:"cpuLimit"`
+	WorkDir            string      `json:"workDir"`
+	SeccompProfile     string      `json:"seccompProfile"`
+	InstallCommand     *string     `json:"installCommand"`
+	ReturnCommand      bool        `json:"returnCommand"`
+	IncludeWarnings    *bool       `json:"includeWarnings"`
+	AllowDockerAccess  bool        `json:"allowDockerAccess"`
+	AllowNetwork       bool        `json:"allowNetwork"`
+	EntrypointSteps    interface{} `json:"entrypointSteps"`
+	ContinueOnError    bool        `json:"continueOnError"`
+	Image              string      `json:"image"`
+	SkipInstall        bool        `json:"skipDependencyInstall"`
+	ContinueOnDepError bool        `json:"continueOnDepError"`
+	ForcePull          bool        `json:"forcePull"`
+	Env                interface{} `json:"env"`
+	Secrets            interface{} `json:"secrets"`
+}
+
+// languageConfig parses a language argument and returns its configuration.
+// A name that parses but has no configuration is reported instead of
+// running with an empty image and command.
+func languageConfig(name string) (languages.Language, languages.LanguageConfig, error) {
+	language, err := languages.ParseLanguage(name)
+	if err != nil {
+		return "", languages.LanguageConfig{}, err
+	}
+	config, ok := languages.SupportedLanguages[language]
+	if !ok {
+		return "", languages.LanguageConfig{}, fmt.Errorf("language %q is not supported, supported languages are: %s",
+			name, strings.Join(languages.AllLanguages.ToArray(), ", "))
+	}
+	return language, config, nil
+}
+
+// decodeParams unmarshals tool arguments into the struct params points to.
+// Mistyped arguments and missing arguments tagged required:"true" are reported
+// by name instead of silently falling back to a default.
+func decodeParams(arguments map[string]interface{}, params interface{}) error {
+	data, err := json.Marshal(arguments)
+	if err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	if err := json.Unmarshal(data, params); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return fmt.Errorf("%s must be %s, got %s", typeErr.Field, jsonTypeName(typeErr.Type), typeErr.Value)
+		}
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	v := reflect.ValueOf(params).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Tag.Get("required") != "true" {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if _, ok := arguments[name]; !ok || v.Field(i).IsZero() {
+			return fmt.Errorf("%s is required", name)
+		}
+	}
+	return nil
+}
+
+// jsonTypeName describes the JSON type expected for a Go type
+func jsonTypeName(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Float32, reflect.Float64, reflect.Int, reflect.Int64:
+		return "a number"
+	case reflect.Slice:
+		return "a list"
+	default:
+		return "an object"
+	}
+}
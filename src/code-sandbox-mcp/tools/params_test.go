@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestDecodeParams(t *testing.T) {
+	tests := []struct {
+		name        string
+		arguments   map[string]interface{}
+		errContains string
+	}{
+		{name: "valid", arguments: map[string]interface{}{"code": "print(1)", "language": "python", "steps": 10.0}},
+		{name: "missing required", arguments: map[string]interface{}{"code": "print(1)"}, errContains: "language is required"},
+		{name: "empty required", arguments: map[string]interface{}{"code": "print(1)", "language": ""}, errContains: "language is required"},
+		{name: "mistyped", arguments: map[string]interface{}{"code": "print(1)", "language": "python", "steps": "ten"}, errContains: "steps must be a number, got string"},
+		{name: "mistyped required", arguments: map[string]interface{}{"code": 42.0, "language": "python"}, errContains: "code must be a string, got number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var params runCodeParams
+			err := decodeParams(tt.arguments, &params)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("decodeParams() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeParams() unexpected error: %v", err)
+			}
+			if params.Code != "print(1)" || params.Language != "python" || params.Steps != 10 {
+				t.Errorf("decodeParams() = %+v", params)
+			}
+		})
+	}
+}
+
+func TestParseRunOptionsMistyped(t *testing.T) {
+	for name, value := range map[string]interface{}{
+		"outputPath":     true,
+		"sessionId":      1.0,
+		"timeout":        "30",
+		"returnCommand":  "yes",
+		"workDir":        []interface{}{"/src"},
+		"seccompProfile": 1.0,
+	} {
+		_, err := parseRunOptions(map[string]interface{}{name: value})
+		if err == nil || !strings.HasPrefix(err.Error(), name+" must be") {
+			t.Errorf("parseRunOptions(%s: %v) error = %v, want %s must be ...", name, value, err, name)
+		}
+	}
+}
+
+func TestUnsupportedLanguage(t *testing.T) {
+	tools := map[string]func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error){
+		"run_code":    RunCodeSandbox,
+		"run_project": RunProjectSandbox,
+	}
+	for name, tool := range tools {
+		t.Run(name, func(t *testing.T) {
+			request := mcp.CallToolRequest{}
+			request.Params.Arguments = map[string]interface{}{
+				"language":   "cobol",
+				"code":       "DISPLAY 'HELLO'.",
+				"projectDir": t.TempDir(),
+			}
+			result, err := tool(context.Background(), request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !result.IsError {
+				t.Fatal("expected an error result")
+			}
+			text := result.Content[0].(mcp.TextContent).Text
+			for _, want := range []string{`unsupported language "cobol"`, "python, go, nodejs"} {
+				if !strings.Contains(text, want) {
+					t.Errorf("error %q does not contain %q", text, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/streamable"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// notifyClient sends a notification to the client of the request ctx belongs
+// to. Requests served over Streamable HTTP carry their own way to reach the
+// client, the others go through the MCP server.
+func notifyClient(ctx context.Context, srv *server.MCPServer, method string, params map[string]interface{}) error {
+	if notify, ok := streamable.NotifierFromContext(ctx); ok {
+		return notify(method, params)
+	}
+	return srv.SendNotificationToClient(method, params)
+}
+
+// runPhase is a step of a sandboxed run reported to the client as progress
+type runPhase struct {
+	progress int
+	message  string
+}
+
+// Phases of a run, in the order they happen
+var (
+	phasePullingImage  = runPhase{10, "Pulling image"}
+	phasePreparingDeps = runPhase{30, "Preparing dependencies"}
+	phaseStarting      = runPhase{50, "Starting container"}
+	phaseRunning       = runPhase{70, "Running"}
+	phaseCollecting    = runPhase{90, "Collecting artifacts"}
+	phaseDone          = runPhase{100, "Done"}
+)
+
+// progressReporter sends phase-based progress notifications for a request
+type progressReporter struct {
+	ctx    context.Context
+	server *server.MCPServer
+	token  mcp.ProgressToken
+}
+
+func newProgressReporter(ctx context.Context, token mcp.ProgressToken) *progressReporter {
+	return &progressReporter{
+		ctx:    ctx,
+		server: server.ServerFromContext(ctx),
+		token:  token,
+	}
+}
+
+// report notifies the client that the run has entered a phase. Progress is
+// best effort, so delivery failures are ignored.
+func (r *progressReporter) report(phase runPhase) {
+	if r.server == nil || r.token == nil || r.token == "" {
+		return
+	}
+	_ = notifyClient(r.ctx, r.server,
+		"notifications/progress",
+		map[string]interface{}{
+			"progress":      phase.progress,
+			"total":         100,
+			"message":       phase.message,
+			"progressToken": r.token,
+		},
+	)
+}
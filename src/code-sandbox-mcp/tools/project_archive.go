@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Limits on projects uploaded as archives. The extracted limit guards against
+// archives that compress far better than any real project does.
+const (
+	maxProjectArchiveBytes   = 50 * 1024 * 1024
+	maxProjectExtractedBytes = 500 * 1024 * 1024
+)
+
+// extractProjectArchive decodes a base64-encoded tar archive, optionally
+// gzip-compressed, into a new temporary directory and returns its path.
+// The caller is responsible for removing the directory.
+func extractProjectArchive(encoded string) (string, error) {
+	if base64.StdEncoding.DecodedLen(len(encoded)) > maxProjectArchiveBytes+2 {
+		return "", fmt.Errorf("projectArchive exceeds the limit of %d bytes", maxProjectArchiveBytes)
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("projectArchive is not valid base64: %w", err)
+	}
+	if len(data) > maxProjectArchiveBytes {
+		return "", fmt.Errorf("projectArchive exceeds the limit of %d bytes", maxProjectArchiveBytes)
+	}
+
+	var archive io.Reader = bytes.NewReader(data)
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		gz, err := gzip.NewReader(archive)
+		if err != nil {
+			return "", fmt.Errorf("projectArchive is not a valid gzip stream: %w", err)
+		}
+		defer gz.Close()
+		archive = gz
+	}
+
+	dir, err := os.MkdirTemp("", "docker-sandbox-project-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	if err := extractTar(tar.NewReader(archive), dir); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// extractTar writes the directories and regular files of an archive below dir,
+// keeping their permission bits. Links and special files are rejected, since
+// the tree is also read on the host during dependency detection.
+func extractTar(tr *tar.Reader, dir string) error {
+	var extracted int64
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read projectArchive: %w", err)
+		}
+
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("projectArchive entry escapes the project directory: %s", header.Name)
+		}
+		target := filepath.Join(dir, name)
+		mode := os.FileMode(header.Mode).Perm()
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode|0700); err != nil {
+				return fmt.Errorf("failed to create %s: %w", header.Name, err)
+			}
+		case tar.TypeReg:
+			extracted += header.Size
+			if extracted > maxProjectExtractedBytes {
+				return fmt.Errorf("projectArchive expands to more than %d bytes", maxProjectExtractedBytes)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return fmt.Errorf("failed to create directory for %s: %w", header.Name, err)
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode|0600)
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", header.Name, err)
+			}
+			_, err = io.CopyN(f, tr, header.Size)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return fmt.Errorf("failed to extract %s: %w", header.Name, err)
+			}
+		case tar.TypeXGlobalHeader:
+			// pax metadata, nothing to extract
+		default:
+			return fmt.Errorf("projectArchive entry %s has unsupported type %q, only files and directories are allowed", header.Name, header.Typeflag)
+		}
+	}
+}
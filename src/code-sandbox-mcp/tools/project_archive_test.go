@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type archiveEntry struct {
+	header tar.Header
+	body   string
+}
+
+func encodeArchive(t *testing.T, entries []archiveEntry, compress bool) string {
+	t.Helper()
+	var buf bytes.Buffer
+	var gz *gzip.Writer
+	var tw *tar.Writer
+	if compress {
+		gz = gzip.NewWriter(&buf)
+		tw = tar.NewWriter(gz)
+	} else {
+		tw = tar.NewWriter(&buf)
+	}
+	for _, entry := range entries {
+		header := entry.header
+		header.Size = int64(len(entry.body))
+		if err := tw.WriteHeader(&header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(entry.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestExtractProjectArchive(t *testing.T) {
+	project := []archiveEntry{
+		{header: tar.Header{Name: "src/", Typeflag: tar.TypeDir, Mode: 0755}},
+		{header: tar.Header{Name: "src/main.py", Typeflag: tar.TypeReg, Mode: 0644}, body: "print('hi')\n"},
+		{header: tar.Header{Name: "run.sh", Typeflag: tar.TypeReg, Mode: 0755}, body: "#!/bin/sh\n"},
+	}
+
+	for _, compress := range []bool{false, true} {
+		dir, err := extractProjectArchive(encodeArchive(t, project, compress))
+		if err != nil {
+			t.Fatalf("extractProjectArchive(compress=%v) unexpected error: %v", compress, err)
+		}
+		defer os.RemoveAll(dir)
+
+		content, err := os.ReadFile(filepath.Join(dir, "src", "main.py"))
+		if err != nil || string(content) != "print('hi')\n" {
+			t.Errorf("src/main.py = %q (%v)", content, err)
+		}
+		info, err := os.Stat(filepath.Join(dir, "run.sh"))
+		if err != nil || info.Mode().Perm()&0100 == 0 {
+			t.Errorf("run.sh lost its executable bit: %v (%v)", info.Mode(), err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		archive     string
+		errContains string
+	}{
+		{
+			name:        "path traversal",
+			archive:     encodeArchive(t, []archiveEntry{{header: tar.Header{Name: "../evil.txt", Typeflag: tar.TypeReg, Mode: 0644}, body: "x"}}, false),
+			errContains: "escapes the project directory",
+		},
+		{
+			name:        "absolute path",
+			archive:     encodeArchive(t, []archiveEntry{{header: tar.Header{Name: "/etc/evil", Typeflag: tar.TypeReg, Mode: 0644}, body: "x"}}, false),
+			errContains: "escapes the project directory",
+		},
+		{
+			name:        "symlink",
+			archive:     encodeArchive(t, []archiveEntry{{header: tar.Header{Name: "requirements.txt", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}}}, false),
+			errContains: "unsupported type",
+		},
+		{
+			name:        "not base64",
+			archive:     "not an archive!",
+			errContains: "not valid base64",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := extractProjectArchive(tt.archive)
+			if err == nil {
+				os.RemoveAll(dir)
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Fatalf("extractProjectArchive() error = %v, want error containing %q", err, tt.errContains)
+			}
+		})
+	}
+}
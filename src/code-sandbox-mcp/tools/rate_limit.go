@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// tokenBucket is a token bucket shared by all tool calls. It holds up to burst
+// tokens and refills at rate tokens per second; every call takes one token.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// take removes a token if one is available. Otherwise it returns how long
+// until the next token is added.
+func (b *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// callLimiter is nil when tool calls are not rate limited
+var callLimiter *tokenBucket
+
+// SetRateLimit limits tool calls across all clients to rate calls per second,
+// allowing bursts of up to burst calls. A rate of 0 or less disables the limit.
+// It must be called before the server starts.
+func SetRateLimit(rate float64, burst int) {
+	if rate <= 0 {
+		callLimiter = nil
+		return
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	callLimiter = &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// RateLimited wraps a tool handler so calls over the rate limit are rejected
+// before the handler does any work
+func RateLimited(handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if callLimiter != nil {
+			if ok, wait := callLimiter.take(time.Now()); !ok {
+				return mcp.NewToolResultError(fmt.Sprintf("rate limited, retry after %ds", int(math.Ceil(wait.Seconds())))), nil
+			}
+		}
+		return handler(ctx, request)
+	}
+}
@@ -0,0 +1,58 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Now()
+	b := &tokenBucket{rate: 2, burst: 3, tokens: 3, last: start}
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := b.take(start); !ok {
+			t.Fatalf("take() %d within burst was refused", i+1)
+		}
+	}
+	ok, wait := b.take(start)
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("take() over burst = %v, %v, want refused with a 500ms wait", ok, wait)
+	}
+	if ok, _ := b.take(start.Add(500 * time.Millisecond)); !ok {
+		t.Errorf("take() after refill was refused")
+	}
+	// Idle time refills no more than the burst size
+	for i := 0; i < 3; i++ {
+		b.take(start.Add(time.Hour))
+	}
+	if ok, _ := b.take(start.Add(time.Hour)); ok {
+		t.Errorf("take() past the burst after a long idle period was allowed")
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	SetRateLimit(0.5, 1)
+	t.Cleanup(func() { SetRateLimit(0, 0) })
+
+	calls := 0
+	handler := RateLimited(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		calls++
+		return mcp.NewToolResultText("ok"), nil
+	})
+
+	if result, _ := handler(context.Background(), mcp.CallToolRequest{}); result.IsError {
+		t.Fatalf("first call was rate limited")
+	}
+	result, _ := handler(context.Background(), mcp.CallToolRequest{})
+	text := result.Content[0].(mcp.TextContent).Text
+	if !result.IsError || !strings.Contains(text, "rate limited, retry after 2s") {
+		t.Errorf("second call result = %q, want rate limited error", text)
+	}
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+}
@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"net/url"
+
+	deps "github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+	"github.com/Automata-Labs-team/code-sandbox-mcp/resources"
+)
+
+// PackageRegistries are the package indexes dependency installs go through
+// instead of the public ones, e.g. an internal mirror. Empty fields keep the
+// public index.
+type PackageRegistries struct {
+	PipIndexURL string // Python package index, used by both uv and pip
+	NPMRegistry string // npm registry, used by Bun, npm, yarn and pnpm
+	Token       string // Credentials available to installs as REGISTRY_TOKEN
+}
+
+// packageRegistries holds the operator's registry configuration
+var packageRegistries PackageRegistries
+
+// SetPackageRegistries configures the registries dependency installs use.
+// The token and any credentials in the registry URLs are masked in run output.
+func SetPackageRegistries(registries PackageRegistries) {
+	packageRegistries = registries
+	resources.SetLogSecrets(registries.secrets())
+}
+
+// registryEnv returns the container environment pointing a language's
+// package manager at the configured registries
+func registryEnv(language deps.Language) []string {
+	var env []string
+	switch language {
+	case deps.Python:
+		if packageRegistries.PipIndexURL != "" {
+			// uv doesn't read pip's variable, so it gets its own
+			env = append(env,
+				"PIP_INDEX_URL="+packageRegistries.PipIndexURL,
+				"UV_INDEX_URL="+packageRegistries.PipIndexURL,
+			)
+		}
+	case deps.NodeJS, deps.TypeScript:
+		if packageRegistries.NPMRegistry != "" {
+			env = append(env, "npm_config_registry="+packageRegistries.NPMRegistry)
+		}
+	default:
+		return nil
+	}
+	if packageRegistries.Token != "" {
+		env = append(env, "REGISTRY_TOKEN="+packageRegistries.Token)
+	}
+	return env
+}
+
+// secrets returns the credentials of the configuration: the token and the
+// password, or the user name when used alone as a token, of each registry URL
+func (r PackageRegistries) secrets() []string {
+	secrets := []string{r.Token}
+	for _, registry := range []string{r.PipIndexURL, r.NPMRegistry} {
+		u, err := url.Parse(registry)
+		if err != nil || u.User == nil {
+			continue
+		}
+		// The URL-encoded form is what a package manager would print
+		secrets = append(secrets, u.User.String())
+		if password, ok := u.User.Password(); ok {
+			secrets = append(secrets, password)
+		} else {
+			secrets = append(secrets, u.User.Username())
+		}
+	}
+	return secrets
+}
@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/moby/moby/client"
+)
+
+// Remote Docker modes. A remote daemon doesn't share the server's filesystem,
+// so code, projects and artifacts are moved in and out of containers over the
+// Docker API instead of through bind mounts.
+const (
+	RemoteDockerAuto   = "auto"   // Treat the daemon as remote unless DOCKER_HOST is a local socket
+	RemoteDockerAlways = "always" // Always copy over the API
+	RemoteDockerNever  = "never"  // Always use bind mounts
+)
+
+var remoteDockerMode = RemoteDockerAuto
+
+// SetRemoteDocker sets how the server decides whether the Docker daemon is remote
+func SetRemoteDocker(mode string) error {
+	switch mode {
+	case RemoteDockerAuto, RemoteDockerAlways, RemoteDockerNever:
+		remoteDockerMode = mode
+		return nil
+	default:
+		return fmt.Errorf("invalid remote Docker mode %q, expected one of: %s, %s, %s", mode, RemoteDockerAuto, RemoteDockerAlways, RemoteDockerNever)
+	}
+}
+
+// isRemoteDocker reports whether the daemon cli talks to may be on another
+// machine. In auto mode only unix sockets and Windows named pipes count as local.
+func isRemoteDocker(cli *client.Client) bool {
+	switch remoteDockerMode {
+	case RemoteDockerAlways:
+		return true
+	case RemoteDockerNever:
+		return false
+	}
+	u, err := url.Parse(cli.DaemonHost())
+	if err != nil {
+		return false
+	}
+	return u.Scheme != "unix" && u.Scheme != "npipe"
+}
+
+// makeContainerDir creates an empty directory any user can write to in a
+// created container, for paths that would otherwise come from a bind mount
+func makeContainerDir(ctx context.Context, cli *client.Client, containerID string, dir string) error {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	hdr := &tar.Header{
+		Name:     strings.TrimPrefix(path.Clean(dir), "/") + "/",
+		Mode:     0777,
+		Typeflag: tar.TypeDir,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := cli.CopyToContainer(ctx, containerID, "/", &buf, container.CopyToContainerOptions{}); err != nil {
+		return fmt.Errorf("failed to create %s in container: %w", dir, err)
+	}
+	return nil
+}
+
+// copyIntoContainer copies the contents of srcDir into containerDir of a
+// created container over the Docker API, in place of bind mounting srcDir
+func copyIntoContainer(ctx context.Context, cli *client.Client, containerID string, srcDir string, containerDir string) error {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeDirTar(pw, srcDir, strings.TrimPrefix(path.Clean(containerDir), "/")))
+	}()
+	defer pr.Close()
+
+	if err := cli.CopyToContainer(ctx, containerID, "/", pr, container.CopyToContainerOptions{}); err != nil {
+		return fmt.Errorf("failed to copy %s into container: %w", srcDir, err)
+	}
+	return nil
+}
+
+// writeDirTar writes srcDir as a tar stream with every entry below prefix.
+// Only directories, regular files and symlinks are included.
+func writeDirTar(w io.Writer, srcDir string, prefix string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(srcDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, p)
+		if err != nil {
+			return err
+		}
+
+		var link string
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		case !info.IsDir() && !info.Mode().IsRegular():
+			return nil
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = path.Join(prefix, filepath.ToSlash(rel))
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moby/moby/client"
+)
+
+func TestIsRemoteDocker(t *testing.T) {
+	t.Cleanup(func() { SetRemoteDocker(RemoteDockerAuto) })
+
+	tests := []struct {
+		mode string
+		host string
+		want bool
+	}{
+		{mode: RemoteDockerAuto, host: "unix:///var/run/docker.sock", want: false},
+		{mode: RemoteDockerAuto, host: "tcp://10.0.0.5:2376", want: true},
+		{mode: RemoteDockerAuto, host: "ssh://user@build-host", want: true},
+		{mode: RemoteDockerAlways, host: "unix:///var/run/docker.sock", want: true},
+		{mode: RemoteDockerNever, host: "tcp://10.0.0.5:2376", want: false},
+	}
+	for _, tt := range tests {
+		if err := SetRemoteDocker(tt.mode); err != nil {
+			t.Fatal(err)
+		}
+		cli, err := client.NewClientWithOpts(client.WithHost(tt.host))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := isRemoteDocker(cli); got != tt.want {
+			t.Errorf("isRemoteDocker() with mode %s and host %s = %v, want %v", tt.mode, tt.host, got, tt.want)
+		}
+	}
+
+	if err := SetRemoteDocker("sometimes"); err == nil {
+		t.Errorf("SetRemoteDocker(sometimes) succeeded, want error")
+	}
+}
+
+func TestWriteDirTar(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "main.py"), []byte("print(1)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("src/main.py", filepath.Join(dir, "run.py")); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeDirTar(&buf, dir, "app"); err != nil {
+		t.Fatalf("writeDirTar() unexpected error: %v", err)
+	}
+
+	got := make(map[string]string)
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(tr)
+		got[hdr.Name] = string(content) + hdr.Linkname
+	}
+
+	want := map[string]string{
+		"app/":            "",
+		"app/src/":        "",
+		"app/src/main.py": "print(1)",
+		"app/run.py":      "src/main.py",
+	}
+	if len(got) != len(want) {
+		t.Errorf("writeDirTar() entries = %v, want %v", got, want)
+	}
+	for name, content := range want {
+		if got[name] != content {
+			t.Errorf("entry %s = %q, want %q", name, got[name], content)
+		}
+	}
+}
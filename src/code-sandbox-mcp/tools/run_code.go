@@ -1,18 +1,22 @@
 package tools
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+	"github.com/Automata-Labs-team/code-sandbox-mcp/logging"
 	resources "github.com/Automata-Labs-team/code-sandbox-mcp/resources"
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/image"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/moby/moby/client"
@@ -20,8 +24,11 @@ import (
 )
 
 func RunCodeSandbox(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	arguments := request.Params.Arguments
-	steps, _ := arguments["steps"].(float64)
+	var params runCodeParams
+	if err := decodeParams(request.Params.Arguments, &params); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	steps := params.Steps
 	if steps == 0 {
 		steps = 100
 	}
@@ -31,17 +38,13 @@ func RunCodeSandbox(ctx context.Context, request mcp.CallToolRequest) (*mcp.Call
 		progressToken = request.Params.Meta.ProgressToken
 	}
 
-	language, ok := request.Params.Arguments["language"].(string)
-	if !ok {
-		return mcp.NewToolResultError(fmt.Sprintf("Language not supported: %s", request.Params.Arguments["language"])), nil
-	}
-	code, ok := request.Params.Arguments["code"].(string)
-	if !ok {
-		return mcp.NewToolResultError("language must be a string"), nil
+	parsed, config, code, opts, err := parseSnippetRequest(params, request.Params.Arguments)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
-
+	opts.Stdin = params.Stdin
 	// Extract output path if provided
-	outputPath, _ := request.Params.Arguments["outputPath"].(string)
+	outputPath := opts.OutputPath
 	// Validate that the output path exists if provided
 	if outputPath != "" {
 		if _, err := os.Stat(outputPath); os.IsNotExist(err) {
@@ -49,16 +52,14 @@ func RunCodeSandbox(ctx context.Context, request mcp.CallToolRequest) (*mcp.Call
 			if err := os.MkdirAll(outputPath, 0755); err != nil {
 				return mcp.NewToolResultError(fmt.Sprintf("Failed to create output directory: %v", err)), nil
 			}
-			fmt.Printf("Created output directory: %s\n", outputPath)
+			logging.Debugf("Created output directory: %s", outputPath)
 		} else if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Error checking output directory: %v", err)), nil
 		}
 	}
-	parsed := languages.Language(language)
-	config := languages.SupportedLanguages[languages.Language(language)]
 
 	if progressToken != "" {
-		if err := server.SendNotificationToClient(
+		if err := notifyClient(ctx, server,
 			"notifications/progress",
 			map[string]interface{}{
 				"progress":      int(10),
@@ -76,32 +77,34 @@ func RunCodeSandbox(ctx context.Context, request mcp.CallToolRequest) (*mcp.Call
 	}
 
 	cmd := config.RunCommand
+	image := config.Image
+	if opts.Image != "" {
+		image = opts.Image
+	}
 	escapedCode := strings.ToValidUTF8(code, "")
 
 	// Create a channel to receive the result from runInDocker
 	resultCh := make(chan struct {
-		logs      string
-		artifacts []string
-		err       error
+		result runResult
+		err    error
 	}, 1)
 
 	// Run the Docker container in a goroutine
 	go func() {
-		logs, artifacts, err := runInDocker(ctx, cmd, config.Image, escapedCode, parsed, outputPath)
+		result, err := runInDocker(ctx, cmd, image, escapedCode, parsed, opts)
 		resultCh <- struct {
-			logs      string
-			artifacts []string
-			err       error
-		}{logs, artifacts, err}
+			result runResult
+			err    error
+		}{result, err}
 	}()
 
 	progress := 20
 	for {
 		select {
-		case result := <-resultCh:
+		case res := <-resultCh:
 			if progressToken != "" {
 				// Send final progress update
-				_ = server.SendNotificationToClient(
+				_ = notifyClient(ctx, server,
 					"notifications/progress",
 					map[string]interface{}{
 						"progress":      100,
@@ -110,15 +113,44 @@ func RunCodeSandbox(ctx context.Context, request mcp.CallToolRequest) (*mcp.Call
 					},
 				)
 			}
-			if result.err != nil {
-				return mcp.NewToolResultError(fmt.Sprintf("Error: %v", result.err)), nil
+			if res.err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Error: %v", res.err)), nil
 			}
 
-			if len(result.artifacts) > 0 {
-				return mcp.NewToolResultText(fmt.Sprintf("Logs: %s\n\nArtifacts: %s", result.logs, strings.Join(result.artifacts, ", "))), nil
-			} else {
-				return mcp.NewToolResultText(fmt.Sprintf("Logs: %s", result.logs)), nil
+			resultText := fmt.Sprintf("Container ID: %s\nResource URI: containers://%s/logs\nExit code: %d\n\nLogs: %s",
+				res.result.ContainerID, res.result.ContainerID, res.result.ExitCode, res.result.Logs)
+			resultText += logStreams(res.result.Stdout, res.result.Stderr)
+			if failure := res.result.InstallFailure; failure != nil {
+				resultText = fmt.Sprintf("%s\n\n%s\n\nInstall log:\n%s", failure.summary(), resultText, strings.TrimSuffix(failure.Log, "\n"))
+			}
+			if res.result.TimedOut > 0 {
+				resultText = fmt.Sprintf("Error: execution timed out after %s\n\n%s", res.result.TimedOut, resultText)
+			} else if res.result.OOMKilled > 0 {
+				resultText = fmt.Sprintf("Error: the code exceeded its memory limit of %dMB and was killed. Pass a larger memoryLimitMB if it needs more\n\n%s", res.result.OOMKilled, resultText)
+			}
+			resultText += res.result.NetworkHint
+			if len(res.result.Artifacts) > 0 {
+				resultText += fmt.Sprintf("\n\nArtifacts: %s", strings.Join(res.result.Artifacts, ", "))
+				resultText += artifactPreviews(res.result.Artifacts)
 			}
+			if len(res.result.ArtifactsSkipped) > 0 {
+				resultText += fmt.Sprintf("\n\nArtifacts skipped: %s", strings.Join(res.result.ArtifactsSkipped, ", "))
+			}
+			if len(res.result.OutputFiles) > 0 {
+				resultText += fmt.Sprintf("\n\nOutput files: %s", strings.Join(res.result.OutputFiles, ", "))
+			}
+			resultText += res.result.Durations.summary()
+			resultText += res.result.dependencyCacheSummary()
+			resultText += res.result.commandSummary()
+			if len(res.result.BuildWarnings) > 0 {
+				resultText += fmt.Sprintf("\n\nBuild warnings:\n%s", strings.Join(res.result.BuildWarnings, "\n"))
+			}
+			if len(res.result.Warnings) > 0 {
+				resultText += fmt.Sprintf("\n\nWarnings: %s", strings.Join(res.result.Warnings, "; "))
+			}
+			result := resultWithImages(resultText, res.result.Artifacts)
+			result.IsError = res.result.ExitCode != 0 || res.result.TimedOut > 0 || res.result.OOMKilled > 0
+			return result, nil
 		default:
 			time.Sleep(2 * time.Second)
 			if progressToken != "" {
@@ -127,7 +159,7 @@ func RunCodeSandbox(ctx context.Context, request mcp.CallToolRequest) (*mcp.Call
 				} else {
 					progress = progress + 5
 				}
-				if err := server.SendNotificationToClient(
+				if err := notifyClient(ctx, server,
 					"notifications/progress",
 					map[string]interface{}{
 						"progress":      progress,
@@ -135,7 +167,7 @@ func RunCodeSandbox(ctx context.Context, request mcp.CallToolRequest) (*mcp.Call
 						"progressToken": progressToken,
 					},
 				); err != nil {
-					server.SendNotificationToClient("notifications/error", map[string]interface{}{
+					notifyClient(ctx, server, "notifications/error", map[string]interface{}{
 						"message": fmt.Sprintf("Failed to send progress: %v", err),
 					})
 				}
@@ -144,32 +176,158 @@ func RunCodeSandbox(ctx context.Context, request mcp.CallToolRequest) (*mcp.Call
 	}
 }
 
-func runInDocker(ctx context.Context, cmd []string, dockerImage string, code string, language languages.Language, outputPath string) (string, []string, error) {
+// parseSnippetRequest reads the snippet to run and its settings from the
+// arguments run_code and analyze_code share, returning the language and its
+// configuration, the code and the run options
+func parseSnippetRequest(params runCodeParams, arguments map[string]interface{}) (languages.Language, languages.LanguageConfig, string, runOptions, error) {
+	parsed, config, err := languageConfig(params.Language)
+	if err != nil {
+		return "", config, "", runOptions{}, err
+	}
+	files, err := parseSnippetFiles(params.Files)
+	if err != nil {
+		return "", config, "", runOptions{}, err
+	}
+	hasCode := params.Code != "" || params.FilePath != ""
+	if (params.Code != "" && params.FilePath != "") || (!hasCode && len(files) == 0) {
+		return "", config, "", runOptions{}, fmt.Errorf("exactly one of code or filePath must be provided, or files with an entrypoint")
+	}
+	code := params.Code
+
+	opts, err := parseRunOptions(arguments)
+	if err != nil {
+		return "", config, "", opts, err
+	}
+	if params.FilePath != "" {
+		if code, opts.FileExtension, err = readCodeFile(params.FilePath, parsed); err != nil {
+			return "", config, "", opts, err
+		}
+	}
+	opts.Files = files
+	if params.Entrypoint != "" || !hasCode {
+		// Without code the entrypoint defaults to main.<ext> among the files
+		entrypoint := params.Entrypoint
+		if entrypoint == "" {
+			entrypoint = "main." + config.FileExtension
+		}
+		if entrypoint, err = snippetFileName(entrypoint); err != nil {
+			return "", config, "", opts, err
+		}
+		if _, ok := files[entrypoint]; !ok {
+			return "", config, "", opts, fmt.Errorf("entrypoint %s is not one of the files", entrypoint)
+		}
+		opts.Entrypoint = entrypoint
+	}
+	return parsed, config, code, opts, nil
+}
+
+// resultWithImages returns a result made of the text followed by an image
+// block for every image artifact among uris that is small enough to inline
+func resultWithImages(text string, uris []string) *mcp.CallToolResult {
+	result := mcp.NewToolResultText(text)
+	for _, uri := range uris {
+		if data, mimeType, ok := resources.InlineImage(uri); ok {
+			result.Content = append(result.Content, mcp.NewImageContent(data, mimeType))
+		}
+	}
+	return result
+}
+
+// logStreams labels the output of each stream separately when a run wrote to
+// both, as the combined logs don't say which line came from where
+func logStreams(stdout, stderr string) string {
+	switch {
+	case stdout != "" && stderr != "":
+		return fmt.Sprintf("\n\n--- stdout ---\n%s\n--- stderr ---\n%s", strings.TrimSuffix(stdout, "\n"), strings.TrimSuffix(stderr, "\n"))
+	case stderr != "":
+		return "\n\nAll output was written to stderr"
+	default:
+		return ""
+	}
+}
+
+// artifactPreviews renders inline previews of the text artifacts among uris
+func artifactPreviews(uris []string) string {
+	var b strings.Builder
+	for _, uri := range uris {
+		if preview, ok := resources.ArtifactPreview(uri); ok {
+			fmt.Fprintf(&b, "\n\nPreview of %s:\n%s", uri, preview)
+		}
+	}
+	return b.String()
+}
+
+// runResult holds the outcome of a single sandboxed run
+type runResult struct {
+	ContainerID      string
+	Image            string // Image the run used
+	ExitCode         int64
+	Logs             string // Combined stdout and stderr, in the order they were written
+	Stdout           string
+	Stderr           string
+	TimedOut         time.Duration // Timeout the run was stopped after, zero when it finished in time
+	OOMKilled        int64         // Memory limit in MB the run was killed for exceeding, zero if it wasn't
+	NetworkHint      string        // Explains a failure that looks caused by the missing network
+	Command          []string      // Final container command, only set when requested
+	Artifacts        []string
+	ArtifactsSkipped []string
+	OutputFiles      []string        // Host paths artifacts were copied to in outputPath
+	Warnings         []string        // Problems collecting the run's results
+	BuildWarnings    []string        // Non-fatal warnings the compiler or runtime reported on stderr
+	InstallFailure   *installFailure // Set when installing the dependencies failed
+	// DependencyCacheUsed is set when dependencies were installed with a
+	// cache volume mounted, DependenciesCached when that volume already existed
+	DependencyCacheUsed bool
+	DependenciesCached  bool
+	Durations           runDurations
+}
+
+// commandSummary renders the final container command when it was requested
+func (r runResult) commandSummary() string {
+	if len(r.Command) == 0 {
+		return ""
+	}
+	command, _ := json.Marshal(r.Command)
+	return fmt.Sprintf("\n\nCommand: %s", command)
+}
+
+// dependencyCacheSummary reports whether an install step reused the dependency
+// cache, or nothing when no cache was involved
+func (r runResult) dependencyCacheSummary() string {
+	if !r.DependencyCacheUsed {
+		return ""
+	}
+	return fmt.Sprintf("\n\nDependencies cached: %t", r.DependenciesCached)
+}
+
+func runInDocker(ctx context.Context, cmd []string, dockerImage string, code string, language languages.Language, opts runOptions) (runResult, error) {
+	outputPath := opts.OutputPath
+	release, err := acquireRunSlot(ctx)
+	if err != nil {
+		return runResult{}, fmt.Errorf("no run slot became free: %w", err)
+	}
+	defer release()
+
 	cli, err := client.NewClientWithOpts(
 		client.FromEnv,
 		client.WithAPIVersionNegotiation(),
 	)
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to create Docker client: %w", err)
+		return runResult{}, fmt.Errorf("failed to create Docker client: %w", err)
 	}
 	defer cli.Close()
 
 	// Pull the Docker image
-	reader, err := cli.ImagePull(ctx, dockerImage, image.PullOptions{})
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to pull Docker image %s: %w", dockerImage, err)
-	}
-	defer reader.Close()
-
-	_, err = io.Copy(io.Discard, reader)
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to copy Docker image pull output: %w", err)
+	pullStart := time.Now()
+	if err := pullImage(ctx, cli, dockerImage, opts.ForcePull); err != nil {
+		return runResult{}, err
 	}
+	pullDuration := time.Since(pullStart)
 
 	// Create a temporary directory for the code file
 	tmpDir, err := os.MkdirTemp("", "docker-sandbox-*")
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to create temporary directory: %w", err)
+		return runResult{}, fmt.Errorf("failed to create temporary directory: %w", err)
 	}
 
 	// Only remove the tmpDir when done
@@ -178,78 +336,68 @@ func runInDocker(ctx context.Context, cmd []string, dockerImage string, code str
 	// Create artifacts directory
 	artifactsDir := filepath.Join(tmpDir, "artifacts")
 	if err := os.Mkdir(artifactsDir, 0755); err != nil {
-		return "", nil, fmt.Errorf("failed to create artifacts directory: %w", err)
-	}
-
-	// Write the code to a file in the temporary directory
-	tmpFile := filepath.Join(tmpDir, "main."+languages.SupportedLanguages[language].FileExtension)
-	err = os.WriteFile(tmpFile, []byte(code), 0644)
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to write code to temporary file: %w", err)
+		return runResult{}, fmt.Errorf("failed to create artifacts directory: %w", err)
 	}
 
-	// Parse imports to detect required packages
-	var packages []string
-	if language == languages.Python {
-		packages = languages.ParsePythonImports(code)
-		fmt.Printf("Detected Python packages: %v\n", packages)
-	} else if language == languages.NodeJS {
-		packages = languages.ParseNodeImports(code)
-	} else if language == languages.Go {
-		packages = languages.ParseGoImports(code)
+	plan := planSnippet(cmd, code, language, opts)
+	if err := writeSnippet(tmpDir, plan.FileName, code, opts); err != nil {
+		return runResult{}, err
 	}
 
 	// Create a requirements.txt file if Python packages are detected
-	if language == languages.Python && len(packages) > 0 {
+	if language == languages.Python && len(plan.Packages) > 0 {
 		requirementsPath := filepath.Join(tmpDir, "requirements.txt")
-		requirementsContent := strings.Join(packages, "\n")
-		fmt.Printf("Writing requirements file to %s with content:\n%s\n", requirementsPath, requirementsContent)
+		requirementsContent := strings.Join(plan.Packages, "\n")
+		logging.Debugf("Writing requirements file to %s with content:\n%s", requirementsPath, requirementsContent)
 		if err := os.WriteFile(requirementsPath, []byte(requirementsContent), 0644); err != nil {
-			return "", nil, fmt.Errorf("failed to write requirements file: %w", err)
+			return runResult{}, fmt.Errorf("failed to write requirements file: %w", err)
 		}
 	} else if language == languages.Python {
-		fmt.Printf("No Python packages detected in imports\n")
+		logging.Debugf("No Python packages detected in imports")
 	}
 
-	// Modify the command to install dependencies first if needed
-	var finalCmd []string
-	if language == languages.Python && len(packages) > 0 {
-		// Install dependencies first using uv (faster than pip), then run the code
-		installCmd := "uv pip install --system " + strings.Join(packages, " ") + " && " + strings.Join(cmd, " ")
-		fmt.Printf("Using install command: %s\n", installCmd)
-		finalCmd = []string{
-			"/bin/sh",
-			"-c",
-			installCmd,
+	// Rust snippets using crates are built with cargo from a generated Cargo.toml
+	if language == languages.Rust && len(plan.Packages) > 0 {
+		if err := os.WriteFile(filepath.Join(tmpDir, "Cargo.toml"), []byte(cargoManifest(plan.Packages)), 0644); err != nil {
+			return runResult{}, fmt.Errorf("failed to write Cargo.toml: %w", err)
 		}
-	} else {
-		finalCmd = cmd
 	}
 
-	// Create container config
-	env := []string{"ARTIFACTS_DIR=/artifacts"}
+	finalCmd := plan.Cmd
+	networkMode, isolate := networkPlan(opts.AllowNetwork, finalCmd)
 
-	// Mount the temporary directory to /app and artifacts directory to /artifacts
-	binds := []string{
-		fmt.Sprintf("%s:/app", tmpDir),
-		fmt.Sprintf("%s:/artifacts", artifactsDir),
+	// Create container config
+	env := append([]string{"ARTIFACTS_DIR=/artifacts"}, registryEnv(language)...)
+	env = append(env, languages.SupportedLanguages[language].Env...)
+	if len(opts.Env) > 0 {
+		logging.Debugf("Setting environment variables: %s", maskedEnv(opts.Env))
+		env = append(env, opts.Env...)
 	}
 
-	// We'll use the artifactsDir for both resource registration and direct access
-	// This simplifies our approach by having a single source of truth
-	if outputPath != "" {
-		fmt.Printf("Using artifacts directory for both resource registration and direct file access to %s\n", outputPath)
+	// Mount the temporary directory to the work directory and artifacts directory to /artifacts.
+	// A remote daemon can't see either, so the code is copied in and the
+	// artifacts copied out over the API instead.
+	remote := isRemoteDocker(cli)
+	if remote && len(opts.ReadOnlyFiles) > 0 {
+		return runResult{}, fmt.Errorf("readOnlyFiles needs a local Docker daemon")
+	}
+	var binds []string
+	if !remote {
+		binds = append(binds,
+			fmt.Sprintf("%s:%s", tmpDir, opts.WorkDir),
+			fmt.Sprintf("%s:/artifacts", artifactsDir),
+		)
 	}
 
 	// Add direct binding for user artifacts directory if specified
 	userArtifactsDir := os.Getenv("ARTIFACTS_DIR")
-	if userArtifactsDir != "" {
+	if userArtifactsDir != "" && !remote {
 		// Create user artifacts directory if it doesn't exist
 		if _, err := os.Stat(userArtifactsDir); os.IsNotExist(err) {
 			if err := os.MkdirAll(userArtifactsDir, 0755); err != nil {
-				fmt.Printf("Warning: Failed to create user artifacts directory %s: %v\n", userArtifactsDir, err)
+				logging.Warnf("Failed to create user artifacts directory %s: %v", userArtifactsDir, err)
 			} else {
-				fmt.Printf("Created user artifacts directory: %s\n", userArtifactsDir)
+				logging.Debugf("Created user artifacts directory: %s", userArtifactsDir)
 			}
 		}
 
@@ -257,101 +405,323 @@ func runInDocker(ctx context.Context, cmd []string, dockerImage string, code str
 		binds = append(binds, fmt.Sprintf("%s:/user-artifacts", userArtifactsDir))
 		// Add environment variable so the container code knows about the user artifacts directory
 		env = append(env, "USER_ARTIFACTS_DIR=/user-artifacts")
-		fmt.Printf("Added direct binding for user artifacts: %s -> /user-artifacts\n", userArtifactsDir)
+		logging.Debugf("Added direct binding for user artifacts: %s -> /user-artifacts", userArtifactsDir)
 	}
 
-	config := &container.Config{
-		Image: dockerImage,
-		Cmd:   finalCmd,
-		Tty:   false,
-		// Set environment variables
-		Env: env,
-	}
+	var result runResult
+	var runID string
+	var exitCode int64
+	var timedOut bool
+	var out io.Reader
+	timeout := opts.timeout(language)
+	if poolable(opts, plan.Packages, remote) {
+		// Snippets that install nothing run in a warm container from the
+		// pool, whose directories take the place of the temporary directory
+		pc, err := checkoutContainer(ctx, cli, dockerImage, language, opts)
+		if err != nil {
+			return runResult{}, err
+		}
+		artifactsDir = filepath.Join(pc.Dir, "artifacts")
+		if err := writeSnippet(pc.Dir, plan.FileName, code, opts); err != nil {
+			discardContainer(context.WithoutCancel(ctx), cli, pc)
+			return runResult{}, err
+		}
+		run := resources.Run{SessionID: opts.SessionID, Language: language.String(), Image: dockerImage, PullDuration: pullDuration}
+		var output *bytes.Buffer
+		runID, exitCode, timedOut, output, err = execPooled(ctx, cli, pc, finalCmd, env, run, timeout)
+		if err != nil {
+			discardContainer(context.WithoutCancel(ctx), cli, pc)
+			return runResult{}, err
+		}
+		// Nothing the snippet started may keep running while its artifacts
+		// are collected. A timed out run took its container with it, any
+		// other goes back to the pool once its artifacts have been collected.
+		if !timedOut && stopPooledProcesses(ctx, cli, pc) {
+			defer releaseContainer(context.WithoutCancel(ctx), cli, pc)
+		} else {
+			_ = removeContainer(context.WithoutCancel(ctx), cli, pc.ID)
+			defer os.RemoveAll(pc.Dir)
+		}
+		out = output
+	} else {
+		config := &container.Config{
+			Image: dockerImage,
+			Cmd:   finalCmd,
+			Tty:   false,
+			// Set environment variables
+			Env:         env,
+			User:        languages.SupportedLanguages[language].User,
+			Labels:      runLabels(opts.SessionID, language.String(), timeout),
+			StopTimeout: &opts.StopTimeout,
+			// Stdin is closed once the input has been written, instead of staying
+			// open for another attach
+			AttachStdin: opts.Stdin != "",
+			OpenStdin:   opts.Stdin != "",
+			StdinOnce:   opts.Stdin != "",
+		}
 
-	hostConfig := &container.HostConfig{
-		Binds: binds,
-	}
+		binds = append(binds, opts.ReadOnlyFiles...)
+		if opts.AllowDockerAccess {
+			binds = append(binds, fmt.Sprintf("%s:%s", dockerSocket, dockerSocket))
+		}
 
-	// Update container config to work in the mounted directory
-	config.WorkingDir = "/app"
+		hostConfig := &container.HostConfig{
+			Binds:       binds,
+			NetworkMode: networkMode,
+			SecurityOpt: opts.securityOpts(),
+			Resources:   opts.resources(language),
+		}
+		if len(opts.Secrets) > 0 {
+			hostConfig.Tmpfs = secretsTmpfs()
+		}
 
-	sandboxContainer, err := cli.ContainerCreate(ctx, config, hostConfig, nil, nil, "")
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to create container: %w", err)
-	}
+		if opts.InstallCommand != "" || ((language == languages.Python || language == languages.Rust) && len(plan.Packages) > 0) {
+			cacheMount, cached, ok, err := dependencyCacheMount(ctx, cli, language)
+			if err != nil {
+				return runResult{}, err
+			}
+			if ok {
+				hostConfig.Mounts = append(hostConfig.Mounts, cacheMount)
+				result.DependencyCacheUsed, result.DependenciesCached = true, cached
+			}
+		}
 
-	if err := cli.ContainerStart(ctx, sandboxContainer.ID, container.StartOptions{}); err != nil {
-		return "", nil, fmt.Errorf("failed to start container: %w", err)
-	}
+		// Update container config to work in the mounted directory
+		config.WorkingDir = opts.WorkDir
 
-	// Wait for container to finish
-	statusCh, errCh := cli.ContainerWait(ctx, sandboxContainer.ID, container.WaitConditionNotRunning)
+		sandboxContainer, err := cli.ContainerCreate(ctx, config, hostConfig, nil, nil, "")
+		if err != nil {
+			return runResult{}, fmt.Errorf("failed to create container: %w", err)
+		}
+		resources.RegisterContainer(resources.ActiveContainer{ContainerID: sandboxContainer.ID, Language: language.String(), Image: dockerImage})
+		// Remove the container however the run ends. Its logs and artifacts are
+		// collected before this runs, and its output is saved so the logs resource
+		// keeps working.
+		defer stopAndRemoveContainer(context.WithoutCancel(ctx), cli, sandboxContainer.ID)
+		// Secrets the program prints are masked like registry credentials
+		resources.SetRunSecrets(sandboxContainer.ID, secretValues(opts.Secrets))
+		defer resources.ForgetRunSecrets(sandboxContainer.ID)
+		if remote {
+			if err := copyIntoContainer(ctx, cli, sandboxContainer.ID, tmpDir, opts.WorkDir); err != nil {
+				return runResult{}, err
+			}
+			if err := makeContainerDir(ctx, cli, sandboxContainer.ID, "/artifacts"); err != nil {
+				return runResult{}, err
+			}
+		}
+		// Attach before starting, so no input is lost to a program that reads right away
+		var stdin types.HijackedResponse
+		if opts.Stdin != "" {
+			stdin, err = cli.ContainerAttach(ctx, sandboxContainer.ID, container.AttachOptions{Stream: true, Stdin: true})
+			if err != nil {
+				return runResult{}, fmt.Errorf("failed to attach to container stdin: %w", err)
+			}
+			defer stdin.Close()
+		}
+		resources.RegisterRun(resources.Run{
+			ContainerID: sandboxContainer.ID,
+			SessionID:   opts.SessionID,
+			Language:    language.String(),
+			Image:       dockerImage,
+			StartedAt:   time.Now(),
+
+			PullDuration: pullDuration,
+		})
+
+		if err := cli.ContainerStart(ctx, sandboxContainer.ID, container.StartOptions{}); err != nil {
+			return runResult{}, fmt.Errorf("failed to start container: %w", err)
+		}
+		if len(opts.Secrets) > 0 {
+			if err := writeSecrets(ctx, cli, sandboxContainer.ID, opts.Secrets); err != nil {
+				return runResult{}, err
+			}
+		}
+		if opts.Stdin != "" {
+			go writeStdin(stdin, opts.Stdin)
+		}
+		installDone := make(chan struct{})
+		if isolate {
+			go func() {
+				isolateAfterInstall(ctx, sandboxContainer.ID)
+				close(installDone)
+			}()
+		} else {
+			close(installDone)
+		}
+		streamDone := streamContainerLogs(ctx, server.ServerFromContext(ctx), sandboxContainer.ID)
 
-	select {
-	case err := <-errCh:
+		// Wait for container to finish, stopping it if it runs past its timeout
+		exitCode, timedOut, err = waitWithTimeout(ctx, cli, sandboxContainer.ID, timeout)
 		if err != nil {
-			panic(err)
+			return runResult{}, err
 		}
-	case <-statusCh:
+		<-streamDone
+		// The end of the install step has to be recorded before the durations are read
+		<-installDone
+		runID = sandboxContainer.ID
+
+		logsOut, err := cli.ContainerLogs(ctx, sandboxContainer.ID, container.LogsOptions{ShowStdout: true, ShowStderr: true})
+		if err != nil {
+			return runResult{}, fmt.Errorf("failed to get container logs: %w", err)
+		}
+		defer logsOut.Close()
+		out = logsOut
 	}
 
-	out, err := cli.ContainerLogs(ctx, sandboxContainer.ID, container.LogsOptions{ShowStdout: true, ShowStderr: true})
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to get container logs: %w", err)
+	if run, ok := resources.GetRun(runID); ok && run.Status == resources.RunStatusCancelled {
+		_ = recordRun(ctx, cli, resources.RunRecord{ContainerID: runID, Command: finalCmd, ExitCode: exitCode})
+		return runResult{}, fmt.Errorf("run was cancelled")
+	}
+	resources.SetRunExited(runID, exitCode)
+	if run, ok := resources.GetRun(runID); ok {
+		result.Durations, _ = durationsOf(run)
 	}
-	defer out.Close()
 
-	var b strings.Builder
-	_, err = stdcopy.StdCopy(&b, &b, out)
+	// Each stream is also kept on its own, so the result can tell them apart
+	// and warnings can be picked out of stderr
+	var b, stdout, stderr strings.Builder
+	_, err = stdcopy.StdCopy(io.MultiWriter(&b, &stdout), io.MultiWriter(&b, &stderr), out)
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to copy container output: %w", err)
+		return runResult{}, fmt.Errorf("failed to copy container output: %w", err)
+	}
+	// Registry credentials an install step printed, and the run's secrets,
+	// never leave the server
+	logs := resources.MaskRunSecrets(runID, b.String())
+	stdoutLogs, stderrLogs := resources.MaskRunSecrets(runID, stdout.String()), resources.MaskRunSecrets(runID, stderr.String())
+	record := resources.RunRecord{
+		ContainerID: runID,
+		Command:     finalCmd,
+		ExitCode:    exitCode,
+		TimedOut:    timedOut,
+		Logs:        logs,
+	}
+	// A run that timed out or ran out of memory still returns its logs and the
+	// artifacts it wrote before it was stopped, but is reported as an error
+	if timedOut {
+		result.TimedOut = timeout
+	} else if wasOOMKilled(ctx, cli, runID) {
+		result.OOMKilled = opts.resources(language).Memory / (1024 * 1024)
+		record.OOMKilled = true
+		resources.SetRunOOMKilled(runID)
 	}
 
+	result.ContainerID = runID
+	result.ExitCode = exitCode
+	if opts.ReturnCommand {
+		result.Command = finalCmd
+	}
+	result.Logs = stripInstallMarkers(logs)
+	result.Stdout = stripInstallMarkers(stdoutLogs)
+	result.Stderr = stripInstallMarkers(stderrLogs)
+	if failure, rest, failed := splitInstallFailure(logs); failed {
+		// The install log is reported on its own, so the logs only hold what
+		// the code wrote, if it ran at all
+		result.InstallFailure = &failure
+		result.Logs = stripInstallMarkers(rest)
+		if !failure.Continued {
+			result.Stdout, result.Stderr = "", ""
+		}
+	}
+	if !opts.AllowNetwork && exitCode != 0 {
+		result.NetworkHint = networkDisabledHint(result.Logs)
+	}
+	if !opts.OmitWarnings {
+		result.BuildWarnings = languages.ExtractWarnings(language, stderrLogs)
+	}
 	// Use the centralized artifact collection function
 	// Pass outputPath as the specified output directory (if provided)
 	// or empty string if no special output path requested
-	artifactURIs, err := resources.CollectArtifactsFromDir(sandboxContainer.ID, artifactsDir, outputPath)
+	// Collection keeps going past unreadable files, so a failure here still
+	// comes with the artifacts that could be collected
+	// Outputs written outside /artifacts are copied in next to the others
+	// first. Files from /artifacts win when names clash. With a remote daemon
+	// /artifacts itself isn't mounted and is copied out the same way.
+	var extraSkipped, oversized []string
+	artifactPaths := opts.ArtifactPaths
+	if remote {
+		artifactPaths = append([]string{"/artifacts"}, artifactPaths...)
+	}
+	for _, artifactPath := range artifactPaths {
+		// Only /artifacts keeps its subdirectories, the other paths are collected by name
+		skipped, tooLarge, err := copyOutOfContainer(ctx, cli, runID, artifactPath, artifactsDir, artifactPath == "/artifacts", resources.MaxTotalArtifactBytes(), resources.MaxArtifactFileBytes())
+		extraSkipped = append(extraSkipped, skipped...)
+		oversized = append(oversized, tooLarge...)
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("some artifacts could not be collected: %v", err))
+		}
+	}
+
+	output := resources.ArtifactOutput{Dir: outputPath, Overwrite: opts.Overwrite, Globs: opts.OutputGlobs, Ephemeral: opts.EphemeralArtifacts}
+	collection, err := resources.CollectArtifactsFromDir(runID, artifactsDir, output)
 	if err != nil {
-		return b.String(), nil, fmt.Errorf("failed to collect artifacts: %w", err)
+		result.Warnings = append(result.Warnings, fmt.Sprintf("some artifacts could not be collected: %v", err))
 	}
+	result.Artifacts = collection.URIs
+	result.ArtifactsSkipped = append(collection.Skipped, extraSkipped...)
+	if oversized = append(oversized, collection.Oversized...); len(oversized) > 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("artifacts skipped for exceeding the size limits: %s", strings.Join(oversized, ", ")))
+	}
+	result.OutputFiles = outputFileList(collection.OutputFiles)
 
-	// DIRECT ARTIFACT COPY FOR DEBUGGING
-	// This is a fallback direct copy mechanism to ensure artifacts are copied correctly
-	if outputPath != "" {
-		files, err := os.ReadDir(artifactsDir)
-		if err == nil && len(files) > 0 {
-			fmt.Printf("DIRECT COPY: Attempting direct copy of artifacts to %s\n", outputPath)
+	record.Artifacts = collection.URIs
+	if err := recordRun(ctx, cli, record); err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("run record could not be written: %v", err))
+	}
+	if err := resources.SaveContainerOutput(runID, exitCode, logs, stdoutLogs, stderrLogs); err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("logs will not be available once the container is removed: %v", err))
+	}
 
-			// Make sure the output directory exists
-			if err := os.MkdirAll(outputPath, 0755); err != nil {
-				fmt.Printf("DIRECT COPY ERROR: Failed to create output directory: %v\n", err)
-			} else {
-				// Copy each file directly
-				for _, file := range files {
-					if file.IsDir() {
-						continue
-					}
-
-					srcPath := filepath.Join(artifactsDir, file.Name())
-					dstPath := filepath.Join(outputPath, file.Name())
-
-					// Read source
-					data, err := os.ReadFile(srcPath)
-					if err != nil {
-						fmt.Printf("DIRECT COPY ERROR: Failed to read artifact %s: %v\n", file.Name(), err)
-						continue
-					}
-
-					// Write to destination
-					if err := os.WriteFile(dstPath, data, 0644); err != nil {
-						fmt.Printf("DIRECT COPY ERROR: Failed to write to %s: %v\n", dstPath, err)
-					} else {
-						fmt.Printf("DIRECT COPY SUCCESS: Copied %s to %s\n", file.Name(), dstPath)
-					}
+	// Fallback copy of the collected artifacts to the output directory, for
+	// files that collection didn't write there itself. Only collected files
+	// are copied, so skipped artifacts stay skipped.
+	if outputPath != "" && len(collection.URIs) > 0 {
+		logging.Debugf("Copying artifacts to %s", outputPath)
+
+		// Make sure the output directory exists
+		if err := os.MkdirAll(outputPath, 0755); err != nil {
+			logging.Warnf("Failed to create output directory: %v", err)
+		} else {
+			for _, uri := range collection.URIs {
+				_, name, err := resources.ParseArtifactURI(uri)
+				if err != nil || !output.Includes(name) {
+					continue
+				}
+				if _, handled := collection.OutputFiles[name]; handled {
+					continue
+				}
+
+				f, err := os.Open(filepath.Join(artifactsDir, filepath.FromSlash(name)))
+				if err != nil {
+					logging.Warnf("Failed to read artifact %s: %v", name, err)
+					continue
+				}
+
+				dstPath, err := resources.WriteOutputFile(output, name, f)
+				f.Close()
+				if err != nil {
+					logging.Warnf("Failed to write %s to %s: %v", name, outputPath, err)
+				} else if dstPath != "" {
+					logging.Debugf("Copied artifact %s to %s", name, dstPath)
+					result.OutputFiles = append(result.OutputFiles, dstPath)
 				}
 			}
 		}
 	}
 
-	return b.String(), artifactURIs, nil
+	return result, nil
+}
+
+// outputFileList lists the host paths artifacts were copied to, sorted, noting
+// the artifacts whose existing output file was kept by the overwrite policy
+func outputFileList(outputFiles map[string]string) []string {
+	var list []string
+	for name, hostPath := range outputFiles {
+		if hostPath == "" {
+			list = append(list, fmt.Sprintf("%s (existing file kept)", name))
+		} else {
+			list = append(list, hostPath)
+		}
+	}
+	sort.Strings(list)
+	return list
 }
@@ -2,6 +2,7 @@ package tools
 
 import (
 	"context"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -13,6 +14,7 @@ func TestRunInDocker(t *testing.T) {
 		name        string
 		language    languages.Language
 		code        string
+		stdin       string
 		wantOutput  string
 		wantErr     bool
 		errContains string
@@ -48,6 +50,26 @@ func TestRunInDocker(t *testing.T) {
 			wantOutput: "5\n",
 			wantErr:    false,
 		},
+		{
+			name:     "typescript language",
+			language: languages.TypeScript,
+			code: `
+				interface Point {
+					x: number;
+					y: number;
+				}
+				
+				const calculateDistance = (p1: Point, p2: Point): number => {
+					return Math.sqrt(Math.pow(p2.x - p1.x, 2) + Math.pow(p2.y - p1.y, 2));
+				};
+				
+				const point1: Point = { x: 0, y: 0 };
+				const point2: Point = { x: 3, y: 4 };
+				console.log(calculateDistance(point1, point2));
+			`,
+			wantOutput: "5\n",
+			wantErr:    false,
+		},
 		{
 			name:     "go code with package",
 			language: languages.Go,
@@ -67,6 +89,14 @@ func TestRunInDocker(t *testing.T) {
 			wantOutput: "HELLO FROM GO!\n",
 			wantErr:    false,
 		},
+		{
+			name:       "python reading stdin",
+			language:   languages.Python,
+			code:       `print(sum(int(x) for x in input().split()))`,
+			stdin:      "1 2 3",
+			wantOutput: "6\n",
+			wantErr:    false,
+		},
 	}
 
 	ctx := context.Background()
@@ -74,8 +104,8 @@ func TestRunInDocker(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			config := languages.SupportedLanguages[tt.language]
-			// Pass an empty string for outputPath in tests
-			output, artifacts, err := runInDocker(ctx, config.RunCommand, config.Image, tt.code, tt.language, "")
+			// No outputPath in tests
+			result, err := runInDocker(ctx, config.RunCommand, config.Image, tt.code, tt.language, runOptions{StopTimeout: DefaultStopTimeout, Stdin: tt.stdin})
 
 			// Check error cases
 			if (err != nil) != tt.wantErr {
@@ -90,9 +120,9 @@ func TestRunInDocker(t *testing.T) {
 			// Check output
 			if !tt.wantErr {
 				// Normalize line endings and trim spaces
-				got := strings.TrimSpace(output)
+				got := strings.TrimSpace(result.Logs)
 				t.Logf("got: %q", got)
-				t.Logf("artifacts: %v", artifacts)
+				t.Logf("artifacts: %v", result.Artifacts)
 				want := strings.TrimSpace(tt.wantOutput)
 				if got != want {
 					t.Errorf("runInDocker() output = %q, want %q", got, want)
@@ -101,3 +131,33 @@ func TestRunInDocker(t *testing.T) {
 		})
 	}
 }
+
+func TestOutputFileList(t *testing.T) {
+	got := outputFileList(map[string]string{
+		"report.csv": "/out/report.csv",
+		"plot.png":   "/out/plot-1.png",
+		"data.json":  "",
+	})
+	want := []string{"/out/plot-1.png", "/out/report.csv", "data.json (existing file kept)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("outputFileList() = %q, want %q", got, want)
+	}
+}
+
+func TestLogStreams(t *testing.T) {
+	tests := []struct {
+		name, stdout, stderr, want string
+	}{
+		{name: "both streams", stdout: "result: 6\n", stderr: "Traceback\n", want: "\n\n--- stdout ---\nresult: 6\n--- stderr ---\nTraceback"},
+		{name: "stdout only", stdout: "result: 6\n", want: ""},
+		{name: "stderr only", stderr: "Traceback\n", want: "\n\nAll output was written to stderr"},
+		{name: "no output", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := logStreams(tt.stdout, tt.stderr); got != tt.want {
+				t.Errorf("logStreams() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package tools
+
+import "context"
+
+// runSem is nil when the number of runs at the same time is not limited
+var runSem chan struct{}
+
+// SetMaxConcurrentRuns limits how many run_code and run_project runs may
+// execute at the same time. Further runs wait for a slot. Zero or less removes
+// the limit. It must be called before any run starts.
+func SetMaxConcurrentRuns(n int) {
+	if n < 1 {
+		runSem = nil
+		return
+	}
+	runSem = make(chan struct{}, n)
+}
+
+// acquireRunSlot waits for a free run slot. The returned func gives the slot
+// back and must be called once the run has finished.
+func acquireRunSlot(ctx context.Context) (func(), error) {
+	sem := runSem
+	if sem == nil {
+		return func() {}, nil
+	}
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,38 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAcquireRunSlot(t *testing.T) {
+	defer SetMaxConcurrentRuns(0)
+
+	SetMaxConcurrentRuns(0)
+	for i := 0; i < 3; i++ {
+		if _, err := acquireRunSlot(context.Background()); err != nil {
+			t.Fatalf("acquireRunSlot() without a limit = %v", err)
+		}
+	}
+
+	SetMaxConcurrentRuns(1)
+	release, err := acquireRunSlot(context.Background())
+	if err != nil {
+		t.Fatalf("acquireRunSlot() = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := acquireRunSlot(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("acquireRunSlot() with no free slot = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	release()
+	release, err = acquireRunSlot(context.Background())
+	if err != nil {
+		t.Fatalf("acquireRunSlot() after release = %v", err)
+	}
+	release()
+}
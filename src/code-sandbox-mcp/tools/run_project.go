@@ -4,13 +4,15 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
-	"regexp"
 	"strings"
+	"time"
 
 	deps "github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+	"github.com/Automata-Labs-team/code-sandbox-mcp/logging"
+	"github.com/Automata-Labs-team/code-sandbox-mcp/resources"
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/image"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/moby/moby/client"
@@ -19,7 +21,6 @@ import (
 // extractRequirementsFromPythonFiles scans all Python files in a directory
 // and extracts requirements from comments formatted as "# requirements: package1, package2"
 func extractRequirementsFromPythonFiles(projectDir string) ([]string, error) {
-	requirementsRe := regexp.MustCompile(`(?m)^#\s*requirements:\s*(.+)$`)
 	var allRequirements []string
 	requirementsMap := make(map[string]bool)
 
@@ -37,23 +38,15 @@ func extractRequirementsFromPythonFiles(projectDir string) ([]string, error) {
 		// Read file content
 		content, err := os.ReadFile(path)
 		if err != nil {
-			fmt.Printf("Warning: failed to read file %s: %v\n", path, err)
+			logging.Warnf("Failed to read file %s: %v", path, err)
 			return nil // Continue with other files
 		}
 
-		// Find requirements comments
-		matches := requirementsRe.FindAllStringSubmatch(string(content), -1)
-		for _, match := range matches {
-			if len(match) >= 2 {
-				// Split by comma and add to map to remove duplicates
-				reqsList := strings.Split(match[1], ",")
-				for _, req := range reqsList {
-					req = strings.TrimSpace(req)
-					if req != "" && !requirementsMap[req] {
-						requirementsMap[req] = true
-						allRequirements = append(allRequirements, req)
-					}
-				}
+		// Find requirements comments, skipping those another file already listed
+		for _, req := range deps.ParseRequirementsComments(string(content)) {
+			if !requirementsMap[req] {
+				requirementsMap[req] = true
+				allRequirements = append(allRequirements, req)
 			}
 		}
 
@@ -73,88 +66,300 @@ func RunProjectSandbox(ctx context.Context, request mcp.CallToolRequest) (*mcp.C
 		progressToken = request.Params.Meta.ProgressToken
 	}
 
-	language, ok := request.Params.Arguments["language"].(string)
-	if !ok {
-		return nil, fmt.Errorf("invalid language")
+	var params runProjectParams
+	if err := decodeParams(request.Params.Arguments, &params); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	parsed, config, err := languageConfig(params.Language)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	entrypoint := params.EntrypointCmd
+	projectDir := params.ProjectDir
+	projectArchive := params.ProjectArchive
+	if (projectDir == "") == (projectArchive == "") {
+		return mcp.NewToolResultError("exactly one of projectDir or projectArchive must be provided"), nil
 	}
-	entrypoint, ok := request.Params.Arguments["entrypointCmd"].(string)
-	if !ok {
-		return nil, fmt.Errorf("invalid entrypoint")
+
+	opts, err := parseRunOptions(request.Params.Arguments)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
-	projectDir, ok := request.Params.Arguments["projectDir"].(string)
-	if !ok {
-		return nil, fmt.Errorf("invalid projectDir")
+
+	// An uploaded project is extracted to a directory of its own that is
+	// removed once the run has finished
+	var onDone func()
+	if projectArchive != "" {
+		projectDir, err = extractProjectArchive(projectArchive)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		extractedDir := projectDir
+		onDone = func() { os.RemoveAll(extractedDir) }
+	} else {
+		// Validate project directory
+		projectDir = filepath.Clean(projectDir)
+		if _, err := os.Stat(projectDir); os.IsNotExist(err) {
+			return mcp.NewToolResultError(fmt.Sprintf("project directory does not exist: %s", projectDir)), nil
+		}
+		if err := checkHostPath("projectDir", projectDir); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
 	}
 
-	// Validate project directory
-	projectDir = filepath.Clean(projectDir)
-	if _, err := os.Stat(projectDir); os.IsNotExist(err) {
-		return nil, fmt.Errorf("project directory does not exist: %s", projectDir)
+	if params.CleanProjectArtifacts {
+		if err := cleanProjectArtifacts(projectDir); err != nil {
+			if onDone != nil {
+				onDone()
+			}
+			return mcp.NewToolResultError(err.Error()), nil
+		}
 	}
 
-	config := deps.SupportedLanguages[deps.Language(language)]
-	containerId, artifacts, err := runProjectInDocker(ctx, progressToken, strings.Fields(entrypoint), config.Image, projectDir, deps.Language(language))
+	// Without an entrypoint, run the project the way it declares itself
+	var entrypointSource string
+	if len(opts.EntrypointSteps) > 0 && strings.TrimSpace(entrypoint) != "" {
+		if onDone != nil {
+			onDone()
+		}
+		return mcp.NewToolResultError("entrypointCmd and entrypointSteps cannot be used together"), nil
+	}
+	if len(opts.EntrypointSteps) == 0 && strings.TrimSpace(entrypoint) == "" {
+		entrypoint, entrypointSource, err = inferEntrypoint(projectDir, parsed)
+		if err != nil {
+			if onDone != nil {
+				onDone()
+			}
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+
+	if params.DependencyFile != "" {
+		opts.DependencyFile, err = resolveDependencyFile(projectDir, params.DependencyFile, config)
+		if err != nil {
+			if onDone != nil {
+				onDone()
+			}
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+
+	cmd := strings.Fields(entrypoint)
+	if len(opts.EntrypointSteps) > 0 {
+		cmd = []string{"/bin/sh", stepsScriptPath}
+	}
+
+	// Node.js projects install with the package manager they declare, which
+	// decides the image as well
+	image := config.Image
+	var packageManagerSource string
+	if parsed.UsesNPM() {
+		firstCmd := cmd
+		if len(opts.EntrypointSteps) > 0 {
+			firstCmd = strings.Fields(opts.EntrypointSteps[0])
+		}
+		depDir := filepath.Join(projectDir, filepath.Dir(filepath.FromSlash(opts.DependencyFile)))
+		opts.PackageManager, packageManagerSource = detectNodePackageManager(depDir, firstCmd)
+		image = nodeProjectImage(opts.PackageManager)
+	}
+	if opts.Image != "" {
+		image = opts.Image
+	}
+
+	// A runtime version pinned by the project selects the matching image.
+	// Bun projects don't run on Node, so .nvmrc doesn't apply to them.
+	var versionNote string
+	if version, source := detectRuntimeVersion(projectDir, parsed); version != "" {
+		if opts.Image != "" {
+			versionNote = fmt.Sprintf("\n\nRuntime version: %s from %s ignored, the run uses the given image", version, source)
+		} else if parsed.UsesNPM() && opts.PackageManager == nodeBun {
+			versionNote = fmt.Sprintf("\n\nRuntime version: %s from %s ignored, the project runs with Bun", version, source)
+		} else {
+			opts.FallbackImage = image
+			image = runtimeImage(parsed, version)
+			versionNote = fmt.Sprintf("\n\nRuntime version: %s (from %s)", version, source)
+		}
+	}
+
+	// The run keeps its slot until the background run has finished
+	release, err := acquireRunSlot(ctx)
 	if err != nil {
+		if onDone != nil {
+			onDone()
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("Error: no run slot became free: %v", err)), nil
+	}
+	cleanup := onDone
+	onDone = func() {
+		release()
+		if cleanup != nil {
+			cleanup()
+		}
+	}
+
+	result, err := runProjectInDocker(ctx, progressToken, cmd, image, projectDir, parsed, opts, onDone)
+	if err != nil {
+		// The run never started, so nothing else will clean up
+		if onDone != nil {
+			onDone()
+		}
 		return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
 	}
 
 	// Always include the container logs URI
-	resultText := fmt.Sprintf("Resource URI: containers://%s/logs", containerId)
-
-	// Also include artifact URIs if available
-	if len(artifacts) > 0 {
-		resultText += fmt.Sprintf("\n\nArtifacts: %s", strings.Join(artifacts, ", "))
+	resultText := fmt.Sprintf("Container ID: %s\nResource URI: containers://%s/logs", result.ContainerID, result.ContainerID)
+	if len(opts.EntrypointSteps) > 0 {
+		resultText += fmt.Sprintf("\n\nRunning %d entrypoint steps. Call get_run_results once the run has finished for the outcome of each step", len(opts.EntrypointSteps))
+	}
+	if entrypointSource != "" {
+		resultText += fmt.Sprintf("\n\nEntrypoint: %s (detected from %s)", entrypoint, entrypointSource)
+	}
+	if opts.PackageManager != "" {
+		resultText += fmt.Sprintf("\n\nPackage manager: %s (from %s)", opts.PackageManager, packageManagerSource)
+	}
+	resultText += versionNote
+	if opts.PackageManager != "" || versionNote != "" {
+		resultText += fmt.Sprintf("\n\nImage: %s", result.Image)
 	}
+	if len(result.Warnings) > 0 {
+		resultText += fmt.Sprintf("\n\nWarnings: %s", strings.Join(result.Warnings, "; "))
+	}
+
+	// The run is still going, so its artifacts are collected once it exits
+	resultText += fmt.Sprintf("\n\nFiles written to %s/ are collected as artifacts once the run has finished; get_run_results lists them", projectArtifactsDir)
+	resultText += result.Durations.summary()
+	resultText += result.dependencyCacheSummary()
+	resultText += result.commandSummary()
 
 	return mcp.NewToolResultText(resultText), nil
 }
 
-func runProjectInDocker(ctx context.Context, progressToken mcp.ProgressToken, cmd []string, dockerImage string, projectDir string, language deps.Language) (string, []string, error) {
-	server := server.ServerFromContext(ctx)
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+// resolveDependencyFile validates a dependency file given relative to the project
+// and returns its cleaned, slash-separated path
+func resolveDependencyFile(projectDir string, dependencyFile string, config deps.LanguageConfig) (string, error) {
+	if filepath.IsAbs(dependencyFile) {
+		return "", fmt.Errorf("dependencyFile must be relative to the project directory: %s", dependencyFile)
+	}
+	rel, err := filepath.Rel(projectDir, filepath.Join(projectDir, dependencyFile))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("dependencyFile must be within the project directory: %s", dependencyFile)
+	}
+
+	if len(config.DependencyFiles) == 0 {
+		return "", fmt.Errorf("dependencyFile is not supported, the language has no dependency files")
+	}
+	supported := false
+	for _, file := range config.DependencyFiles {
+		if filepath.Base(rel) == file {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return "", fmt.Errorf("unsupported dependency file %s, expected one of: %s", dependencyFile, strings.Join(config.DependencyFiles, ", "))
+	}
+
+	info, err := os.Stat(filepath.Join(projectDir, rel))
+	if err != nil || info.IsDir() {
+		return "", fmt.Errorf("dependency file does not exist: %s", dependencyFile)
+	}
+
+	return filepath.ToSlash(rel), nil
+}
+
+// detectDependencyFile returns the first of the language's dependency files
+// found at the project root, or "" when there is none
+func detectDependencyFile(projectDir string, language deps.Language) string {
+	for _, file := range deps.SupportedLanguages[language].DependencyFiles {
+		if _, err := os.Stat(filepath.Join(projectDir, file)); err == nil {
+			return file
+		}
+	}
+	return ""
+}
+
+// projectArtifactsDir is the project subdirectory runs write their outputs to
+const projectArtifactsDir = "artifacts"
+
+// cleanProjectArtifacts empties the project's artifacts directory so a run
+// starts without outputs from earlier runs. Nothing outside that directory is
+// touched, and a symlinked artifacts directory is refused rather than followed.
+func cleanProjectArtifacts(projectDir string) error {
+	dir := filepath.Join(projectDir, projectArtifactsDir)
+	info, err := os.Lstat(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to create Docker client: %w", err)
+		return fmt.Errorf("failed to check project artifacts directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("cannot clean %s: not a directory", dir)
 	}
-	defer cli.Close()
 
-	if progressToken != "" {
-		if err := server.SendNotificationToClient(
-			"notifications/progress",
-			map[string]interface{}{
-				"progress":      10,
-				"progressToken": progressToken,
-			},
-		); err != nil {
-			return "", nil, fmt.Errorf("failed to send progress notification: %w", err)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read project artifacts directory: %w", err)
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return fmt.Errorf("failed to clean project artifacts directory: %w", err)
 		}
 	}
+	return nil
+}
 
-	// Pull the Docker image
-	_, err = cli.ImagePull(ctx, dockerImage, image.PullOptions{})
+func runProjectInDocker(ctx context.Context, progressToken mcp.ProgressToken, cmd []string, dockerImage string, projectDir string, language deps.Language, opts runOptions, onDone func()) (runResult, error) {
+	server := server.ServerFromContext(ctx)
+	progress := newProgressReporter(ctx, progressToken)
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to pull Docker image %s: %w", dockerImage, err)
+		return runResult{}, fmt.Errorf("failed to create Docker client: %w", err)
+	}
+	defer cli.Close()
+
+	// Pull the Docker image. An image for a pinned runtime version that
+	// doesn't exist falls back to the default image.
+	progress.report(phasePullingImage)
+	var result runResult
+	pullStart := time.Now()
+	if err := pullImage(ctx, cli, dockerImage, opts.ForcePull); err != nil {
+		if opts.FallbackImage == "" {
+			return runResult{}, err
+		}
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%v; using %s instead", err, opts.FallbackImage))
+		dockerImage = opts.FallbackImage
+		if err := pullImage(ctx, cli, dockerImage, opts.ForcePull); err != nil {
+			return runResult{}, err
+		}
 	}
+	result.Image = dockerImage
+	result.Durations.Pull = time.Since(pullStart)
 
 	// Check for dependency files and prepare install command
+	progress.report(phasePreparingDeps)
 	var hasDepFile bool
 	var depFile string
 
-	// Look for standard dependency files first
-	for _, file := range deps.SupportedLanguages[language].DependencyFiles {
-		if _, err := os.Stat(filepath.Join(projectDir, file)); err == nil {
-			hasDepFile = true
-			depFile = file
-			break
-		}
+	if opts.SkipDependencyInstall {
+		// The image already has the project's dependencies
+	} else if opts.DependencyFile != "" {
+		// An explicit dependency file overrides auto-detection
+		hasDepFile = true
+		depFile = opts.DependencyFile
+	} else {
+		// Look for standard dependency files first
+		depFile = detectDependencyFile(projectDir, language)
+		hasDepFile = depFile != ""
 	}
 
 	// For Python projects, also check for requirements comments in .py files
 	// if we didn't find a requirements.txt file
-	if language == deps.Python && (!hasDepFile || depFile != "requirements.txt") {
+	if language == deps.Python && !opts.SkipDependencyInstall && opts.DependencyFile == "" && (!hasDepFile || depFile != "requirements.txt") {
 		// Create a temporary requirements file from requirements comments
 		reqsFromComments, err := extractRequirementsFromPythonFiles(projectDir)
 		if err != nil {
-			fmt.Printf("Warning: failed to extract requirements from Python files: %v\n", err)
+			logging.Warnf("Failed to extract requirements from Python files: %v", err)
 		} else if len(reqsFromComments) > 0 {
 			// Create or update requirements.txt file
 			reqsPath := filepath.Join(projectDir, "requirements.txt")
@@ -192,101 +397,149 @@ func runProjectInDocker(ctx context.Context, progressToken mcp.ProgressToken, cm
 
 			err = os.WriteFile(reqsPath, []byte(strings.Join(finalReqs, "\n")), 0644)
 			if err != nil {
-				fmt.Printf("Warning: failed to write requirements.txt: %v\n", err)
+				logging.Warnf("Failed to write requirements.txt: %v", err)
 			} else {
 				hasDepFile = true
 				depFile = "requirements.txt"
-				fmt.Printf("Created requirements.txt from requirements comments: %v\n", finalReqs)
+				logging.Debugf("Created requirements.txt from requirements comments: %v", finalReqs)
 			}
 		}
 	}
 
-	// Create container config with working directory set to /app
+	if len(opts.Env) > 0 {
+		logging.Debugf("Setting environment variables: %s", maskedEnv(opts.Env))
+	}
+
+	// Create container config with working directory set to the mounted project
 	containerConfig := &container.Config{
-		Image:      dockerImage,
-		WorkingDir: "/app",
-		Tty:        false,
+		Image:       dockerImage,
+		WorkingDir:  opts.WorkDir,
+		Tty:         false,
+		Env:         append(append(registryEnv(language), deps.SupportedLanguages[language].Env...), opts.Env...),
+		User:        deps.SupportedLanguages[language].User,
+		Labels:      runLabels(opts.SessionID, language.String(), opts.timeout(language)),
+		StopTimeout: &opts.StopTimeout,
 	}
 
 	// If we have dependencies, modify the command to install them first
+	spec := CommandSpec{
+		Language:       language,
+		Cmd:            cmd,
+		WorkDir:        opts.WorkDir,
+		PackageManager: opts.PackageManager,
+		InstallCommand: opts.InstallCommand,
+
+		IsolateAfterInstall:    !opts.AllowNetwork,
+		ContinueOnInstallError: opts.ContinueOnDepError,
+	}
 	if hasDepFile {
-		switch language {
-		case deps.Python:
-			if depFile == "requirements.txt" {
-				containerConfig.Cmd = []string{
-					"/bin/sh", "-c", fmt.Sprintf("uv pip install --system -r %s && %s", depFile, strings.Join(cmd, " ")),
-				}
-			} else if depFile == "pyproject.toml" || depFile == "setup.py" {
-				containerConfig.Cmd = []string{
-					"/bin/sh", "-c", fmt.Sprintf("uv pip install --system . && %s", strings.Join(cmd, " ")),
-				}
-			}
-		case deps.Go:
-			// Combine the install command with the run command
-			containerConfig.Cmd = append(deps.SupportedLanguages[language].InstallCommand, cmd...)
-		case deps.NodeJS:
-			// Bun automatically installs dependencies when running the project, so just combine "bun" with the command after index 1
-			containerConfig.Cmd = append([]string{"bun"}, cmd[1:]...)
-		}
-	} else {
-		// Handle the case where there are no dependency files
-		switch language {
-		case deps.Python:
-			// For Python without dependencies, use shell to execute the command
-			containerConfig.Cmd = []string{
-				"/bin/sh", "-c", strings.Join(cmd, " "),
-			}
-		default:
-			// For other languages, use the command as is
-			containerConfig.Cmd = cmd
-		}
+		spec.DependencyFile = depFile
 	}
-
-	if progressToken != "" {
-		server.SendNotificationToClient(
-			"notifications/progress",
-			map[string]interface{}{
-				"progress":      50,
-				"progressToken": progressToken,
-			},
-		)
+	containerConfig.Cmd = BuildContainerCommand(spec)
+	if len(opts.Secrets) > 0 {
+		containerConfig.Cmd = withSecrets(containerConfig.Cmd)
 	}
 
-	// Mount the project directory to /app
+	progress.report(phaseStarting)
+
+	// Mount the project directory to the work directory. A remote daemon can't
+	// see it, so the project is copied in over the API instead.
+	remote := isRemoteDocker(cli)
+	if remote && len(opts.ReadOnlyFiles) > 0 {
+		return runResult{}, fmt.Errorf("readOnlyFiles needs a local Docker daemon")
+	}
+	networkMode, isolate := networkPlan(opts.AllowNetwork, containerConfig.Cmd)
 	hostConfig := &container.HostConfig{
-		Binds: []string{
-			fmt.Sprintf("%s:/app", projectDir),
-		},
+		NetworkMode: networkMode,
+		SecurityOpt: opts.securityOpts(),
+		Resources:   opts.resources(language),
+	}
+	if len(opts.Secrets) > 0 {
+		hostConfig.Tmpfs = secretsTmpfs()
+	}
+	if !remote {
+		hostConfig.Binds = append(hostConfig.Binds, fmt.Sprintf("%s:%s", projectDir, opts.WorkDir))
+	}
+	if opts.AllowDockerAccess {
+		hostConfig.Binds = append(hostConfig.Binds, fmt.Sprintf("%s:%s", dockerSocket, dockerSocket))
+	}
+	hostConfig.Binds = append(hostConfig.Binds, opts.ReadOnlyFiles...)
+
+	if hasDepFile || opts.InstallCommand != "" {
+		cacheMount, cached, ok, err := dependencyCacheMount(ctx, cli, language)
+		if err != nil {
+			return runResult{}, err
+		}
+		if ok {
+			hostConfig.Mounts = append(hostConfig.Mounts, cacheMount)
+			result.DependencyCacheUsed, result.DependenciesCached = true, cached
+		}
 	}
 
 	resp, err := cli.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, "")
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to create container: %w", err)
+		return runResult{}, fmt.Errorf("failed to create container: %w", err)
 	}
-
-	if progressToken != "" {
-		server.SendNotificationToClient(
-			"notifications/progress",
-			map[string]interface{}{
-				"progress":      75,
-				"progressToken": progressToken,
-			},
-		)
+	resources.RegisterContainer(resources.ActiveContainer{ContainerID: resp.ID, Language: language.String(), Image: dockerImage})
+	if remote {
+		if err := copyIntoContainer(ctx, cli, resp.ID, projectDir, opts.WorkDir); err != nil {
+			removeContainer(context.Background(), cli, resp.ID)
+			return runResult{}, err
+		}
+	}
+	if len(opts.EntrypointSteps) > 0 {
+		script := buildStepsScript(opts.EntrypointSteps, opts.ContinueOnError)
+		if err := writeContainerFile(ctx, cli, resp.ID, stepsScriptPath, script); err != nil {
+			removeContainer(context.Background(), cli, resp.ID)
+			return runResult{}, err
+		}
 	}
+	resources.RegisterRun(resources.Run{
+		ContainerID: resp.ID,
+		SessionID:   opts.SessionID,
+		Language:    language.String(),
+		Image:       dockerImage,
+		StartedAt:   time.Now(),
+
+		PullDuration: result.Durations.Pull,
+	})
+
+	// Secrets the program prints are masked like registry credentials, until
+	// the run's output has been saved
+	resources.SetRunSecrets(resp.ID, secretValues(opts.Secrets))
 
 	if err := cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
-		return "", nil, fmt.Errorf("failed to start container: %w", err)
+		resources.ForgetRunSecrets(resp.ID)
+		return runResult{}, fmt.Errorf("failed to start container: %w", err)
 	}
-
-	if progressToken != "" {
-		server.SendNotificationToClient(
-			"notifications/progress",
-			map[string]interface{}{
-				"progress":      100,
-				"progressToken": progressToken,
-			},
-		)
+	if len(opts.Secrets) > 0 {
+		if err := writeSecrets(ctx, cli, resp.ID, opts.Secrets); err != nil {
+			stopAndRemoveContainer(context.WithoutCancel(ctx), cli, resp.ID)
+			resources.ForgetRunSecrets(resp.ID)
+			return runResult{}, err
+		}
+	}
+	// The run keeps going after this call returns, so the stream must not be
+	// tied to the request's lifetime
+	if isolate {
+		go isolateAfterInstall(context.WithoutCancel(ctx), resp.ID)
 	}
+	streamContainerLogs(context.WithoutCancel(ctx), server, resp.ID)
+	// A project copied in over the API writes its artifacts in the container,
+	// so they are copied back out before it is removed
+	artifacts := runArtifacts{HostDir: filepath.Join(projectDir, projectArtifactsDir)}
+	if remote {
+		artifacts.ContainerDir = path.Join(opts.WorkDir, projectArtifactsDir)
+	}
+	backgroundRuns.Add(1)
+	go watchBackgroundRun(context.WithoutCancel(ctx), resp.ID, containerConfig.Cmd, opts.EntrypointSteps, opts.timeout(language), artifacts, onDone)
+
+	// The run continues in the background, so completion isn't reported here
+	progress.report(phaseRunning)
 
-	return resp.ID, nil, nil
+	result.ContainerID = resp.ID
+	if opts.ReturnCommand {
+		result.Command = containerConfig.Cmd
+	}
+	return result, nil
 }
@@ -0,0 +1,142 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestResolveDependencyFile(t *testing.T) {
+	projectDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(projectDir, "backend"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "backend", "requirements.txt"), []byte("requests\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		dependencyFile string
+		want           string
+		errContains    string
+	}{
+		{
+			name:           "file in subdirectory",
+			dependencyFile: "backend/requirements.txt",
+			want:           "backend/requirements.txt",
+		},
+		{
+			name:           "unclean path",
+			dependencyFile: "./backend/../backend/requirements.txt",
+			want:           "backend/requirements.txt",
+		},
+		{
+			name:           "missing file",
+			dependencyFile: "requirements.txt",
+			errContains:    "does not exist",
+		},
+		{
+			name:           "path traversal",
+			dependencyFile: "../requirements.txt",
+			errContains:    "within the project directory",
+		},
+		{
+			name:           "absolute path",
+			dependencyFile: filepath.Join(projectDir, "backend", "requirements.txt"),
+			errContains:    "relative to the project directory",
+		},
+		{
+			name:           "unsupported file",
+			dependencyFile: "backend/Pipfile",
+			errContains:    "unsupported dependency file",
+		},
+	}
+
+	config := languages.SupportedLanguages[languages.Python]
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveDependencyFile(projectDir, tt.dependencyFile, config)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("resolveDependencyFile() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveDependencyFile() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveDependencyFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanProjectArtifacts(t *testing.T) {
+	projectDir := t.TempDir()
+	artifactsDir := filepath.Join(projectDir, projectArtifactsDir)
+	for _, path := range []string{
+		filepath.Join(artifactsDir, "old.png"),
+		filepath.Join(artifactsDir, "nested", "old.csv"),
+		filepath.Join(projectDir, "main.py"),
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := cleanProjectArtifacts(projectDir); err != nil {
+		t.Fatalf("cleanProjectArtifacts() unexpected error: %v", err)
+	}
+	entries, err := os.ReadDir(artifactsDir)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("artifacts directory not emptied: %v (%v)", entries, err)
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, "main.py")); err != nil {
+		t.Errorf("project file outside the artifacts directory was touched: %v", err)
+	}
+
+	// A symlinked artifacts directory must not be followed
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "keep.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	linkedProject := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(linkedProject, projectArtifactsDir)); err != nil {
+		t.Fatal(err)
+	}
+	if err := cleanProjectArtifacts(linkedProject); err == nil {
+		t.Error("cleanProjectArtifacts() expected an error for a symlinked artifacts directory")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "keep.txt")); err != nil {
+		t.Errorf("file behind the symlink was removed: %v", err)
+	}
+
+	// A project without an artifacts directory is left alone
+	if err := cleanProjectArtifacts(t.TempDir()); err != nil {
+		t.Errorf("cleanProjectArtifacts() unexpected error without artifacts directory: %v", err)
+	}
+}
+
+func TestRunProjectSandboxMissingProjectDir(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{"language": "python", "projectDir": missing, "entrypointCmd": "python main.py"}
+
+	result, err := RunProjectSandbox(context.Background(), request)
+	if err != nil {
+		t.Fatalf("RunProjectSandbox() returned a protocol error: %v", err)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !result.IsError || !strings.Contains(text, "project directory does not exist") {
+		t.Errorf("RunProjectSandbox() = %q, want a tool error naming the missing directory", text)
+	}
+}
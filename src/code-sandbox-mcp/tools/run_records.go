@@ -0,0 +1,35 @@
+package tools
+
+import (
+	"context"
+	"time"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/resources"
+	"github.com/moby/moby/client"
+)
+
+// recordRun writes the record of a finished run when run recording is
+// enabled. The run's session, language, image, start time and status are
+// taken from the run registry.
+func recordRun(ctx context.Context, cli *client.Client, record resources.RunRecord) error {
+	if !resources.RunRecordingEnabled() {
+		return nil
+	}
+
+	record.FinishedAt = time.Now()
+	if run, ok := resources.GetRun(record.ContainerID); ok {
+		record.SessionID = run.SessionID
+		record.Language = run.Language
+		record.Image = run.Image
+		record.StartedAt = run.StartedAt
+		record.Status = run.Status
+	}
+	if inspect, _, err := cli.ImageInspectWithRaw(ctx, record.Image); err == nil {
+		record.ImageDigest = inspect.ID
+		if len(inspect.RepoDigests) > 0 {
+			record.ImageDigest = inspect.RepoDigests[0]
+		}
+	}
+
+	return resources.WriteRunRecord(record)
+}
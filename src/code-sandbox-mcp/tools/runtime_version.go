@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	deps "github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+)
+
+// runtimeVersionFile is a file a project pins its runtime version with
+type runtimeVersionFile struct {
+	Name string
+	// Pattern extracts the version from the file's first matching line
+	Pattern *regexp.Regexp
+}
+
+// runtimeVersionFiles lists the version files of each language, in the order they are checked
+var runtimeVersionFiles = map[deps.Language][]runtimeVersionFile{
+	// Images are only published per minor version, so patch versions are dropped
+	deps.Python:     {{Name: ".python-version", Pattern: regexp.MustCompile(`^(\d+\.\d+)(\.\d+)?$`)}},
+	deps.NodeJS:     nodeVersionFiles,
+	deps.TypeScript: nodeVersionFiles,
+	deps.Go:         {{Name: "go.mod", Pattern: regexp.MustCompile(`^go\s+(\d+\.\d+(\.\d+)?)$`)}},
+}
+
+// nodeVersionFiles are the version files of JavaScript and TypeScript projects
+var nodeVersionFiles = []runtimeVersionFile{
+	{Name: ".nvmrc", Pattern: regexp.MustCompile(`^v?(\d+(\.\d+){0,2})$`)},
+	{Name: ".node-version", Pattern: regexp.MustCompile(`^v?(\d+(\.\d+){0,2})$`)},
+}
+
+// runtimeImages are the image references of each language for a pinned version
+var runtimeImages = map[deps.Language]string{
+	deps.Python:     "ghcr.io/astral-sh/uv:python%s-bookworm-slim",
+	deps.NodeJS:     "node:%s-bookworm-slim",
+	deps.TypeScript: "node:%s-bookworm-slim",
+	deps.Go:         "docker.io/library/golang:%s-bookworm",
+}
+
+// detectRuntimeVersion returns the runtime version a project pins in its
+// version files and the file it came from, or "" when it pins none
+func detectRuntimeVersion(projectDir string, language deps.Language) (version string, source string) {
+	for _, file := range runtimeVersionFiles[language] {
+		f, err := os.Open(filepath.Join(projectDir, file.Name))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if match := file.Pattern.FindStringSubmatch(line); match != nil {
+				f.Close()
+				return match[1], file.Name
+			}
+		}
+		f.Close()
+	}
+	return "", ""
+}
+
+// runtimeImage returns the image running language at version
+func runtimeImage(language deps.Language, version string) string {
+	return fmt.Sprintf(runtimeImages[language], version)
+}
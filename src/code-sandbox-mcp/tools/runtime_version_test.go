@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	deps "github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+)
+
+func TestDetectRuntimeVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		language    deps.Language
+		files       map[string]string
+		wantVersion string
+		wantSource  string
+		wantImage   string
+	}{
+		{
+			name:        "python-version drops the patch version",
+			language:    deps.Python,
+			files:       map[string]string{".python-version": "3.11.4\n"},
+			wantVersion: "3.11",
+			wantSource:  ".python-version",
+			wantImage:   "ghcr.io/astral-sh/uv:python3.11-bookworm-slim",
+		},
+		{
+			name:     "python-version with an interpreter name",
+			language: deps.Python,
+			files:    map[string]string{".python-version": "pypy3.10\n"},
+		},
+		{
+			name:        "nvmrc",
+			language:    deps.NodeJS,
+			files:       map[string]string{".nvmrc": "v20.11.0\n"},
+			wantVersion: "20.11.0",
+			wantSource:  ".nvmrc",
+			wantImage:   "node:20.11.0-bookworm-slim",
+		},
+		{
+			name:        "node-version",
+			language:    deps.NodeJS,
+			files:       map[string]string{".node-version": "18\n"},
+			wantVersion: "18",
+			wantSource:  ".node-version",
+			wantImage:   "node:18-bookworm-slim",
+		},
+		{
+			name:     "nvmrc alias",
+			language: deps.NodeJS,
+			files:    map[string]string{".nvmrc": "lts/iron\n"},
+		},
+		{
+			name:        "go.mod go directive",
+			language:    deps.Go,
+			files:       map[string]string{"go.mod": "module example.com/app\n\ngo 1.22.3\n\nrequire github.com/google/uuid v1.6.0\n"},
+			wantVersion: "1.22.3",
+			wantSource:  "go.mod",
+			wantImage:   "docker.io/library/golang:1.22.3-bookworm",
+		},
+		{
+			name:     "go.mod without go directive",
+			language: deps.Go,
+			files:    map[string]string{"go.mod": "module example.com/app\n"},
+		},
+		{
+			name:     "language without version files",
+			language: deps.Dart,
+			files:    map[string]string{".python-version": "3.11\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			version, source := detectRuntimeVersion(dir, tt.language)
+			if version != tt.wantVersion || source != tt.wantSource {
+				t.Errorf("detectRuntimeVersion() = %q, %q, want %q, %q", version, source, tt.wantVersion, tt.wantSource)
+			}
+			if tt.wantImage != "" {
+				if got := runtimeImage(tt.language, version); got != tt.wantImage {
+					t.Errorf("runtimeImage() = %q, want %q", got, tt.wantImage)
+				}
+			}
+		})
+	}
+}
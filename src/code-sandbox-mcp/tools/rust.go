@@ -0,0 +1,38 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// rustCargoRun runs a Rust snippet that uses crates through the Cargo.toml
+// generated next to it. Build output stays out of the work directory.
+var rustCargoRun = []string{"/bin/sh", "-c", "CARGO_TARGET_DIR=/tmp/target cargo run --quiet"}
+
+// rustCrateRequirements are the dependency entries of crates that are
+// rarely useful without some of their features
+var rustCrateRequirements = map[string]string{
+	"serde": `{ version = "1", features = ["derive"] }`,
+	"tokio": `{ version = "1", features = ["full"] }`,
+}
+
+// cargoManifest returns a Cargo.toml building main.rs with the given crates
+// at their latest versions
+func cargoManifest(crates []string) string {
+	sorted := append([]string{}, crates...)
+	sort.Strings(sorted)
+
+	var b strings.Builder
+	b.WriteString("[package]\nname = \"main\"\nversion = \"0.1.0\"\nedition = \"2021\"\n\n")
+	b.WriteString("[[bin]]\nname = \"main\"\npath = \"main.rs\"\n\n")
+	b.WriteString("[dependencies]\n")
+	for _, crate := range sorted {
+		requirement, ok := rustCrateRequirements[crate]
+		if !ok {
+			requirement = `"*"`
+		}
+		fmt.Fprintf(&b, "%s = %s\n", crate, requirement)
+	}
+	return b.String()
+}
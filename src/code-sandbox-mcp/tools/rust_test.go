@@ -0,0 +1,24 @@
+package tools
+
+import "testing"
+
+func TestCargoManifest(t *testing.T) {
+	got := cargoManifest([]string{"serde_json", "serde", "rand"})
+	want := `[package]
+name = "main"
+version = "0.1.0"
+edition = "2021"
+
+[[bin]]
+name = "main"
+path = "main.rs"
+
+[dependencies]
+rand = "*"
+serde = { version = "1", features = ["derive"] }
+serde_json = "*"
+`
+	if got != want {
+		t.Errorf("cargoManifest() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,45 @@
+package tools
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// restrictiveSeccompProfile blocks syscalls that code run in the sandbox has
+// no business making: kernel modules, mounts, namespaces, tracing and clock changes
+//
+//go:embed seccomp_restrictive.json
+var restrictiveSeccompProfile string
+
+// loadSeccompProfile resolves the seccompProfile parameter to the profile JSON
+// passed to Docker. "default" keeps Docker's default profile and returns "",
+// "restrictive" selects the built-in profile, anything else is read as a file path.
+func loadSeccompProfile(profile string) (string, error) {
+	switch profile {
+	case "default":
+		return "", nil
+	case "restrictive":
+		return restrictiveSeccompProfile, nil
+	case "unconfined":
+		return "", fmt.Errorf("seccompProfile cannot be unconfined")
+	}
+
+	data, err := os.ReadFile(profile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read seccomp profile %s: %w", profile, err)
+	}
+
+	var parsed struct {
+		DefaultAction string `json:"defaultAction"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return "", fmt.Errorf("invalid seccomp profile %s: %w", profile, err)
+	}
+	if parsed.DefaultAction == "" {
+		return "", fmt.Errorf("invalid seccomp profile %s: missing defaultAction", profile)
+	}
+
+	return string(data), nil
+}
@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/moby/moby/client"
+)
+
+// secretsDir is the in-container tmpfs secrets are written to, one file per secret
+const secretsDir = "/run/secrets"
+
+// secretsReadyFile is created once every secret has been written
+const secretsReadyFile = secretsDir + "/.ready"
+
+// maxSecretsBytes bounds the combined size of a run's secrets, which must fit
+// in the tmpfs
+const maxSecretsBytes = 512 * 1024
+
+// secretNameRe matches secret names, which become file names in secretsDir
+var secretNameRe = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._-]*$`)
+
+// waitForSecrets waits until the server has written the run's secrets. It
+// gives up after 30 seconds rather than run the program without them.
+const waitForSecrets = "n=0; until [ -e " + secretsReadyFile + " ]; do n=$((n+1)); " +
+	"[ $n -le 300 ] || { echo 'secrets were not provided in time' >&2; exit 1; }; " +
+	"sleep 0.1; done"
+
+// parseSecrets reads the secrets parameter, an object of secret names to values
+func parseSecrets(value interface{}) (map[string]string, error) {
+	secrets, err := stringMap("secrets", value)
+	if err != nil {
+		return nil, err
+	}
+	total := 0
+	for name, secret := range secrets {
+		if !secretNameRe.MatchString(name) {
+			return nil, fmt.Errorf("invalid secret name %q: only letters, digits, '.', '_' and '-' are allowed, and it can't start with '.' or '-'", name)
+		}
+		total += len(secret)
+	}
+	if total > maxSecretsBytes {
+		return nil, fmt.Errorf("secrets are larger than %d KB in total", maxSecretsBytes/1024)
+	}
+	return secrets, nil
+}
+
+// secretValues returns the values of secrets, to be masked in the run's output
+func secretValues(secrets map[string]string) []string {
+	values := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		values = append(values, secret)
+	}
+	return values
+}
+
+// secretsTmpfs is the tmpfs mount holding a run's secrets. Its contents live
+// in memory only and go away with the container.
+func secretsTmpfs() map[string]string {
+	return map[string]string{secretsDir: "rw,noexec,nosuid,size=1m,mode=0755"}
+}
+
+// withSecrets makes a container command wait for its secrets before it runs
+func withSecrets(cmd []string) []string {
+	return append([]string{"/bin/sh", "-c", waitForSecrets + ` && exec "$@"`, "sh"}, cmd...)
+}
+
+// writeSecrets writes secrets into the tmpfs of a started container and then
+// marks them ready. The values are passed on the exec's standard input, so
+// they never show up in its command line, the container's configuration or
+// the server's output.
+func writeSecrets(ctx context.Context, cli *client.Client, containerID string, secrets map[string]string) error {
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := writeSecretFile(ctx, cli, containerID, secretsDir+"/"+name, secrets[name]); err != nil {
+			return fmt.Errorf("failed to write secret %s: %w", name, err)
+		}
+	}
+	if err := writeSecretFile(ctx, cli, containerID, secretsReadyFile, ""); err != nil {
+		return fmt.Errorf("failed to write secrets: %w", err)
+	}
+	return nil
+}
+
+// writeSecretFile writes content to a file in the container as root, which
+// owns the tmpfs, readable by the container's own user
+func writeSecretFile(ctx context.Context, cli *client.Client, containerID string, containerPath string, content string) error {
+	exec, err := cli.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		User:         "0",
+		Cmd:          []string{"/bin/sh", "-c", `umask 022 && cat > "$1"`, "sh", containerPath},
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return err
+	}
+	attach, err := cli.ContainerExecAttach(ctx, exec.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return err
+	}
+	defer attach.Close()
+
+	if _, err := io.WriteString(attach.Conn, content); err != nil {
+		return err
+	}
+	if err := attach.CloseWrite(); err != nil {
+		return err
+	}
+	// Wait for the exec to finish. Its output is an error message at most.
+	_, _ = io.Copy(io.Discard, attach.Reader)
+
+	inspect, err := cli.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return err
+	}
+	if inspect.ExitCode != 0 {
+		return fmt.Errorf("exit code %d", inspect.ExitCode)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSecrets(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       interface{}
+		want        map[string]string
+		errContains string
+	}{
+		{
+			name:  "object",
+			value: map[string]interface{}{"api_key": "k-123", "db.password": "p"},
+			want:  map[string]string{"api_key": "k-123", "db.password": "p"},
+		},
+		{name: "json string", value: `{"token": "t"}`, want: map[string]string{"token": "t"}},
+		{name: "path in name", value: map[string]interface{}{"../etc/passwd": "x"}, errContains: "invalid secret name"},
+		{name: "hidden name", value: map[string]interface{}{".ready": "x"}, errContains: "invalid secret name"},
+		{name: "too large", value: map[string]interface{}{"big": strings.Repeat("x", maxSecretsBytes+1)}, errContains: "larger than"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSecrets(tt.value)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("parseSecrets() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSecrets() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSecrets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithSecrets(t *testing.T) {
+	cmd := []string{"/bin/sh", "-c", "python3 main.py"}
+	got := withSecrets(cmd)
+	if got[0] != "/bin/sh" || !strings.Contains(got[2], secretsReadyFile) || !reflect.DeepEqual(got[4:], cmd) {
+		t.Errorf("withSecrets() = %q, want the command run after waiting for %s", got, secretsReadyFile)
+	}
+	// The wait must not hide the isolation step from networkPlan
+	isolated := BuildContainerCommand(CommandSpec{Language: "python", Cmd: []string{"python3", "main.py"}, WorkDir: "/app", Packages: []string{"requests"}, IsolateAfterInstall: true})
+	if !hasIsolationStep(withSecrets(isolated)) {
+		t.Error("hasIsolationStep() = false for a command with secrets and an isolation step")
+	}
+}
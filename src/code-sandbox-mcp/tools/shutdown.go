@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/logging"
+	"github.com/Automata-Labs-team/code-sandbox-mcp/resources"
+	"github.com/docker/docker/api/types/container"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/moby/moby/client"
+)
+
+// rootCtx is cancelled when the server shuts down, which cancels every tool
+// call still in progress
+var rootCtx, cancelRoot = context.WithCancel(context.Background())
+
+// activeContainerIDs returns the containers this process created and hasn't
+// removed, so they can be removed when it shuts down rather than be left running
+func activeContainerIDs() []string {
+	var ids []string
+	for _, c := range resources.ActiveContainers() {
+		ids = append(ids, c.ContainerID)
+	}
+	return ids
+}
+
+// removeContainer force-removes a container and forgets it
+func removeContainer(ctx context.Context, cli *client.Client, containerID string) error {
+	err := cli.ContainerRemove(ctx, containerID, container.RemoveOptions{Force: true})
+	if err == nil || client.IsErrNotFound(err) {
+		resources.UnregisterContainer(containerID)
+		return nil
+	}
+	return err
+}
+
+// WithShutdown makes a tool handler's context end when the server shuts
+// down, and refuses new calls once it has
+func WithShutdown(handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if rootCtx.Err() != nil {
+			return mcp.NewToolResultError("the server is shutting down"), nil
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		stop := context.AfterFunc(rootCtx, cancel)
+		defer stop()
+		return handler(ctx, request)
+	}
+}
+
+// backgroundRuns counts the runs followed by watchBackgroundRun, which save
+// a run's output before removing its container
+var backgroundRuns sync.WaitGroup
+
+// Shutdown cancels the tool calls in progress and removes every container
+// this process started. The containers are stopped first, so the output of
+// background runs is saved as if they had ended on their own, and whatever is
+// left is removed once that is done. It returns when everything is removed or
+// ctx is done.
+func Shutdown(ctx context.Context) {
+	cancelRoot()
+
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		logging.Errorf("Failed to create Docker client to remove containers: %v", err)
+		return
+	}
+	defer cli.Close()
+
+	forEach := func(ids []string, f func(id string)) {
+		var wg sync.WaitGroup
+		for _, id := range ids {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				f(id)
+			}()
+		}
+		wg.Wait()
+	}
+
+	for _, pc := range takeAllIdleContainers() {
+		discardContainer(ctx, cli, pc)
+	}
+	forEach(activeContainerIDs(), func(id string) {
+		if err := cli.ContainerStop(ctx, id, container.StopOptions{}); err != nil && !client.IsErrNotFound(err) {
+			logging.Warnf("Failed to stop container %s on shutdown: %v", id, err)
+		}
+	})
+
+	watched := make(chan struct{})
+	go func() {
+		backgroundRuns.Wait()
+		close(watched)
+	}()
+	select {
+	case <-watched:
+	case <-ctx.Done():
+	}
+
+	forEach(activeContainerIDs(), func(id string) {
+		if err := removeContainer(ctx, cli, id); err != nil {
+			logging.Warnf("Failed to remove container %s on shutdown: %v", id, err)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// resetRootContext gives a test a root context of its own to cancel
+func resetRootContext(t *testing.T) {
+	savedCtx, savedCancel := rootCtx, cancelRoot
+	rootCtx, cancelRoot = context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancelRoot()
+		rootCtx, cancelRoot = savedCtx, savedCancel
+	})
+}
+
+func TestWithShutdown(t *testing.T) {
+	resetRootContext(t)
+
+	started := make(chan struct{})
+	handler := WithShutdown(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		close(started)
+		<-ctx.Done()
+		return mcp.NewToolResultError(ctx.Err().Error()), nil
+	})
+
+	done := make(chan *mcp.CallToolResult)
+	go func() {
+		result, _ := handler(context.Background(), mcp.CallToolRequest{})
+		done <- result
+	}()
+	<-started
+	cancelRoot()
+
+	select {
+	case result := <-done:
+		if !result.IsError {
+			t.Errorf("handler result = %+v, want the cancellation", result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler still running after shutdown")
+	}
+
+	result, err := handler(context.Background(), mcp.CallToolRequest{})
+	if err != nil || !result.IsError || !strings.Contains(result.Content[0].(mcp.TextContent).Text, "shutting down") {
+		t.Errorf("call after shutdown = %+v, %v, want it refused", result, err)
+	}
+}
+
+func TestTakeAllIdleContainers(t *testing.T) {
+	setPoolSize(t, 4)
+	now := time.Now()
+	for _, pc := range []*pooledContainer{{ID: "a", Key: "python"}, {ID: "b", Key: "go"}, {ID: "c", Key: "python"}} {
+		putIdleContainer(pc, now)
+	}
+
+	if all := takeAllIdleContainers(); len(all) != 3 {
+		t.Errorf("takeAllIdleContainers() = %d containers, want 3", len(all))
+	}
+	if pc := takeIdleContainer("python"); pc != nil {
+		t.Errorf("pool still holds %s after it was emptied", pc.ID)
+	}
+}
@@ -0,0 +1,187 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+)
+
+// maxSnippetFilesBytes bounds the combined size of the files of a run_code call
+const maxSnippetFilesBytes = maxCodeFileBytes
+
+// snippetFileNameRe matches the file names run_code accepts in files. They
+// end up in shell commands, so they are restricted to characters that need
+// no quoting.
+var snippetFileNameRe = regexp.MustCompile(`^[A-Za-z0-9._/-]+$`)
+
+// parseSnippetFiles reads the files parameter of run_code, a map of relative
+// file names to their contents given as an object or as a JSON-encoded
+// object. Names must stay inside the work directory and out of artifacts/,
+// which holds the run's outputs.
+func parseSnippetFiles(value interface{}) (map[string]string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	raw, err := stringMap("files", value)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]string, len(raw))
+	total := 0
+	for name, s := range raw {
+		clean, err := snippetFileName(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := files[clean]; dup {
+			return nil, fmt.Errorf("file %s is given more than once", clean)
+		}
+		total += len(s)
+		if total > maxSnippetFilesBytes {
+			return nil, fmt.Errorf("files are larger than %d MB in total", maxSnippetFilesBytes/(1024*1024))
+		}
+		files[clean] = s
+	}
+	return files, nil
+}
+
+// snippetFileName validates a file name of the files parameter and returns
+// it cleaned
+func snippetFileName(name string) (string, error) {
+	if !snippetFileNameRe.MatchString(name) {
+		return "", fmt.Errorf("invalid file name %q: only letters, digits, '.', '_', '-' and '/' are allowed", name)
+	}
+	clean := path.Clean(name)
+	if path.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("invalid file name %q: must be relative to the work directory", name)
+	}
+	if clean == projectArtifactsDir || strings.HasPrefix(clean, projectArtifactsDir+"/") {
+		return "", fmt.Errorf("invalid file name %q: %s/ is reserved for the run's artifacts", name, projectArtifactsDir)
+	}
+	return clean, nil
+}
+
+// writeSnippetFiles writes files into dir, creating their directories
+func writeSnippetFiles(dir string, files map[string]string) error {
+	for name, content := range files {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", name, err)
+		}
+		if err := os.WriteFile(target, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// writeSnippet writes the files of a run_code call into dir, followed by its
+// code as fileName. A call made only of files has no code to write.
+func writeSnippet(dir string, fileName string, code string, opts runOptions) error {
+	if err := writeSnippetFiles(dir, opts.Files); err != nil {
+		return err
+	}
+	if code == "" && opts.Entrypoint != "" {
+		return nil
+	}
+	if err := os.WriteFile(filepath.Join(dir, fileName), []byte(code), 0644); err != nil {
+		return fmt.Errorf("failed to write code to temporary file: %w", err)
+	}
+	return nil
+}
+
+// snippetSources joins the code of the main file with that of every file in
+// files written in the language, so dependency detection sees all of them
+func snippetSources(code string, files map[string]string, language languages.Language) string {
+	ext := "." + languages.SupportedLanguages[language].FileExtension
+	names := make([]string, 0, len(files))
+	for name := range files {
+		if strings.HasSuffix(name, ext) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	sources := []string{code}
+	for _, name := range names {
+		sources = append(sources, files[name])
+	}
+	return strings.Join(sources, "\n")
+}
+
+// withoutLocalPythonModules drops the packages that are modules among files,
+// e.g. "helpers" for helpers.py or a helpers/ package, so they aren't installed
+func withoutLocalPythonModules(packages []string, files map[string]string) []string {
+	local := make(map[string]bool)
+	for name := range files {
+		first, _, nested := strings.Cut(name, "/")
+		if nested {
+			local[first] = true
+		} else if module, ok := strings.CutSuffix(name, ".py"); ok {
+			local[module] = true
+		}
+	}
+
+	var kept []string
+	for _, pkg := range packages {
+		if !local[pkg] {
+			kept = append(kept, pkg)
+		}
+	}
+	return kept
+}
+
+// snippetEntrypoint points cmd, the run command of mainFile, at entrypoint
+// instead. Java runs the class the entrypoint declares. Go, C and C++ build
+// the other source files at the top of the work directory along with it, as
+// their compilers only see the files they are given.
+func snippetEntrypoint(cmd []string, language languages.Language, mainFile string, entrypoint string, files map[string]string) []string {
+	if language == languages.Java {
+		if entrypoint == mainFile {
+			return cmd
+		}
+		return javaRun(javaMainClass(files[entrypoint]))
+	}
+
+	sources := append([]string{entrypoint}, linkedSources(language, entrypoint, files)...)
+	var rewritten []string
+	for _, arg := range cmd {
+		if arg == mainFile {
+			// The file is an argument of its own, e.g. go run main.go
+			rewritten = append(rewritten, sources...)
+			continue
+		}
+		rewritten = append(rewritten, strings.ReplaceAll(arg, mainFile, strings.Join(sources, " ")))
+	}
+	return rewritten
+}
+
+// linkedSources lists the top-level source files among files, other than
+// entrypoint, that are compiled together with it. Go tests are left out.
+func linkedSources(language languages.Language, entrypoint string, files map[string]string) []string {
+	if language != languages.Go && language != languages.C && language != languages.Cpp {
+		return nil
+	}
+	extensions := append([]string{languages.SupportedLanguages[language].FileExtension}, extraSourceExtensions[language]...)
+	var sources []string
+	for name := range files {
+		if name == entrypoint || strings.Contains(name, "/") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		for _, ext := range extensions {
+			if strings.HasSuffix(name, "."+ext) {
+				sources = append(sources, name)
+				break
+			}
+		}
+	}
+	sort.Strings(sources)
+	return sources
+}
@@ -0,0 +1,137 @@
+package tools
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestParseSnippetFiles(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       interface{}
+		want        map[string]string
+		errContains string
+	}{
+		{name: "omitted", value: nil},
+		{
+			name:  "object",
+			value: map[string]interface{}{"helpers.py": "x = 1", "./data/input.csv": "a,b"},
+			want:  map[string]string{"helpers.py": "x = 1", "data/input.csv": "a,b"},
+		},
+		{
+			name:  "json string",
+			value: `{"lib/util.go": "package main"}`,
+			want:  map[string]string{"lib/util.go": "package main"},
+		},
+		{name: "parent directory", value: map[string]interface{}{"../evil.py": ""}, errContains: "relative to the work directory"},
+		{name: "escapes through subdirectory", value: map[string]interface{}{"a/../../evil.py": ""}, errContains: "relative to the work directory"},
+		{name: "absolute", value: map[string]interface{}{"/etc/passwd": ""}, errContains: "relative to the work directory"},
+		{name: "artifacts directory", value: map[string]interface{}{"artifacts/out.txt": ""}, errContains: "reserved"},
+		{name: "shell characters", value: map[string]interface{}{"a b;rm.py": ""}, errContains: "invalid file name"},
+		{name: "non-string contents", value: map[string]interface{}{"a.py": 1.0}, errContains: "must be a string"},
+		{name: "invalid json", value: `{"a.py":`, errContains: "files must be a JSON object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSnippetFiles(tt.value)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("parseSnippetFiles() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSnippetFiles() unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("parseSnippetFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnippetEntrypoint(t *testing.T) {
+	tests := []struct {
+		name       string
+		language   languages.Language
+		mainFile   string
+		entrypoint string
+		files      map[string]string
+		want       []string
+	}{
+		{
+			name:       "python file",
+			language:   languages.Python,
+			mainFile:   "main.py",
+			entrypoint: "app/run.py",
+			files:      map[string]string{"app/run.py": "", "helpers.py": ""},
+			want:       []string{"python3", "app/run.py"},
+		},
+		{
+			name:       "go builds the other top-level files",
+			language:   languages.Go,
+			mainFile:   "main.go",
+			entrypoint: "main.go",
+			files:      map[string]string{"util.go": "", "util_test.go": "", "sub/other.go": ""},
+			want:       []string{"go", "run", "main.go", "util.go"},
+		},
+		{
+			name:       "c compiles every translation unit",
+			language:   languages.C,
+			mainFile:   "main.c",
+			entrypoint: "prog.c",
+			files:      map[string]string{"prog.c": "", "list.c": "", "list.h": ""},
+			want:       []string{"/bin/sh", "-c", "gcc -o /tmp/a.out prog.c list.c -lm && /tmp/a.out"},
+		},
+		{
+			name:       "java runs the entrypoint's class",
+			language:   languages.Java,
+			mainFile:   "Main.java",
+			entrypoint: "App.java",
+			files:      map[string]string{"App.java": "public class App {}"},
+			want:       javaRun("App"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := languages.SupportedLanguages[tt.language].RunCommand
+			if tt.language == languages.Java {
+				cmd = javaRun("Main")
+			}
+			got := snippetEntrypoint(cmd, tt.language, tt.mainFile, tt.entrypoint, tt.files)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("snippetEntrypoint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithoutLocalPythonModules(t *testing.T) {
+	files := map[string]string{"helpers.py": "", "models/__init__.py": "", "data.csv": ""}
+	got := withoutLocalPythonModules([]string{"requests", "helpers", "models", "numpy"}, files)
+	if want := []string{"requests", "numpy"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("withoutLocalPythonModules() = %v, want %v", got, want)
+	}
+}
+
+func TestRunCodeEntrypointNotInFiles(t *testing.T) {
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{
+		"language": "python",
+		"files":    map[string]interface{}{"app.py": "print(1)"},
+	}
+	result, err := RunCodeSandbox(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !result.IsError || !strings.Contains(text, "entrypoint main.py is not one of the files") {
+		t.Errorf("RunCodeSandbox() = %q, want an error about the missing entrypoint", text)
+	}
+}
@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"sort"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/languages"
+	"github.com/Automata-Labs-team/code-sandbox-mcp/logging"
+)
+
+// snippetPlan is how run_code runs a snippet: the file the code is saved as,
+// the packages installed for it, sorted, and the final container command
+type snippetPlan struct {
+	FileName string
+	Packages []string
+	Cmd      []string
+}
+
+// planSnippet works out how code is run in language, starting from the
+// language's run command. It only looks at the code and the options, so
+// analyze_code can report the plan without creating a container.
+func planSnippet(cmd []string, code string, language languages.Language, opts runOptions) snippetPlan {
+	// Java needs the file to be named after the snippet's public class
+	fileName := "main." + languages.SupportedLanguages[language].FileExtension
+	if opts.FileExtension != "" {
+		fileName = "main." + opts.FileExtension
+		cmd = withFileExtension(cmd, language, opts.FileExtension)
+	}
+	if language == languages.Java {
+		class := javaMainClass(code)
+		fileName = class + ".java"
+		cmd = javaRun(class)
+	}
+	// Extra files may hold the program to run in place of the code
+	if len(opts.Files) > 0 {
+		entrypoint := opts.Entrypoint
+		if entrypoint == "" {
+			entrypoint = fileName
+		}
+		cmd = snippetEntrypoint(cmd, language, fileName, entrypoint, opts.Files)
+	}
+
+	// Parse imports to detect required packages. For Python this includes the
+	// packages of "# requirements:" comments, which win over bare imports.
+	// Imports of the other files count too, but not those of each other.
+	var packages []string
+	sources := snippetSources(code, opts.Files, language)
+	if opts.SkipDependencyInstall {
+		logging.Debugf("Skipping dependency detection, the image is preprovisioned")
+	} else if language == languages.Python {
+		packages = withoutLocalPythonModules(languages.ParsePythonImports(sources), opts.Files)
+		logging.Debugf("Detected Python packages: %v", packages)
+	} else if language.UsesNPM() {
+		packages = languages.ParseNodeImports(sources)
+	} else if language == languages.Go {
+		packages = languages.ParseGoImports(sources)
+	} else if language == languages.Rust {
+		packages = languages.ParseRustImports(sources)
+	}
+	// Detection doesn't keep the order of the imports, so the packages are
+	// sorted for the same code to always give the same command
+	sort.Strings(packages)
+
+	// Rust snippets using crates are built with cargo from a generated Cargo.toml
+	if language == languages.Rust && len(packages) > 0 {
+		cmd = rustCargoRun
+	}
+
+	// Modify the command to install dependencies first if needed
+	cmd = rebaseWorkDir(cmd, opts.WorkDir)
+	finalCmd := BuildContainerCommand(CommandSpec{
+		Language:       language,
+		Cmd:            cmd,
+		WorkDir:        opts.WorkDir,
+		Packages:       packages,
+		InstallCommand: opts.InstallCommand,
+
+		IsolateAfterInstall:    !opts.AllowNetwork,
+		ContinueOnInstallError: opts.ContinueOnDepError,
+	})
+	if len(opts.Secrets) > 0 {
+		finalCmd = withSecrets(finalCmd)
+	}
+	return snippetPlan{FileName: fileName, Packages: packages, Cmd: finalCmd}
+}
@@ -0,0 +1,20 @@
+package tools
+
+import (
+	"io"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+)
+
+// writeStdin writes data to a container's attached standard input and then
+// closes it, so the program sees end of file. It runs alongside the wait for
+// the container: a program that exits without reading all of its input would
+// otherwise leave the write blocked on a full pipe. Closing the attached
+// connection once the container is done ends such a write.
+func writeStdin(stdin types.HijackedResponse, data string) {
+	if _, err := io.Copy(stdin.Conn, strings.NewReader(data)); err != nil {
+		return
+	}
+	_ = stdin.CloseWrite()
+}
@@ -0,0 +1,46 @@
+package tools
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestWriteStdin(t *testing.T) {
+	container, server := net.Pipe()
+	defer server.Close()
+	go writeStdin(types.HijackedResponse{Conn: server}, "1 2 3\n")
+
+	buf := make([]byte, 6)
+	if _, err := io.ReadFull(container, buf); err != nil {
+		t.Fatalf("reading stdin: %v", err)
+	}
+	if got := string(buf); got != "1 2 3\n" {
+		t.Errorf("got stdin %q, want %q", got, "1 2 3\n")
+	}
+}
+
+func TestWriteStdinUnreadInput(t *testing.T) {
+	// A program that stops reading and exits must not leave the write blocked
+	container, server := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		writeStdin(types.HijackedResponse{Conn: server}, strings.Repeat("x", 1<<20))
+		close(done)
+	}()
+
+	if _, err := io.ReadFull(container, make([]byte, 10)); err != nil {
+		t.Fatalf("reading stdin: %v", err)
+	}
+	container.Close()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("writeStdin did not return after the container stopped reading")
+	}
+}
@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/logging"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// TestStdioTransportCarriesOnlyJSONRPC serves tool calls over the stdio
+// transport with os.Stdout as its output, as the server does, and checks that
+// every line written there is a JSON-RPC message. Anything else printed to
+// stdout would corrupt the stream for the client.
+func TestStdioTransportCarriesOnlyJSONRPC(t *testing.T) {
+	defer logging.SetLevel(logging.DefaultLevel)
+	logging.SetLevel(logging.LevelDebug)
+
+	projectDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectDir, "main.py"), []byte("# requirements: numpy\nimport numpy\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := server.NewMCPServer("test", "v0.0.0")
+	s.AddTool(mcp.NewTool("run_code"), RunCodeSandbox)
+	s.AddTool(mcp.NewTool("analyze_project"), AnalyzeProject)
+	s.AddTool(mcp.NewTool("list_languages"), ListSupportedLanguages)
+
+	calls := []map[string]interface{}{
+		{"name": "list_languages", "arguments": map[string]interface{}{}},
+		{"name": "analyze_project", "arguments": map[string]interface{}{"projectDir": projectDir}},
+		{"name": "run_code", "arguments": map[string]interface{}{"language": "python"}},
+		{"name": "run_code", "arguments": map[string]interface{}{"language": "cobol", "code": "x"}},
+	}
+	var input bytes.Buffer
+	input.WriteString(`{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"v0.0.0"}}}` + "\n")
+	for i, call := range calls {
+		message, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": i + 1, "method": "tools/call", "params": call})
+		if err != nil {
+			t.Fatal(err)
+		}
+		input.Write(append(message, '\n'))
+	}
+
+	stdout := captureStdout(t, func() {
+		if err := server.NewStdioServer(s).Listen(context.Background(), &input, os.Stdout); err != nil {
+			t.Errorf("Listen() unexpected error: %v", err)
+		}
+	})
+
+	responses := 0
+	scanner := bufio.NewScanner(strings.NewReader(stdout))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var message struct {
+			JSONRPC string `json:"jsonrpc"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil || message.JSONRPC != "2.0" {
+			t.Errorf("stdout line is not a JSON-RPC message: %q", scanner.Text())
+			continue
+		}
+		responses++
+	}
+	if want := len(calls) + 1; responses != want {
+		t.Errorf("got %d JSON-RPC messages on stdout, want %d", responses, want)
+	}
+}
+
+// captureStdout runs f with os.Stdout redirected and returns what was written
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = saved }()
+
+	done := make(chan string)
+	go func() {
+		var b strings.Builder
+		if _, err := io.Copy(&b, r); err != nil {
+			fmt.Fprintf(&b, "\nreading stdout failed: %v", err)
+		}
+		done <- b.String()
+	}()
+
+	f()
+	w.Close()
+	return <-done
+}
@@ -0,0 +1,163 @@
+package tools
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/resources"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/moby/moby/client"
+)
+
+// stepsScriptPath is where the script running a run's entrypoint steps is
+// placed in the container
+const stepsScriptPath = "/tmp/code-sandbox-steps.sh"
+
+// stepMarker starts the lines the steps script prints around every step so
+// the output can be split up by step afterwards
+const stepMarker = "__code_sandbox_step__"
+
+// parseEntrypointSteps reads the entrypointSteps parameter, a list of commands
+// or a string with one command per line
+func parseEntrypointSteps(value interface{}) ([]string, error) {
+	var items []string
+	switch v := value.(type) {
+	case string:
+		items = strings.Split(v, "\n")
+	case []interface{}:
+		for _, item := range v {
+			step, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("entrypointSteps must be strings")
+			}
+			items = append(items, step)
+		}
+	default:
+		return nil, fmt.Errorf("entrypointSteps must be a list of commands or a string with one command per line")
+	}
+
+	var steps []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			steps = append(steps, item)
+		}
+	}
+	return steps, nil
+}
+
+// shellQuote quotes s as a single word for sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// buildStepsScript returns a shell script running the steps one after the
+// other. It stops at the first failing step unless continueOnError is set,
+// and exits with the exit code of the first failure.
+func buildStepsScript(steps []string, continueOnError bool) string {
+	var b strings.Builder
+	b.WriteString("failed=0\n")
+	for i, step := range steps {
+		fmt.Fprintf(&b, "echo '%s %d start'\n", stepMarker, i+1)
+		fmt.Fprintf(&b, "/bin/sh -c %s\n", shellQuote(step))
+		b.WriteString("rc=$?\n")
+		fmt.Fprintf(&b, "echo \"%s %d exit $rc\"\n", stepMarker, i+1)
+		if continueOnError {
+			b.WriteString("[ $rc -eq 0 ] || [ $failed -ne 0 ] || failed=$rc\n")
+		} else {
+			b.WriteString("[ $rc -eq 0 ] || exit $rc\n")
+		}
+	}
+	b.WriteString("exit $failed\n")
+	return b.String()
+}
+
+// writeContainerFile creates an executable file in a created container
+func writeContainerFile(ctx context.Context, cli *client.Client, containerID string, containerPath string, content string) error {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	hdr := &tar.Header{
+		Name:     strings.TrimPrefix(containerPath, "/"),
+		Mode:     0755,
+		Size:     int64(len(content)),
+		Typeflag: tar.TypeReg,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if _, err := tw.Write([]byte(content)); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := cli.CopyToContainer(ctx, containerID, "/", &buf, container.CopyToContainerOptions{}); err != nil {
+		return fmt.Errorf("failed to write %s in container: %w", containerPath, err)
+	}
+	return nil
+}
+
+// collectStepResults reads the output of a finished steps run and records the
+// outcome of every step in the run registry
+func collectStepResults(ctx context.Context, cli *client.Client, containerID string, steps []string) error {
+	out, err := cli.ContainerLogs(ctx, containerID, container.LogsOptions{ShowStdout: true, ShowStderr: true, Timestamps: true})
+	if err != nil {
+		return fmt.Errorf("failed to get container logs: %w", err)
+	}
+	defer out.Close()
+
+	var b strings.Builder
+	if _, err := stdcopy.StdCopy(&b, &b, out); err != nil {
+		return fmt.Errorf("failed to copy container output: %w", err)
+	}
+	resources.SetRunSteps(containerID, parseStepResults(resources.MaskRunSecrets(containerID, b.String()), steps))
+	return nil
+}
+
+// parseStepResults splits timestamped log output of a steps script into the
+// results of the individual steps
+func parseStepResults(logs string, steps []string) []resources.StepResult {
+	results := make([]resources.StepResult, len(steps))
+	starts := make([]time.Time, len(steps))
+	for i, step := range steps {
+		results[i].Command = step
+	}
+
+	current := -1
+	for _, line := range strings.SplitAfter(logs, "\n") {
+		if line == "" {
+			continue
+		}
+		timestamp, text, _ := strings.Cut(line, " ")
+		at, _ := time.Parse(time.RFC3339Nano, timestamp)
+
+		if fields := strings.Fields(text); len(fields) >= 3 && fields[0] == stepMarker {
+			n, err := strconv.Atoi(fields[1])
+			if err != nil || n < 1 || n > len(steps) {
+				continue
+			}
+			switch {
+			case fields[2] == "start":
+				current = n - 1
+				results[current].Started = true
+				starts[current] = at
+			case fields[2] == "exit" && len(fields) == 4:
+				exitCode, _ := strconv.Atoi(fields[3])
+				results[n-1].Finished = true
+				results[n-1].ExitCode = exitCode
+				results[n-1].Duration = at.Sub(starts[n-1])
+				current = -1
+			}
+			continue
+		}
+		if current >= 0 {
+			results[current].Logs += text
+		}
+	}
+	return results
+}
@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildStepsScript(t *testing.T) {
+	steps := []string{"echo building", "echo 'it''s broken' && exit 3", "echo testing"}
+
+	tests := []struct {
+		name            string
+		continueOnError bool
+		wantExit        int
+		wantSteps       int
+	}{
+		{name: "stops at first failure", wantExit: 3, wantSteps: 2},
+		{name: "continue on error", continueOnError: true, wantExit: 3, wantSteps: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := exec.Command("/bin/sh", "-c", buildStepsScript(steps, tt.continueOnError)).Output()
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) || exitErr.ExitCode() != tt.wantExit {
+				t.Fatalf("script error = %v, want exit code %d", err, tt.wantExit)
+			}
+			if got := strings.Count(string(out), stepMarker+" "); got != 2*tt.wantSteps {
+				t.Errorf("script printed %d step markers, want %d\n%s", got, 2*tt.wantSteps, out)
+			}
+			if !strings.Contains(string(out), "its broken") {
+				t.Errorf("script output %q is missing the quoted step's output", out)
+			}
+		})
+	}
+}
+
+func TestParseStepResults(t *testing.T) {
+	logs := strings.Join([]string{
+		"2025-01-01T10:00:00.000000000Z " + stepMarker + " 1 start",
+		"2025-01-01T10:00:00.100000000Z compiling",
+		"2025-01-01T10:00:01.500000000Z " + stepMarker + " 1 exit 0",
+		"2025-01-01T10:00:01.600000000Z " + stepMarker + " 2 start",
+		"2025-01-01T10:00:01.700000000Z FAIL: TestThing",
+		"2025-01-01T10:00:02.000000000Z " + stepMarker + " 2 exit 1",
+		"",
+	}, "\n")
+
+	results := parseStepResults(logs, []string{"go build ./...", "go test ./...", "./app"})
+	if len(results) != 3 {
+		t.Fatalf("parseStepResults() returned %d results, want 3", len(results))
+	}
+	if r := results[0]; !r.Finished || r.ExitCode != 0 || r.Duration != 1500*time.Millisecond || r.Logs != "compiling\n" {
+		t.Errorf("step 1 = %+v, want exit 0 after 1.5s with its output", r)
+	}
+	if r := results[1]; !r.Finished || r.ExitCode != 1 || r.Logs != "FAIL: TestThing\n" {
+		t.Errorf("step 2 = %+v, want exit 1 with its output", r)
+	}
+	if r := results[2]; r.Started || r.Command != "./app" {
+		t.Errorf("step 3 = %+v, want not started", r)
+	}
+}
@@ -0,0 +1,157 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/resources"
+	"github.com/docker/docker/api/types/container"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/moby/moby/client"
+)
+
+// Bounds on how long stream_stats follows a container, in seconds
+const (
+	defaultStatsDuration = 60
+	maxStatsDuration     = 600
+)
+
+// statsSample is the resource usage reported to the client for one stats reading
+type statsSample struct {
+	CPUPercent       float64 `json:"cpuPercent"`
+	MemoryUsageBytes uint64  `json:"memoryUsageBytes"`
+	MemoryLimitBytes uint64  `json:"memoryLimitBytes"`
+	NetworkRxBytes   uint64  `json:"networkRxBytes"`
+	NetworkTxBytes   uint64  `json:"networkTxBytes"`
+}
+
+// newStatsSample condenses a raw stats reading into the values clients care about
+func newStatsSample(stats container.StatsResponse) statsSample {
+	sample := statsSample{
+		CPUPercent:       cpuPercent(stats.CPUStats, stats.PreCPUStats),
+		MemoryUsageBytes: stats.MemoryStats.Usage,
+		MemoryLimitBytes: stats.MemoryStats.Limit,
+	}
+	for _, network := range stats.Networks {
+		sample.NetworkRxBytes += network.RxBytes
+		sample.NetworkTxBytes += network.TxBytes
+	}
+	return sample
+}
+
+// cpuPercent computes CPU usage since the previous reading the same way the
+// docker CLI does, where 100% is one fully used CPU
+func cpuPercent(current, previous container.CPUStats) float64 {
+	cpuDelta := float64(current.CPUUsage.TotalUsage) - float64(previous.CPUUsage.TotalUsage)
+	systemDelta := float64(current.SystemUsage) - float64(previous.SystemUsage)
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0
+	}
+	onlineCPUs := float64(current.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(current.CPUUsage.PercpuUsage))
+	}
+	return cpuDelta / systemDelta * onlineCPUs * 100
+}
+
+// StreamStats follows a run's resource usage and sends each reading to the
+// client as a log message notification until the container exits or the
+// duration runs out
+func StreamStats(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	containerID, ok := request.Params.Arguments["containerId"].(string)
+	if !ok || containerID == "" {
+		return mcp.NewToolResultError("containerId must be a non-empty string"), nil
+	}
+	if _, ok := resources.GetRun(containerID); !ok {
+		return mcp.NewToolResultError(fmt.Sprintf("no run found for container %s", containerID)), nil
+	}
+
+	duration := defaultStatsDuration
+	if value, ok := request.Params.Arguments["durationSeconds"]; ok {
+		seconds, ok := value.(float64)
+		if !ok || seconds <= 0 || seconds > maxStatsDuration {
+			return mcp.NewToolResultError(fmt.Sprintf("durationSeconds must be between 1 and %d", maxStatsDuration)), nil
+		}
+		duration = int(seconds)
+	}
+
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to create Docker client: %v", err)), nil
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(duration)*time.Second)
+	defer cancel()
+
+	// The stats stream does not reliably end when the container stops, so
+	// watch for the exit separately and end the stream when it happens
+	exited := make(chan struct{})
+	go func() {
+		statusCh, errCh := cli.ContainerWait(ctx, containerID, container.WaitConditionNotRunning)
+		select {
+		case <-statusCh:
+			close(exited)
+			cancel()
+		case <-errCh:
+		}
+	}()
+
+	stats, err := cli.ContainerStats(ctx, containerID, true)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get container stats: %v", err)), nil
+	}
+	defer stats.Body.Close()
+
+	mcpServer := server.ServerFromContext(ctx)
+	var samples int
+	var peak statsSample
+	decoder := json.NewDecoder(stats.Body)
+	for {
+		var raw container.StatsResponse
+		if err := decoder.Decode(&raw); err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to read container stats: %v", err)), nil
+			}
+			break
+		}
+
+		sample := newStatsSample(raw)
+		samples++
+		peak.CPUPercent = max(peak.CPUPercent, sample.CPUPercent)
+		peak.MemoryUsageBytes = max(peak.MemoryUsageBytes, sample.MemoryUsageBytes)
+		peak.MemoryLimitBytes = sample.MemoryLimitBytes
+		peak.NetworkRxBytes, peak.NetworkTxBytes = sample.NetworkRxBytes, sample.NetworkTxBytes
+
+		if mcpServer != nil {
+			_ = notifyClient(ctx, mcpServer, "notifications/message", map[string]interface{}{
+				"level":  "info",
+				"logger": containerID,
+				"data": map[string]interface{}{
+					"containerId": containerID,
+					"stats":       sample,
+				},
+			})
+		}
+	}
+
+	reason := "duration limit reached"
+	select {
+	case <-exited:
+		reason = "container exited"
+	default:
+		if err := ctx.Err(); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			reason = "cancelled"
+		}
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf(
+		"Stopped streaming stats for %s: %s\n\nSamples: %d\nPeak CPU: %.1f%%\nPeak memory: %d / %d bytes\nNetwork: %d bytes received, %d bytes sent",
+		containerID, reason, samples, peak.CPUPercent, peak.MemoryUsageBytes, peak.MemoryLimitBytes, peak.NetworkRxBytes, peak.NetworkTxBytes,
+	)), nil
+}
@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"math"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestCPUPercent(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  container.CPUStats
+		previous container.CPUStats
+		want     float64
+	}{
+		{
+			name: "one of two cpus busy",
+			current: container.CPUStats{
+				CPUUsage:    container.CPUUsage{TotalUsage: 2000},
+				SystemUsage: 4000,
+				OnlineCPUs:  2,
+			},
+			previous: container.CPUStats{
+				CPUUsage:    container.CPUUsage{TotalUsage: 1000},
+				SystemUsage: 2000,
+			},
+			want: 100,
+		},
+		{
+			name: "falls back to per-cpu usage count",
+			current: container.CPUStats{
+				CPUUsage:    container.CPUUsage{TotalUsage: 1500, PercpuUsage: []uint64{0, 0, 0, 0}},
+				SystemUsage: 4000,
+			},
+			previous: container.CPUStats{
+				CPUUsage:    container.CPUUsage{TotalUsage: 1000},
+				SystemUsage: 2000,
+			},
+			want: 100,
+		},
+		{
+			name:    "first reading has no previous sample",
+			current: container.CPUStats{CPUUsage: container.CPUUsage{TotalUsage: 1000}, SystemUsage: 2000, OnlineCPUs: 1},
+			want:    50,
+		},
+		{
+			name: "no system time elapsed",
+			current: container.CPUStats{
+				CPUUsage:    container.CPUUsage{TotalUsage: 2000},
+				SystemUsage: 2000,
+				OnlineCPUs:  1,
+			},
+			previous: container.CPUStats{
+				CPUUsage:    container.CPUUsage{TotalUsage: 1000},
+				SystemUsage: 2000,
+			},
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cpuPercent(tt.current, tt.previous); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("cpuPercent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Automata-Labs-team/code-sandbox-mcp/resources"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/moby/moby/client"
+)
+
+// Limits for tail_logs: the longest wait for new output in seconds, how often
+// the logs are checked while waiting and the most output returned per call
+const (
+	maxTailWait       = 30
+	tailPollInterval  = 500 * time.Millisecond
+	maxTailChunkBytes = 64 * 1024
+)
+
+// TailLogs returns the log output a container produced after a given offset.
+// It can wait for new output to arrive, so clients can poll cheaply.
+func TailLogs(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	containerID, ok := request.Params.Arguments["containerId"].(string)
+	if !ok || containerID == "" {
+		return mcp.NewToolResultError("containerId must be a non-empty string"), nil
+	}
+	run, ok := resources.GetRun(containerID)
+	if !ok {
+		return mcp.NewToolResultError(fmt.Sprintf("no run found for container %s", containerID)), nil
+	}
+
+	// Without an explicit offset, continue from where the last call stopped
+	offset := run.LogOffset
+	if value, ok := request.Params.Arguments["offset"]; ok {
+		o, ok := value.(float64)
+		if !ok || o < 0 {
+			return mcp.NewToolResultError("offset must be a non-negative number"), nil
+		}
+		offset = int64(o)
+	}
+
+	var wait time.Duration
+	if value, ok := request.Params.Arguments["waitSeconds"]; ok {
+		seconds, ok := value.(float64)
+		if !ok || seconds < 0 || seconds > maxTailWait {
+			return mcp.NewToolResultError(fmt.Sprintf("waitSeconds must be between 0 and %d", maxTailWait)), nil
+		}
+		wait = time.Duration(seconds * float64(time.Second))
+	}
+
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to create Docker client: %v", err)), nil
+	}
+	defer cli.Close()
+
+	deadline := time.Now().Add(wait)
+	var logs string
+	var exited bool
+	for {
+		// Check for exit before reading so no output written before the exit is missed.
+		// A pooled container keeps running once its run has finished.
+		exited = runFinished(containerID) || containerExited(ctx, cli, containerID)
+		logs, err = resources.ReadContainerLogs(ctx, containerID)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read logs: %v", err)), nil
+		}
+		if int64(len(logs)) > offset || exited || !time.Now().Before(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return mcp.NewToolResultError("tail_logs was cancelled"), nil
+		case <-time.After(tailPollInterval):
+		}
+	}
+
+	if offset > int64(len(logs)) {
+		offset = int64(len(logs))
+	}
+	chunk := logs[offset:]
+	more := false
+	if len(chunk) > maxTailChunkBytes {
+		chunk, more = chunk[:maxTailChunkBytes], true
+	}
+	next := offset + int64(len(chunk))
+	resources.SetRunLogOffset(containerID, next)
+
+	status := "running"
+	if exited {
+		status = "exited"
+	}
+	resultText := fmt.Sprintf("Container: %s\nStatus: %s\nNext offset: %d", containerID, status, next)
+	if more {
+		resultText += "\nMore output is available, call again with the next offset"
+	}
+	if chunk != "" {
+		resultText += "\n\n" + chunk
+	}
+	return mcp.NewToolResultText(resultText), nil
+}
+
+// runFinished reports whether the run recorded for a container has ended
+func runFinished(containerID string) bool {
+	run, ok := resources.GetRun(containerID)
+	return ok && run.Status != resources.RunStatusRunning
+}
+
+// containerExited reports whether a container has stopped running. A
+// container that no longer exists counts as exited.
+func containerExited(ctx context.Context, cli *client.Client, containerID string) bool {
+	inspect, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return client.IsErrNotFound(err)
+	}
+	return inspect.State == nil || !inspect.State.Running
+}